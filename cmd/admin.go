@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+type adminServer struct {
+	pb.UnimplementedAdminServer
+	db        db.ActionsDB
+	retention db.RetentionPolicyT
+}
+
+// Handler
+func (s *adminServer) ApplyRetention(ctx context.Context, req *pb.RetentionRequest) (*pb.RetentionResponse, error) {
+
+	removed, err := s.db.ApplyRetention(s.retention, req.GetDryRun())
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	resp := &pb.RetentionResponse{Removed: make([]*pb.Partition, 0, len(removed))}
+	for _, p := range removed {
+		resp.Removed = append(resp.Removed, partitionToPb(p))
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *adminServer) ListPartitions(ctx context.Context, req *pb.PartitionsRequest) (*pb.PartitionsResponse, error) {
+
+	switch req.GetTypeTable() {
+	case "", "I", "W", "E":
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "not allowed type of table: {%s}", req.GetTypeTable())
+	}
+
+	parts, err := s.db.Partitions()
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	resp := &pb.PartitionsResponse{Partitions: make([]*pb.Partition, 0, len(parts))}
+	for _, p := range parts {
+		if req.GetTypeTable() != "" && req.GetTypeTable() != p.TypeTable {
+			continue
+		}
+		resp.Partitions = append(resp.Partitions, partitionToPb(p))
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *adminServer) CreateToken(ctx context.Context, req *pb.CreateTokenRequest) (*pb.CreateTokenResponse, error) {
+
+	token := db.TokenT{
+		Name:       req.GetName(),
+		Projects:   req.GetProjects(),
+		Operations: req.GetOperations(),
+	}
+	if req.GetTimeExpire() != nil {
+		token.TimeExpire = req.GetTimeExpire().AsTime()
+	}
+
+	token, secret, err := s.db.CreateToken(token)
+	if err != nil {
+		return nil, statusByError(err)
+	}
+	log.Printf("Token {%s} {%s} is created: projects %v, operations %v", token.Id, token.Name, token.Projects, token.Operations)
+
+	return &pb.CreateTokenResponse{Token: tokenToPb(token), Secret: secret}, nil
+}
+
+// Handler
+func (s *adminServer) ListTokens(ctx context.Context, req *pb.ListTokensRequest) (*pb.ListTokensResponse, error) {
+
+	tokens, err := s.db.ListTokens()
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	resp := &pb.ListTokensResponse{Tokens: make([]*pb.Token, 0, len(tokens))}
+	for _, t := range tokens {
+		resp.Tokens = append(resp.Tokens, tokenToPb(t))
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *adminServer) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+
+	token, err := s.db.RevokeToken(req.GetId())
+	if err != nil {
+		return nil, statusByError(err)
+	}
+	log.Printf("Token {%s} {%s} is revoked", token.Id, token.Name)
+
+	return &pb.RevokeTokenResponse{Token: tokenToPb(token)}, nil
+}
+
+// Token for the response
+func tokenToPb(t db.TokenT) *pb.Token {
+
+	token := &pb.Token{
+		Id:         t.Id,
+		Name:       t.Name,
+		Projects:   t.Projects,
+		Operations: t.Operations,
+		TimeCreate: timestamppb.New(t.TimeCreate),
+	}
+	if !t.TimeExpire.IsZero() {
+		token.TimeExpire = timestamppb.New(t.TimeExpire)
+	}
+	if !t.TimeRevoke.IsZero() {
+		token.TimeRevoke = timestamppb.New(t.TimeRevoke)
+	}
+
+	return token
+}
+
+// Log table for the response
+func partitionToPb(p db.PartitionT) *pb.Partition {
+
+	part := &pb.Partition{
+		TypeTable: p.TypeTable,
+		NameTable: p.NameTable,
+		Seq:       int32(p.Seq),
+		TimeOpen:  timestamppb.New(p.TimeOpen),
+		State:     p.State,
+		FirstId:   p.FirstId,
+		LastId:    p.LastId,
+		RowCount:  p.RowCount,
+	}
+	if !p.TimeClose.IsZero() {
+		part.TimeClose = timestamppb.New(p.TimeClose)
+	}
+	if !p.FirstTime.IsZero() {
+		part.FirstTime = timestamppb.New(p.FirstTime)
+		part.LastTime = timestamppb.New(p.LastTime)
+	}
+
+	return part
+}
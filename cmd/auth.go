@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Projects of clients by identity of the client certificate: subject CN, DNS, email or URI of SAN.
+// Projects of all identities of the certificate are joined, db.AllProjects - any project and the admin service
+type clientProjectsT map[string][]string
+
+// Authentication of clients by the token of the authorization header or by the client certificate
+type authT struct {
+	certs    bool            // clients of TLS_CLIENT_CA are authenticated by certificates
+	projects clientProjectsT // projects of certificates. nil - all projects
+	tokens   db.ActionsDB    // storage of tokens. nil - tokens are off
+}
+
+// Access of the client
+type accessT struct {
+	identity   string          // for messages of errors and limits by client
+	tokenId    string          // id of the token. Empty - the client certificate
+	all        bool            // any project
+	projects   map[string]bool // projects to write and read
+	operations map[string]bool // db.OperationWrite, db.OperationRead, db.OperationAdmin
+}
+
+// Key of the access of the client in the context of the request
+type ctxAccessKey struct{}
+
+// Services open to any client without authentication
+var publicServices = map[string]bool{
+	healthpb.Health_ServiceDesc.ServiceName:    true,
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+}
+
+// Operations of methods of the iwe service. Other methods - db.OperationAdmin
+var operationsByMethod = map[string]string{
+	pb.Iwe_SaveMessage_FullMethodName:    db.OperationWrite,
+	pb.Iwe_SaveMessages_FullMethodName:   db.OperationWrite,
+	pb.Iwe_StreamMessages_FullMethodName: db.OperationWrite,
+	pb.Iwe_QueryMessages_FullMethodName:  db.OperationRead,
+	pb.Iwe_SearchMessages_FullMethodName: db.OperationRead,
+	pb.Iwe_TailMessages_FullMethodName:   db.OperationRead,
+}
+
+// Credentials of the server by the config: the key pair PATH_PUBLIC_KEY, PATH_PRIVATE_KEY. With TLS_CLIENT_CA clients
+// present certificates signed by the CA (mTLS), optional if clients can use tokens. Return credentials, true for mTLS, error
+func newServerCreds(c configT) (credentials.TransportCredentials, bool, error) {
+
+	cert, err := tls.LoadX509KeyPair(c.Server.PathPublicKey, c.Server.PathPrivateKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("fault read sertificats: %v", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	pathCA := c.Auth.ClientCA
+	if pathCA == "" {
+		return credentials.NewTLS(cfg), false, nil
+	}
+	pemCA, err := os.ReadFile(pathCA)
+	if err != nil {
+		return nil, false, fmt.Errorf("fault read TLS_CLIENT_CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCA) {
+		return nil, false, fmt.Errorf("fault parse TLS_CLIENT_CA: {%s} has no certificates", pathCA)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if c.Auth.Tokens {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return credentials.NewTLS(cfg), true, nil
+}
+
+// Authentication of clients: by certificates with mTLS, by tokens of the storage with AUTH_TOKENS=true.
+// nil - clients are not authenticated. Return authentication, error
+func newAuth(cfg authCfgT, certs bool, objDB db.ActionsDB) (*authT, error) {
+
+	if !certs && !cfg.Tokens {
+		return nil, nil
+	}
+
+	a := &authT{certs: certs}
+	if cfg.Tokens {
+		a.tokens = objDB
+	}
+	if certs {
+		projects, err := newClientProjects(cfg.ClientProjectsFile)
+		if err != nil {
+			return nil, err
+		}
+		a.projects = projects
+	}
+
+	return a, nil
+}
+
+// Projects of clients from the JSON file CLIENT_PROJECTS_FILE: {"identity": ["project", ...]}.
+// Empty path - nil, any verified client has access to all projects. Return projects, error
+func newClientProjects(path string) (clientProjectsT, error) {
+
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fault read CLIENT_PROJECTS_FILE: %v", err)
+	}
+	var m clientProjectsT
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("fault parse CLIENT_PROJECTS_FILE: {%v}", err)
+	}
+	if m == nil {
+		m = clientProjectsT{}
+	}
+
+	return m, nil
+}
+
+// Interceptor of unary RPCs without authentication of clients: the admin service is denied
+func adminDeniedInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	if serviceOf(info.FullMethod) == pb.Admin_ServiceDesc.ServiceName {
+		return nil, errAdminDenied()
+	}
+
+	return handler(ctx, req)
+}
+
+// Interceptor of stream RPCs without authentication of clients: the admin service is denied
+func adminDeniedStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	if serviceOf(info.FullMethod) == pb.Admin_ServiceDesc.ServiceName {
+		return errAdminDenied()
+	}
+
+	return handler(srv, ss)
+}
+
+// Error of the admin service without authentication of clients
+func errAdminDenied() error {
+	return status.Error(codes.PermissionDenied, "admin service requires authentication of clients: TLS_CLIENT_CA or AUTH_TOKENS")
+}
+
+// Interceptor of unary RPCs: authentication of the client and authorisation of the request
+func (a *authT) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	if publicServices[serviceOf(info.FullMethod)] {
+		return handler(ctx, req)
+	}
+
+	access, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = authorize(access, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(context.WithValue(ctx, ctxAccessKey{}, access), req)
+}
+
+// Interceptor of stream RPCs: authentication of the client, authorisation of the stream and of each received message
+func (a *authT) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	if publicServices[serviceOf(info.FullMethod)] {
+		return handler(srv, ss)
+	}
+
+	access, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	err = authorize(access, info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authStreamT{
+		ServerStream: ss,
+		ctx:          context.WithValue(ss.Context(), ctxAccessKey{}, access),
+		access:       access,
+		fullMethod:   info.FullMethod,
+	})
+}
+
+// Stream with authorisation of received messages
+type authStreamT struct {
+	grpc.ServerStream
+	ctx        context.Context // with the access of the client
+	access     accessT
+	fullMethod string
+}
+
+// Context of the stream with the access of the client
+func (s *authStreamT) Context() context.Context {
+	return s.ctx
+}
+
+// Receiving the message. Message of other project - PermissionDenied, the stream is finished
+func (s *authStreamT) RecvMsg(msg any) error {
+
+	err := s.ServerStream.RecvMsg(msg)
+	if err != nil {
+		return err
+	}
+
+	return authorize(s.access, s.fullMethod, msg)
+}
+
+// Id of the token of the client of the request. Empty - without token
+func tokenIdFrom(ctx context.Context) string {
+	access, _ := ctx.Value(ctxAccessKey{}).(accessT)
+	return access.tokenId
+}
+
+// Access of the client: by the token of the authorization header, else by the verified certificate. Without them - Unauthenticated
+func (a *authT) authenticate(ctx context.Context) (accessT, error) {
+
+	if a.tokens != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) != 0 {
+			return a.accessByToken(values[0])
+		}
+	}
+
+	if a.certs {
+		if p, ok := peer.FromContext(ctx); ok {
+			info, ok := p.AuthInfo.(credentials.TLSInfo)
+			if ok && len(info.State.VerifiedChains) != 0 && len(info.State.VerifiedChains[0]) != 0 {
+				return a.projects.access(info.State.VerifiedChains[0][0]), nil
+			}
+		}
+	}
+
+	switch {
+	case a.tokens != nil && a.certs:
+		return accessT{}, status.Error(codes.Unauthenticated, "token or client certificate is required")
+	case a.tokens != nil:
+		return accessT{}, status.Error(codes.Unauthenticated, "token is required")
+	default:
+		return accessT{}, status.Error(codes.Unauthenticated, "client certificate is required")
+	}
+}
+
+// Access of the client by the value of the authorization header: Bearer <secret>
+func (a *authT) accessByToken(header string) (accessT, error) {
+
+	scheme, secret, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") || secret == "" {
+		return accessT{}, status.Error(codes.Unauthenticated, "authorization is not allowed: want Bearer <token>")
+	}
+
+	token, err := a.tokens.TokenBySecret(strings.TrimSpace(secret))
+	if errors.Is(err, db.ErrNotFound) {
+		return accessT{}, status.Error(codes.Unauthenticated, "token is not valid")
+	}
+	if err != nil {
+		return accessT{}, statusByError(err)
+	}
+	if !token.Active(time.Now()) {
+		return accessT{}, status.Errorf(codes.Unauthenticated, "token {%s} is revoked or expired", token.Id)
+	}
+
+	access := accessT{
+		identity:   "token " + token.Id,
+		tokenId:    token.Id,
+		projects:   make(map[string]bool),
+		operations: make(map[string]bool),
+	}
+	for _, project := range token.Projects {
+		if project == db.AllProjects {
+			access.all = true
+		}
+		access.projects[project] = true
+	}
+	for _, op := range token.Operations {
+		access.operations[op] = true
+	}
+	// as certificates: admin - only with db.AllProjects
+	access.operations[db.OperationAdmin] = access.operations[db.OperationAdmin] && access.all
+
+	return access, nil
+}
+
+// Access of the client by the certificate: write and read of projects, admin - with db.AllProjects.
+// nil projects - access to all
+func (m clientProjectsT) access(cert *x509.Certificate) accessT {
+
+	ids := certIdentities(cert)
+	access := accessT{
+		identity:   cert.Subject.CommonName,
+		all:        m == nil,
+		projects:   make(map[string]bool),
+		operations: map[string]bool{db.OperationWrite: true, db.OperationRead: true},
+	}
+	if access.identity == "" && len(ids) > 0 {
+		access.identity = ids[0]
+	}
+
+	for _, id := range ids {
+		for _, project := range m[id] {
+			if project == db.AllProjects {
+				access.all = true
+			}
+			access.projects[project] = true
+		}
+	}
+	access.operations[db.OperationAdmin] = access.all
+
+	return access
+}
+
+// Identities of the certificate: subject CN, DNS, email, URI of SAN
+func certIdentities(cert *x509.Certificate) []string {
+
+	var ids []string
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+
+	return ids
+}
+
+// Authorisation of the request of the RPC: the operation of the method is allowed, nameProject of requests and
+// of messages of batches - only allowed projects. The admin service is not bound to projects. req nil - only the operation is checked
+func authorize(access accessT, fullMethod string, req any) error {
+
+	op, ok := operationsByMethod[fullMethod]
+	if !ok {
+		op = db.OperationAdmin
+	}
+	if !access.operations[op] {
+		return status.Errorf(codes.PermissionDenied, "client {%s} has no access to {%s} operations", access.identity, op)
+	}
+	if op == db.OperationAdmin {
+		return nil
+	}
+
+	switch r := req.(type) {
+	case *pb.BatchRequest:
+		for _, msg := range r.GetMessages() {
+			err := access.checkProject(msg.GetNameProject())
+			if err != nil {
+				return err
+			}
+		}
+	case interface{ GetNameProject() string }:
+		return access.checkProject(r.GetNameProject())
+	}
+
+	return nil
+}
+
+// Check the project is allowed. Empty project (all projects for reading) - only for access to all
+func (a accessT) checkProject(project string) error {
+
+	if a.all || a.projects[project] {
+		return nil
+	}
+	if project == "" {
+		return status.Errorf(codes.PermissionDenied, "client {%s} must set nameProject", a.identity)
+	}
+
+	return status.Errorf(codes.PermissionDenied, "client {%s} has no access to project {%s}", a.identity, project)
+}
+
+// Service of the full name of the method: /apigrps.iwe/SaveMessage - apigrps.iwe
+func serviceOf(fullMethod string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service
+}
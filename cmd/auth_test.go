@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+)
+
+// Certificate and key generated for tests
+type testCertT struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// Create the certificate signed by parent, nil parent - self-signed CA
+func createCert(t *testing.T, tmpl *x509.Certificate, parent *testCertT) testCertT {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signCert, signKey := tmpl, key
+	if parent != nil {
+		signCert, signKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signCert, &key.PublicKey, signKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCertT{cert: cert, key: key, der: der}
+}
+
+// Create the CA of tests
+func createCA(t *testing.T, name string) testCertT {
+	return createCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil)
+}
+
+// Create the certificate of the client signed by the CA
+func createClientCert(t *testing.T, ca testCertT, cn string, uris ...string) testCertT {
+
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+
+	return createCert(t, tmpl, &ca)
+}
+
+// Writing the certificate and the key as PEM files. Return paths of the certificate and the key
+func writeCert(t *testing.T, c testCertT, name string) (string, string) {
+	t.Helper()
+
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	pathCert := filepath.Join(dir, name+".crt")
+	pathKey := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(pathCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	require.NoError(t, os.WriteFile(pathKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return pathCert, pathKey
+}
+
+// Start up the in-process server with mTLS by the config and projects of clients. Return the dial by the certificate of
+// the client (nil - without it), the CA of clients
+func startMTLSServer(t *testing.T, projects string) (func(client *testCertT) *grpc.ClientConn, testCertT) {
+	t.Helper()
+
+	ca := createCA(t, "clients CA")
+	pathCA, _ := writeCert(t, ca, "ca")
+	srvCert := createCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil)
+	pathPublic, pathPrivate := writeCert(t, srvCert, "server")
+	pathProjects := filepath.Join(t.TempDir(), "projects.json")
+	require.NoError(t, os.WriteFile(pathProjects, []byte(projects), 0o600))
+
+	cfg := configT{
+		Server: serverCfgT{PathPublicKey: pathPublic, PathPrivateKey: pathPrivate},
+		Auth:   authCfgT{ClientCA: pathCA, ClientProjectsFile: pathProjects},
+	}
+
+	creds, mtls, err := newServerCreds(cfg)
+	require.NoError(t, err)
+	require.True(t, mtls)
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	objDB := openTestStorage(t, true)
+	auth, err := newAuth(cfg.Auth, true, objDB)
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(auth.streamInterceptor))
+	pb.RegisterIweServer(srv, &server{db: objDB, broker: brk})
+	pb.RegisterAdminServer(srv, &adminServer{db: objDB})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	dial := func(client *testCertT) *grpc.ClientConn {
+		pool := x509.NewCertPool()
+		pool.AddCert(srvCert.cert)
+		cfg := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+		if client != nil {
+			cfg.Certificates = []tls.Certificate{{Certificate: [][]byte{client.der}, PrivateKey: client.key}}
+		}
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithTransportCredentials(credentials.NewTLS(cfg)),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	return dial, ca
+}
+
+// Message of the project for tests
+func testMessage(project string) *pb.MessageRequest {
+	return &pb.MessageRequest{TypeMessage: "I", NameProject: project, LocationEvent: "main.go:1", BodyMessage: "started"}
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Clients write and read only own projects: by CN, by URI of SAN, "*" - all projects and the admin service
+func Test_mTLS_Projects_SUCCESS(t *testing.T) {
+
+	dial, ca := startMTLSServer(t, `{"svc-alpha": ["alpha"], "spiffe://corp/beta": ["beta", "gamma"], "ops": ["*"]}`)
+
+	type tCase struct {
+		nameTest string
+		cn       string
+		uris     []string
+		allowed  []string
+		denied   []string
+		admin    bool
+	}
+
+	tests := []tCase{
+		{nameTest: "by CN", cn: "svc-alpha", allowed: []string{"alpha"}, denied: []string{"beta", "gamma"}},
+		{nameTest: "by URI of SAN", cn: "beta-1", uris: []string{"spiffe://corp/beta"}, allowed: []string{"beta", "gamma"}, denied: []string{"alpha"}},
+		{nameTest: "all projects", cn: "ops", allowed: []string{"alpha", "beta", "other"}, admin: true},
+		{nameTest: "not mapped", cn: "guest", denied: []string{"alpha"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			cert := createClientCert(t, ca, tt.cn, tt.uris...)
+			conn := dial(&cert)
+			iwe := pb.NewIweClient(conn)
+			ctx := context.Background()
+
+			for _, project := range tt.allowed {
+				_, err := iwe.SaveMessage(ctx, testMessage(project))
+				require.NoError(t, err, project)
+				resp, err := iwe.QueryMessages(ctx, &pb.QueryRequest{NameProject: project})
+				require.NoError(t, err, project)
+				assert.NotEmpty(t, resp.GetMessages(), project)
+			}
+			for _, project := range tt.denied {
+				_, err := iwe.SaveMessage(ctx, testMessage(project))
+				assert.Equal(t, codes.PermissionDenied, status.Code(err), project)
+				_, err = iwe.SaveMessages(ctx, &pb.BatchRequest{Messages: []*pb.MessageRequest{testMessage(project)}})
+				assert.Equal(t, codes.PermissionDenied, status.Code(err), project)
+				_, err = iwe.QueryMessages(ctx, &pb.QueryRequest{NameProject: project})
+				assert.Equal(t, codes.PermissionDenied, status.Code(err), project)
+				_, err = iwe.SearchMessages(ctx, &pb.SearchRequest{Query: "started", NameProject: project})
+				assert.Equal(t, codes.PermissionDenied, status.Code(err), project)
+			}
+
+			// all projects are read only with access to all
+			_, err := iwe.QueryMessages(ctx, &pb.QueryRequest{})
+			_, errAdmin := pb.NewAdminClient(conn).ListPartitions(ctx, &pb.PartitionsRequest{})
+			if tt.admin {
+				require.NoError(t, err)
+				require.NoError(t, errAdmin)
+			} else {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+				assert.Equal(t, codes.PermissionDenied, status.Code(errAdmin))
+			}
+
+			// health is open to any client of the CA
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Test - Messages of the stream are checked one by one, the stream with other project is finished
+func Test_mTLS_StreamMessages_SUCCESS(t *testing.T) {
+
+	dial, ca := startMTLSServer(t, `{"svc-alpha": ["alpha"]}`)
+	cert := createClientCert(t, ca, "svc-alpha")
+	iwe := pb.NewIweClient(dial(&cert))
+
+	stream, err := iwe.StreamMessages(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(testMessage("alpha")))
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Len(t, resp.GetResults(), 1)
+
+	stream, err = iwe.StreamMessages(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(testMessage("beta")))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	tail, err := iwe.TailMessages(context.Background(), &pb.TailRequest{NameProject: "beta"})
+	require.NoError(t, err)
+	_, err = tail.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Clients without the certificate or with the certificate of other CA are not connected
+func Test_mTLS_FAULT(t *testing.T) {
+
+	dial, _ := startMTLSServer(t, `{"svc-alpha": ["alpha"]}`)
+	other := createClientCert(t, createCA(t, "other CA"), "svc-alpha")
+
+	type tCase struct {
+		nameTest string
+		cert     *testCertT
+	}
+
+	tests := []tCase{
+		{nameTest: "without certificate", cert: nil},
+		{nameTest: "other CA", cert: &other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			_, err := pb.NewIweClient(dial(tt.cert)).SaveMessage(ctx, testMessage("alpha"))
+			require.Error(t, err)
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+		})
+	}
+}
+
+// Test - Without authentication of clients the admin service is denied, other services are served
+func Test_adminDeniedInterceptor_FAULT(t *testing.T) {
+
+	handler := func(ctx context.Context, req any) (any, error) { return &pb.PartitionsResponse{}, nil }
+
+	for _, method := range []string{pb.Admin_ListPartitions_FullMethodName, pb.Admin_ApplyRetention_FullMethodName, pb.Admin_CreateToken_FullMethodName} {
+		_, err := adminDeniedInterceptor(context.Background(), &pb.PartitionsRequest{}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), method)
+	}
+
+	_, err := adminDeniedInterceptor(context.Background(), testMessage("alpha"), &grpc.UnaryServerInfo{FullMethod: pb.Iwe_SaveMessage_FullMethodName}, handler)
+	require.NoError(t, err)
+}
+
+// Test - Not allowed settings of mTLS
+func Test_newServerCreds_FAULT(t *testing.T) {
+
+	srvCert := createCA(t, "localhost")
+	pathPublic, pathPrivate := writeCert(t, srvCert, "server")
+	pathEmpty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(pathEmpty, []byte("not a certificate"), 0o600))
+
+	type tCase struct {
+		nameTest string
+		server   serverCfgT
+		auth     authCfgT
+	}
+
+	tests := []tCase{
+		{nameTest: "no key pair", server: serverCfgT{PathPublicKey: pathEmpty, PathPrivateKey: pathPrivate}},
+		{nameTest: "no file of CA", server: serverCfgT{PathPublicKey: pathPublic, PathPrivateKey: pathPrivate},
+			auth: authCfgT{ClientCA: filepath.Join(t.TempDir(), "none.pem")}},
+		{nameTest: "CA without certificates", server: serverCfgT{PathPublicKey: pathPublic, PathPrivateKey: pathPrivate},
+			auth: authCfgT{ClientCA: pathEmpty}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			_, _, err := newServerCreds(configT{Server: tt.server, Auth: tt.auth})
+			require.Error(t, err)
+		})
+	}
+
+	_, err := newClientProjects(pathEmpty)
+	require.Error(t, err)
+}
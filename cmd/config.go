@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Config of the server. Values are taken by precedence: flags, env, the YAML file (-config or CONFIG_FILE), defaults.
+// The optional .env file is loaded into env without overriding set variables. Field tags: yaml - key of the file,
+// env - variable (the flag is its name in lower case with "-": PATH_PUBLIC_KEY - -path-public-key), help - usage of the flag
+type configT struct {
+	Server    serverCfgT    `yaml:"server"`
+	Auth      authCfgT      `yaml:"auth"`
+	Limits    limitsCfgT    `yaml:"limits"`
+	Health    healthCfgT    `yaml:"health"`
+	Storage   storageCfgT   `yaml:"storage"`
+	EventTime eventTimeCfgT `yaml:"eventTime"`
+	Tail      tailCfgT      `yaml:"tail"`
+	Retention retentionCfgT `yaml:"retention"`
+}
+
+// Listener of gRPC and the HTTP port of metrics
+type serverCfgT struct {
+	Port            string        `yaml:"port" env:"PORT" help:"address of gRPC: host:port"`
+	PathPublicKey   string        `yaml:"pathPublicKey" env:"PATH_PUBLIC_KEY" help:"certificate of the server"`
+	PathPrivateKey  string        `yaml:"pathPrivateKey" env:"PATH_PRIVATE_KEY" help:"private key of the server"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" help:"in-flight requests are completed on SIGTERM, then cancelled"`
+	Reflection      bool          `yaml:"reflection" env:"GRPC_REFLECTION" help:"server reflection for grpcurl"`
+	MetricsPort     string        `yaml:"metricsPort" env:"METRICS_PORT" help:"HTTP port of Prometheus /metrics. Empty - off"`
+}
+
+// Authentication of clients
+type authCfgT struct {
+	ClientCA           string `yaml:"clientCA" env:"TLS_CLIENT_CA" help:"CA of client certificates, mTLS. Empty - clients without certificates"`
+	ClientProjectsFile string `yaml:"clientProjectsFile" env:"CLIENT_PROJECTS_FILE" help:"JSON of projects by identities of client certificates"`
+	Tokens             bool   `yaml:"tokens" env:"AUTH_TOKENS" help:"clients are authenticated by tokens: authorization: Bearer <token>"`
+}
+
+// Rate limits and quotas of saving messages
+type limitsCfgT struct {
+	File     string `yaml:"file" env:"RATE_LIMITS_FILE" help:"JSON of limits by project. Empty - no limits"`
+	ByClient bool   `yaml:"byClient" env:"RATE_LIMIT_BY_CLIENT" help:"limits of each client of the project"`
+}
+
+// Readiness checks and the healthcheck subcommand
+type healthCfgT struct {
+	Interval   time.Duration `yaml:"interval" env:"HEALTH_INTERVAL" help:"period of readiness checks"`
+	MinFreeMB  uint64        `yaml:"minFreeMB" env:"HEALTH_MIN_FREE_MB" help:"free disk space of the SQLite file, MB"`
+	ServerName string        `yaml:"serverName" env:"HEALTHCHECK_SERVER_NAME" help:"name of the server certificate for healthcheck. Empty - localhost"`
+	PathCert   string        `yaml:"pathCert" env:"HEALTHCHECK_CERT" help:"certificate of the client for healthcheck with TLS_CLIENT_CA"`
+	PathKey    string        `yaml:"pathKey" env:"HEALTHCHECK_KEY" help:"private key of the client for healthcheck"`
+}
+
+// Storage of messages
+type storageCfgT struct {
+	Type        string        `yaml:"type" env:"DB_TYPE" help:"driver of the storage: sqlite, postgres, memory"`
+	Name        string        `yaml:"name" env:"DB_NAME" help:"file of SQLite or DSN of PostgreSQL"`
+	MaxIdNumbI  int64         `yaml:"maxIdNumbI" env:"MAX_IDNUMB_LOGI" help:"messages of the log table of info before rotation"`
+	MaxIdNumbW  int64         `yaml:"maxIdNumbW" env:"MAX_IDNUMB_LOGW" help:"messages of the log table of warnings before rotation"`
+	MaxIdNumbE  int64         `yaml:"maxIdNumbE" env:"MAX_IDNUMB_LOGE" help:"messages of the log table of errors before rotation"`
+	DedupWindow time.Duration `yaml:"dedupWindow" env:"DEDUP_WINDOW" help:"repeated messageId of the project is not saved within the window. 0 - off"`
+}
+
+// Bounds of the event time
+type eventTimeCfgT struct {
+	MaxFuture time.Duration `yaml:"maxFuture" env:"EVENT_TIME_MAX_FUTURE" help:"event time later than the server time. 0 - not checked"`
+	MaxPast   time.Duration `yaml:"maxPast" env:"EVENT_TIME_MAX_PAST" help:"event time earlier than the server time. 0 - not checked"`
+	Skew      string        `yaml:"skew" env:"EVENT_TIME_SKEW" help:"flag - save with the skewed flag, reject - InvalidArgument"`
+}
+
+// Subscribers of tail
+type tailCfgT struct {
+	BufferSize int    `yaml:"bufferSize" env:"TAIL_BUFFER_SIZE" help:"messages in the buffer of the subscriber"`
+	SlowPolicy string `yaml:"slowPolicy" env:"TAIL_SLOW_POLICY" help:"slow subscriber: drop, disconnect"`
+}
+
+// Retention of log tables. Zero - no rule
+type retentionCfgT struct {
+	KeepI      int           `yaml:"keepI" env:"RETENTION_KEEP_LOGI" help:"number of the newest log tables of info to keep"`
+	KeepW      int           `yaml:"keepW" env:"RETENTION_KEEP_LOGW" help:"number of the newest log tables of warnings to keep"`
+	KeepE      int           `yaml:"keepE" env:"RETENTION_KEEP_LOGE" help:"number of the newest log tables of errors to keep"`
+	DaysI      int           `yaml:"daysI" env:"RETENTION_DAYS_LOGI" help:"closed log tables of info older are removed"`
+	DaysW      int           `yaml:"daysW" env:"RETENTION_DAYS_LOGW" help:"closed log tables of warnings older are removed"`
+	DaysE      int           `yaml:"daysE" env:"RETENTION_DAYS_LOGE" help:"closed log tables of errors older are removed"`
+	DirArchive string        `yaml:"dirArchive" env:"RETENTION_DIR_ARCHIVE" help:"export before removing. Empty - no export"`
+	Interval   time.Duration `yaml:"interval" env:"RETENTION_INTERVAL" help:"period of the scheduled retention. 0 - only by admin RPC"`
+}
+
+// Flags of the config: the file and values by env names of fields
+type configFlagsT struct {
+	path   *string
+	values map[string]string // by env name, only set flags
+}
+
+// Flag of the field of config, the value is applied after env
+type configFlagT struct {
+	env    string
+	isBool bool
+	values map[string]string
+}
+
+// Default config
+func defaultConfig() configT {
+	return configT{
+		Server:    serverCfgT{ShutdownTimeout: 10 * time.Second},
+		Health:    healthCfgT{Interval: 10 * time.Second, MinFreeMB: 64},
+		Storage:   storageCfgT{DedupWindow: db.DefaultDedupWindow},
+		EventTime: eventTimeCfgT{MaxFuture: 5 * time.Minute, Skew: "flag"},
+		Tail:      tailCfgT{BufferSize: 256, SlowPolicy: "drop"},
+		Retention: retentionCfgT{Interval: time.Hour},
+	}
+}
+
+// Registration of flags of the config: -config and a flag of each field. Return flags
+func newConfigFlags(flags *flag.FlagSet) *configFlagsT {
+
+	f := &configFlagsT{
+		path:   flags.String("config", "", "YAML file of the config (env CONFIG_FILE)"),
+		values: make(map[string]string),
+	}
+	cfg := defaultConfig()
+	_ = eachField(&cfg, func(v reflect.Value, field reflect.StructField) error {
+		env := field.Tag.Get("env")
+		usage := fmt.Sprintf("%s (env %s)", field.Tag.Get("help"), env)
+		flags.Var(&configFlagT{env: env, isBool: v.Kind() == reflect.Bool, values: f.values}, flagName(env), usage)
+		return nil
+	})
+
+	return f
+}
+
+// Loading of the config: defaults, the file, env (with .env), set flags. The config is checked. Return config, error
+func (f *configFlagsT) load() (configT, error) {
+
+	dotenv, err := godotenv.Read(".env")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return configT{}, fmt.Errorf("fault read env file: %v", err)
+	}
+
+	cfg := defaultConfig()
+
+	path := *f.path
+	if path == "" {
+		path, _ = lookupEnv(dotenv, "CONFIG_FILE")
+	}
+	if path != "" {
+		err := readConfigFile(path, &cfg)
+		if err != nil {
+			return configT{}, err
+		}
+	}
+
+	err = eachField(&cfg, func(v reflect.Value, field reflect.StructField) error {
+		env := field.Tag.Get("env")
+		if s, ok := lookupEnv(dotenv, env); ok {
+			err := setField(v, s)
+			if err != nil {
+				return fmt.Errorf("fault parse %s: {%s}: %v", env, s, err)
+			}
+		}
+		if s, ok := f.values[env]; ok {
+			err := setField(v, s)
+			if err != nil {
+				return fmt.Errorf("fault parse flag -%s: {%s}: %v", flagName(env), s, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return configT{}, err
+	}
+
+	err = cfg.check()
+	if err != nil {
+		return configT{}, err
+	}
+
+	return cfg, nil
+}
+
+// Value of the env variable: the set env of the process, even empty, else not empty value of .env.
+// Return value, false - not set
+func lookupEnv(dotenv map[string]string, env string) (string, bool) {
+
+	if s, ok := os.LookupEnv(env); ok {
+		return s, true
+	}
+	if s := dotenv[env]; s != "" {
+		return s, true
+	}
+
+	return "", false
+}
+
+// Check values of the config. Return error
+func (c configT) check() error {
+
+	switch {
+	case c.Server.ShutdownTimeout <= 0:
+		return fmt.Errorf("not allowed SHUTDOWN_TIMEOUT: {%s}, want > 0", c.Server.ShutdownTimeout)
+	case c.Health.Interval <= 0:
+		return fmt.Errorf("not allowed HEALTH_INTERVAL: {%s}, want > 0", c.Health.Interval)
+	case c.EventTime.MaxFuture < 0:
+		return fmt.Errorf("not allowed EVENT_TIME_MAX_FUTURE: {%s}", c.EventTime.MaxFuture)
+	case c.EventTime.MaxPast < 0:
+		return fmt.Errorf("not allowed EVENT_TIME_MAX_PAST: {%s}", c.EventTime.MaxPast)
+	case c.EventTime.Skew != "flag" && c.EventTime.Skew != "reject":
+		return fmt.Errorf("not allowed EVENT_TIME_SKEW: {%s}, want flag, reject", c.EventTime.Skew)
+	case c.Tail.BufferSize <= 0:
+		return fmt.Errorf("not allowed TAIL_BUFFER_SIZE: {%d}, want > 0", c.Tail.BufferSize)
+	case c.Retention.Interval < 0:
+		return fmt.Errorf("not allowed RETENTION_INTERVAL: {%s}", c.Retention.Interval)
+	}
+
+	// MAX_IDNUMB_LOG*, DEDUP_WINDOW - by the check of the storage
+	err := c.storage().Check()
+	if err != nil {
+		return err
+	}
+	_, err = broker.ParsePolicy(c.Tail.SlowPolicy)
+	if err != nil {
+		return fmt.Errorf("not allowed TAIL_SLOW_POLICY: %v", err)
+	}
+	for typeTable, n := range map[string]int{
+		"KEEP_LOGI": c.Retention.KeepI, "KEEP_LOGW": c.Retention.KeepW, "KEEP_LOGE": c.Retention.KeepE,
+		"DAYS_LOGI": c.Retention.DaysI, "DAYS_LOGW": c.Retention.DaysW, "DAYS_LOGE": c.Retention.DaysE,
+	} {
+		if n < 0 {
+			return fmt.Errorf("not allowed RETENTION_%s: {%d}", typeTable, n)
+		}
+	}
+
+	return nil
+}
+
+// Config of the storage driver
+func (c configT) storage() db.DriverConfigT {
+	return db.DriverConfigT{
+		Type: c.Storage.Type,
+		Name: c.Storage.Name,
+		ConfigT: db.ConfigT{
+			MaxIdNumbI:  c.Storage.MaxIdNumbI,
+			MaxIdNumbW:  c.Storage.MaxIdNumbW,
+			MaxIdNumbE:  c.Storage.MaxIdNumbE,
+			DedupWindow: c.Storage.DedupWindow,
+		},
+	}
+}
+
+// Value of the flag
+func (f *configFlagT) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.env]
+}
+
+// Setting of the flag. The value is checked on loading of the config
+func (f *configFlagT) Set(s string) error {
+	f.values[f.env] = s
+	return nil
+}
+
+// Flags of bool fields are set without value: -auth-tokens
+func (f *configFlagT) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// Reading the YAML file of the config over the current values. Unknown keys are faults
+func readConfigFile(path string, cfg *configT) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("fault read config file: %v", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("fault parse config file {%s}: %v", path, err)
+	}
+
+	return nil
+}
+
+// Walk of fields of sections of the config
+func eachField(cfg *configT, fn func(v reflect.Value, field reflect.StructField) error) error {
+
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := range sections.NumField() {
+		section := sections.Field(i)
+		for j := range section.NumField() {
+			err := fn(section.Field(j), section.Type().Field(j))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Setting of the field by the string. Return error
+func setField(v reflect.Value, s string) error {
+
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("not supported type of field: {%s}", v.Type())
+	}
+
+	return nil
+}
+
+// Name of the flag by the env name: PATH_PUBLIC_KEY - path-public-key
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
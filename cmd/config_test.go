@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Unsetting env variables of the config, they are restored after the test
+func unsetConfigEnv(t *testing.T) {
+	t.Helper()
+
+	cfg := defaultConfig()
+	require.NoError(t, eachField(&cfg, func(v reflect.Value, field reflect.StructField) error {
+		env := field.Tag.Get("env")
+		t.Setenv(env, "")
+		return os.Unsetenv(env)
+	}))
+}
+
+// Loading of the config by the file and arguments in the directory without .env. Return config, error
+func loadTestConfig(t *testing.T, file string, args ...string) (configT, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("CONFIG_FILE", "")
+	if file != "" {
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+		t.Setenv("CONFIG_FILE", path)
+	}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	cfgFlags := newConfigFlags(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return configT{}, err
+	}
+
+	return cfgFlags.load()
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Values are taken by precedence: flags, env, the file, defaults
+func Test_loadConfig_SUCCESS(t *testing.T) {
+
+	unsetConfigEnv(t)
+	t.Setenv("MAX_IDNUMB_LOGE", "300")
+	t.Setenv("AUTH_TOKENS", "true")
+	t.Setenv("METRICS_PORT", "")
+
+	file := `
+server:
+  port: ":50051"
+  metricsPort: ":9090"
+storage:
+  type: sqlite
+  maxIdNumbI: 100
+  maxIdNumbW: 200
+  maxIdNumbE: 200
+tail:
+  slowPolicy: disconnect
+`
+	cfg, err := loadTestConfig(t, file, "-max-idnumb-logi", "1000", "-grpc-reflection", "-dedup-window", "1h")
+	require.NoError(t, err)
+
+	assert.Equal(t, ":50051", cfg.Server.Port)
+	assert.Empty(t, cfg.Server.MetricsPort, "set but empty env overrides the file")
+	assert.True(t, cfg.Server.Reflection)
+	assert.True(t, cfg.Auth.Tokens)
+	assert.Equal(t, "disconnect", cfg.Tail.SlowPolicy)
+	assert.Equal(t, 256, cfg.Tail.BufferSize)
+	assert.Equal(t, 10*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, db.DriverConfigT{
+		Type:    "sqlite",
+		ConfigT: db.ConfigT{MaxIdNumbI: 1000, MaxIdNumbW: 200, MaxIdNumbE: 300, DedupWindow: time.Hour},
+	}, cfg.storage())
+
+	// without the file: defaults
+	t.Setenv("MAX_IDNUMB_LOGI", "10")
+	t.Setenv("MAX_IDNUMB_LOGW", "10")
+	cfg, err = loadTestConfig(t, "")
+	require.NoError(t, err)
+	assert.Equal(t, db.DefaultDedupWindow, cfg.Storage.DedupWindow)
+	assert.Equal(t, db.EventTimePolicyT{MaxFuture: 5 * time.Minute}, newEventTimePolicy(cfg.EventTime))
+
+	// .env: empty values do not override the file, the set env of the process does
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("server:\n  port: \":50051\"\n  metricsPort: \":9090\"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"),
+		[]byte("CONFIG_FILE=\"config.yaml\"\nPORT=\"\"\nMETRICS_PORT=\"\"\nTAIL_BUFFER_SIZE=\"64\"\n"), 0o600))
+	unsetConfigEnv(t)
+	t.Setenv("CONFIG_FILE", "")
+	require.NoError(t, os.Unsetenv("CONFIG_FILE"))
+	t.Setenv("MAX_IDNUMB_LOGI", "10")
+	t.Setenv("MAX_IDNUMB_LOGW", "10")
+	t.Setenv("MAX_IDNUMB_LOGE", "10")
+	t.Setenv("PORT", "")
+	cfg, err = newConfigFlags(flag.NewFlagSet("test", flag.ContinueOnError)).load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Server.Port)
+	assert.Equal(t, ":9090", cfg.Server.MetricsPort)
+	assert.Equal(t, 64, cfg.Tail.BufferSize)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Not allowed values of the config
+func Test_loadConfig_FAULT(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		file     string
+		env      map[string]string
+		args     []string
+		cause    string // text of the cause in the error
+	}{
+		{nameTest: "not number of env", env: map[string]string{"MAX_IDNUMB_LOGI": "many"}, cause: "invalid syntax"},
+		{nameTest: "not duration of flag", args: []string{"-shutdown-timeout", "10"}, cause: "missing unit"},
+		{nameTest: "unknown flag", args: []string{"-max-idnumb"}},
+		{nameTest: "unknown key of the file", file: "storage:\n  maxIdNumb: 100\n"},
+		{nameTest: "not allowed skew", env: map[string]string{"EVENT_TIME_SKEW": "drop"}},
+		{nameTest: "not allowed policy", file: "tail:\n  slowPolicy: block\n"},
+		{nameTest: "negative retention", args: []string{"-retention-keep-logi", "-1"}},
+		{nameTest: "zero timeout", args: []string{"-shutdown-timeout", "0s"}},
+		{nameTest: "zero max of env", env: map[string]string{"MAX_IDNUMB_LOGE": "0"}},
+		{nameTest: "empty env of max", file: "storage:\n  maxIdNumbW: 100\n", env: map[string]string{"MAX_IDNUMB_LOGW": ""}},
+		{nameTest: "zero max of flag", args: []string{"-max-idnumb-logi", "0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			// allowed max, so the config fails by the value of the test
+			unsetConfigEnv(t)
+			for _, env := range []string{"MAX_IDNUMB_LOGI", "MAX_IDNUMB_LOGW", "MAX_IDNUMB_LOGE"} {
+				t.Setenv(env, "10")
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := loadTestConfig(t, tt.file, tt.args...)
+			require.Error(t, err)
+			if tt.cause != "" {
+				assert.ErrorContains(t, err, tt.cause)
+			}
+		})
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "none.yaml"))
+	_, err := newConfigFlags(flag.NewFlagSet("test", flag.ContinueOnError)).load()
+	require.Error(t, err)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "math"
+
+// Free disk space is not checked on this platform
+func freeDisk(dir string) (uint64, error) {
+	return math.MaxUint64, nil
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Free disk space of the directory available to the user, bytes
+func freeDisk(dir string) (uint64, error) {
+
+	var st syscall.Statfs_t
+	err := syscall.Statfs(dir, &st)
+	if err != nil {
+		return 0, fmt.Errorf("fault read free disk space of {%s}: %v", dir, err)
+	}
+
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
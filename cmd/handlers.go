@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
+)
+
+// Number of streamed messages saved in one transaction
+const sizeChunkStream = 500
+
+// Handler
+func (s *server) SaveMessage(ctx context.Context, req *pb.MessageRequest) (*pb.MessageResponse, error) {
+
+	received := timestamppb.Now()
+	msg := messageFromRequest(req)
+	msg.TokenId = tokenIdFrom(ctx)
+	metrics.MessageReceived(msg.TypeMessage, msg.NameProject)
+
+	if msg.TypeMessage == "T" {
+		return &pb.MessageResponse{Status: "Ok", Code: pb.SaveStatus_SAVE_STATUS_SKIPPED, Received: received}, nil
+	}
+
+	err := s.eventTime.Apply(&msg, received.AsTime())
+	if err != nil {
+		metrics.MessageRejected(msg.TypeMessage, msg.NameProject, rejectReason(err))
+		return nil, statusByError(err)
+	}
+
+	ref, err := s.db.SavingMessage(msg)
+	if errors.Is(err, db.ErrDuplicate) {
+		metrics.MessageRejected(msg.TypeMessage, msg.NameProject, metrics.ReasonDuplicate)
+		return &pb.MessageResponse{Status: "Ok", Duplicate: true, Code: pb.SaveStatus_SAVE_STATUS_DUPLICATE, Received: received}, nil
+	}
+
+	//err := db.StoreMessage(s.db, msg)
+	if err != nil {
+		metrics.MessageRejected(msg.TypeMessage, msg.NameProject, rejectReason(err))
+		return nil, statusByError(err)
+	}
+	metrics.MessageStored(msg.TypeMessage, msg.NameProject)
+	s.broker.Publish(msg)
+
+	return &pb.MessageResponse{
+		Status:   "Ok",
+		Code:     pb.SaveStatus_SAVE_STATUS_STORED,
+		Ref:      refToPb(ref),
+		Received: received,
+	}, nil
+}
+
+// Handler
+func (s *server) SaveMessages(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+
+	resp := &pb.BatchResponse{Received: timestamppb.Now()}
+
+	err := s.savingBatch(req.GetMessages(), 0, tokenIdFrom(ctx), resp)
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *server) StreamMessages(stream pb.Iwe_StreamMessagesServer) error {
+
+	resp := &pb.BatchResponse{}
+	chunk := make([]*pb.MessageRequest, 0, sizeChunkStream)
+	var first int32
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return withPartialResult(status.Convert(err), resp)
+		}
+		if resp.Received == nil {
+			resp.Received = timestamppb.Now()
+		}
+
+		chunk = append(chunk, req)
+		if len(chunk) < sizeChunkStream {
+			continue
+		}
+
+		err = s.savingBatch(chunk, first, tokenIdFrom(stream.Context()), resp)
+		if err != nil {
+			return withPartialResult(status.Convert(statusByError(err)), resp)
+		}
+		reportSaved(stream.Context(), resp.Results[first:])
+		first += int32(len(chunk))
+		chunk = chunk[:0]
+	}
+
+	err := s.savingBatch(chunk, first, tokenIdFrom(stream.Context()), resp)
+	if err != nil {
+		return withPartialResult(status.Convert(statusByError(err)), resp)
+	}
+
+	return stream.SendAndClose(resp)
+}
+
+// Handler
+func (s *server) QueryMessages(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+
+	var filter = db.FilterT{}
+
+	filter.TypeMessage = req.GetTypeMessage()
+	filter.NameProject = req.GetNameProject()
+	filter.LocationEvent = req.GetLocationEvent()
+	filter.BodySubstr = req.GetBodySubstring()
+	filter.Limit = int(req.GetLimit())
+	filter.Offset = int(req.GetOffset())
+	if req.GetTimeFrom() != nil {
+		filter.TimeFrom = req.GetTimeFrom().AsTime()
+	}
+	if req.GetTimeTo() != nil {
+		filter.TimeTo = req.GetTimeTo().AsTime()
+	}
+	filter.ByEventTime = req.GetOrder() == pb.TimeOrder_TIME_ORDER_EVENT
+	filter.Attributes = req.GetAttributes()
+
+	msgs, err := s.db.ReadingMessages(filter)
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	resp := &pb.QueryResponse{Messages: make([]*pb.StoredMessage, 0, len(msgs))}
+	for _, msg := range msgs {
+		resp.Messages = append(resp.Messages, storedToPb(msg))
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *server) SearchMessages(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+
+	var search = db.SearchT{}
+
+	search.Query = req.GetQuery()
+	search.TypeMessage = req.GetTypeMessage()
+	search.NameProject = req.GetNameProject()
+	search.Attributes = req.GetAttributes()
+	search.Limit = int(req.GetLimit())
+	search.Offset = int(req.GetOffset())
+	search.MarkStart = req.GetHighlightStart()
+	search.MarkEnd = req.GetHighlightEnd()
+	if req.GetTimeFrom() != nil {
+		search.TimeFrom = req.GetTimeFrom().AsTime()
+	}
+	if req.GetTimeTo() != nil {
+		search.TimeTo = req.GetTimeTo().AsTime()
+	}
+
+	msgs, err := s.db.SearchMessages(search)
+	if err != nil {
+		return nil, statusByError(err)
+	}
+
+	resp := &pb.SearchResponse{Messages: make([]*pb.FoundMessage, 0, len(msgs))}
+	for _, msg := range msgs {
+		resp.Messages = append(resp.Messages, &pb.FoundMessage{
+			Message: storedToPb(msg.StoredMessageT),
+			Rank:    msg.Rank,
+			Snippet: msg.Snippet,
+		})
+	}
+
+	return resp, nil
+}
+
+// Handler
+func (s *server) TailMessages(req *pb.TailRequest, stream pb.Iwe_TailMessagesServer) error {
+
+	switch req.GetTypeMessage() {
+	case "", "I", "W", "E":
+	default:
+		return status.Errorf(codes.InvalidArgument, "not allowed type of message: {%s}", req.GetTypeMessage())
+	}
+
+	sub := s.broker.Subscribe(broker.FilterT{
+		TypeMessage: req.GetTypeMessage(),
+		NameProject: req.GetNameProject(),
+	})
+	defer s.broker.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-sub.C:
+			if !ok && s.broker.Closed() {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber is too slow, disconnected")
+			}
+			err := stream.Send(&pb.StoredMessage{
+				TypeMessage:   ev.TypeMessage,
+				NameProject:   ev.NameProject,
+				LocationEvent: ev.LocationEvent,
+				BodyMessage:   ev.BodyMessage,
+				Timestamp:     timestamppb.New(ev.Received),
+				EventTime:     timestamppb.New(eventTimeOrReceived(ev)),
+				Skewed:        ev.Skewed,
+				Attributes:    ev.Attributes,
+				TokenId:       ev.TokenId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Saving the batch of messages in one transaction. Results are added to the response, indexes start from first.
+// tokenId - token of the client, saved with messages
+func (s *server) savingBatch(reqs []*pb.MessageRequest, first int32, tokenId string, resp *pb.BatchResponse) error {
+
+	results := make([]*pb.MessageResult, len(reqs))
+	msgs := make([]db.MessageT, 0, len(reqs))
+	pos := make([]int, 0, len(reqs)) // position of the saved message in the batch
+
+	for i, req := range reqs {
+		results[i] = &pb.MessageResult{Index: first + int32(i), Status: "Ok", Code: pb.SaveStatus_SAVE_STATUS_SKIPPED}
+
+		msg := messageFromRequest(req)
+		msg.TokenId = tokenId
+		metrics.MessageReceived(msg.TypeMessage, msg.NameProject)
+		if msg.TypeMessage == "T" {
+			continue
+		}
+		err := s.eventTime.Apply(&msg, resp.GetReceived().AsTime())
+		if err != nil {
+			metrics.MessageRejected(msg.TypeMessage, msg.NameProject, rejectReason(err))
+			results[i].Status = err.Error()
+			results[i].Code = pb.SaveStatus_SAVE_STATUS_FAILED
+			continue
+		}
+		msgs = append(msgs, msg)
+		pos = append(pos, i)
+	}
+
+	if len(msgs) != 0 {
+		refs, errs, err := s.db.SavingMessages(msgs)
+		if err != nil {
+			for _, msg := range msgs {
+				metrics.MessageRejected(msg.TypeMessage, msg.NameProject, rejectReason(err))
+			}
+			return err
+		}
+		for i, err := range errs {
+			res := results[pos[i]]
+			if err != nil {
+				metrics.MessageRejected(msgs[i].TypeMessage, msgs[i].NameProject, rejectReason(err))
+			}
+			if errors.Is(err, db.ErrDuplicate) {
+				res.Duplicate = true
+				res.Code = pb.SaveStatus_SAVE_STATUS_DUPLICATE
+				continue
+			}
+			if err != nil {
+				res.Status = err.Error()
+				res.Code = pb.SaveStatus_SAVE_STATUS_FAILED
+				continue
+			}
+			metrics.MessageStored(msgs[i].TypeMessage, msgs[i].NameProject)
+			res.Code = pb.SaveStatus_SAVE_STATUS_STORED
+			res.Ref = refToPb(refs[i])
+			s.broker.Publish(msgs[i])
+		}
+	}
+
+	for _, res := range results {
+		switch res.Code {
+		case pb.SaveStatus_SAVE_STATUS_STORED:
+			resp.Saved++
+		case pb.SaveStatus_SAVE_STATUS_DUPLICATE:
+			resp.Duplicates++
+		case pb.SaveStatus_SAVE_STATUS_FAILED:
+			resp.Failed++
+		}
+	}
+	resp.Results = append(resp.Results, results...)
+
+	return nil
+}
+
+// Message for saving from the request
+func messageFromRequest(req *pb.MessageRequest) db.MessageT {
+
+	msg := db.MessageT{
+		TypeMessage:   req.GetTypeMessage(),
+		NameProject:   req.GetNameProject(),
+		LocationEvent: req.GetLocationEvent(),
+		BodyMessage:   req.GetBodyMessage(),
+		MessageId:     req.GetMessageId(),
+		Attributes:    req.GetAttributes(),
+	}
+	if req.GetEventTime() != nil {
+		msg.EventTime = req.GetEventTime().AsTime()
+	}
+
+	return msg
+}
+
+// Event time of the accepted message. Not set - the time of receiving
+func eventTimeOrReceived(ev broker.EventT) time.Time {
+	if ev.EventTime.IsZero() {
+		return ev.Received
+	}
+	return ev.EventTime
+}
+
+// Status of gRPC by the error of storage: not allowed request - InvalidArgument with field violations,
+// unknown object - NotFound, temporary fault - Unavailable, others - Internal. Faults of the server are logged
+func statusByError(err error) error {
+
+	var errField *db.FieldError
+	switch {
+	case errors.As(err, &errField):
+		st := status.New(codes.InvalidArgument, errField.Error())
+		st, errDetails := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: errField.Field, Description: errField.Description}},
+		})
+		if errDetails != nil {
+			return status.Error(codes.InvalidArgument, errField.Error())
+		}
+		return st.Err()
+	case errors.Is(err, db.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, db.ErrUnavailable):
+		log.Printf("error: {%v}", err)
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		log.Printf("error: {%v}", err)
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// Error of the stream with results of chunks committed before it: the BatchResponse in details of the status.
+// Without committed chunks - the status as is
+func withPartialResult(st *status.Status, resp *pb.BatchResponse) error {
+
+	if len(resp.GetResults()) == 0 {
+		return st.Err()
+	}
+	withResult, err := st.WithDetails(resp)
+	if err != nil {
+		return st.Err()
+	}
+
+	return withResult.Err()
+}
+
+// Reason of the not stored message for metrics by the error of storage, as statusByError
+func rejectReason(err error) string {
+
+	var errField *db.FieldError
+	switch {
+	case errors.Is(err, db.ErrDuplicate):
+		return metrics.ReasonDuplicate
+	case errors.As(err, &errField), errors.Is(err, db.ErrInvalidArgument):
+		return metrics.ReasonInvalid
+	case errors.Is(err, db.ErrUnavailable):
+		return metrics.ReasonUnavailable
+	default:
+		return metrics.ReasonInternal
+	}
+}
+
+// Stored message for the response
+func storedToPb(msg db.StoredMessageT) *pb.StoredMessage {
+	return &pb.StoredMessage{
+		TypeMessage:   msg.TypeMessage,
+		NameProject:   msg.NameProject,
+		LocationEvent: msg.LocationEvent,
+		BodyMessage:   msg.BodyMessage,
+		Id:            msg.Id,
+		NameTable:     msg.NameTable,
+		Timestamp:     timestamppb.New(msg.Timestamp),
+		EventTime:     timestamppb.New(msg.EventTime),
+		Skewed:        msg.Skewed,
+		Attributes:    msg.Attributes,
+		TokenId:       msg.TokenId,
+	}
+}
+
+// Reference to the stored message for the response
+func refToPb(ref db.RefT) *pb.MessageRef {
+	return &pb.MessageRef{NameTable: ref.NameTable, Id: ref.Id}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Storage of tests: batches after the number of saved ones fail as unavailable
+type failingStorageT struct {
+	db.ActionsDB
+	batches int // saved batches before faults
+}
+
+// Saving messages while batches remain, then the fault
+func (s *failingStorageT) SavingMessages(msgs []db.MessageT) ([]db.RefT, []error, error) {
+
+	if s.batches == 0 {
+		return nil, nil, errors.Join(db.ErrUnavailable, errors.New("database is locked"))
+	}
+	s.batches--
+
+	return s.ActionsDB.SavingMessages(msgs)
+}
+
+// Client stream of tests by the list of requests
+type testStreamT struct {
+	grpc.ServerStream
+	reqs []*pb.MessageRequest
+	resp *pb.BatchResponse
+}
+
+// Receiving the next request, io.EOF after the last
+func (s *testStreamT) Recv() (*pb.MessageRequest, error) {
+
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+
+	return req, nil
+}
+
+// Sending the response
+func (s *testStreamT) SendAndClose(resp *pb.BatchResponse) error {
+	s.resp = resp
+	return nil
+}
+
+// Context of the stream
+func (s *testStreamT) Context() context.Context {
+	return context.Background()
+}
+
+// Receiving the next request as the message of interceptors, io.EOF after the last
+func (s *testStreamT) RecvMsg(msg any) error {
+
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	proto.Merge(msg.(proto.Message), req)
+
+	return nil
+}
+
+// Sending the response as the message of interceptors
+func (s *testStreamT) SendMsg(msg any) error {
+	return s.SendAndClose(msg.(*pb.BatchResponse))
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Counters of the batch: T messages are skipped, not counted as saved
+func Test_SaveMessages_SUCCESS(t *testing.T) {
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: openTestStorage(t, true), broker: brk}
+
+	resp, err := s.SaveMessages(context.Background(), &pb.BatchRequest{Messages: []*pb.MessageRequest{
+		{TypeMessage: "I", NameProject: "batch", LocationEvent: "l", BodyMessage: "one"},
+		{TypeMessage: "T", NameProject: "batch", LocationEvent: "l", BodyMessage: "test"},
+		{TypeMessage: "I", NameProject: "batch", LocationEvent: "l", BodyMessage: "two", MessageId: "id-1"},
+		{TypeMessage: "I", NameProject: "batch", LocationEvent: "l", BodyMessage: "two", MessageId: "id-1"},
+		{TypeMessage: "I", NameProject: "batch", LocationEvent: "l", BodyMessage: ""},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), resp.GetSaved())
+	assert.Equal(t, int32(1), resp.GetDuplicates())
+	assert.Equal(t, int32(1), resp.GetFailed())
+	require.Len(t, resp.GetResults(), 5)
+	assert.Equal(t, pb.SaveStatus_SAVE_STATUS_SKIPPED, resp.GetResults()[1].GetCode())
+}
+
+// Test - Results of committed chunks of the stream are returned in details of the error of the later chunk
+func Test_StreamMessages_SUCCESS(t *testing.T) {
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: &failingStorageT{ActionsDB: openTestStorage(t, true), batches: 1}, broker: brk}
+
+	stream := &testStreamT{reqs: testMessages("stream", sizeChunkStream+10)}
+	err = s.StreamMessages(stream)
+	require.Error(t, err)
+	assert.Nil(t, stream.resp)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	require.Len(t, st.Details(), 1)
+	partial, ok := st.Details()[0].(*pb.BatchResponse)
+	require.True(t, ok)
+	assert.Equal(t, int32(sizeChunkStream), partial.GetSaved())
+	require.Len(t, partial.GetResults(), sizeChunkStream)
+	assert.Equal(t, int32(sizeChunkStream-1), partial.GetResults()[sizeChunkStream-1].GetIndex())
+
+	// the fault of the first chunk: nothing is committed, the status as is
+	s.db = &failingStorageT{ActionsDB: openTestStorage(t, true)}
+	err = s.StreamMessages(&testStreamT{reqs: testMessages("stream", 10)})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Empty(t, status.Convert(err).Details())
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Readiness checks of the health service
+type healthConfigT struct {
+	Interval time.Duration // period of checks
+	DirDisk  string        // directory of the database file. Empty - disk space is not checked
+	MinFree  uint64        // minimal free disk space, bytes
+}
+
+// Services reported by the health service, "" - the whole server
+var healthServices = []string{"", pb.Iwe_ServiceDesc.ServiceName, pb.Admin_ServiceDesc.ServiceName}
+
+// Readiness checks by the config
+func newHealthConfig(c configT) healthConfigT {
+
+	cfg := healthConfigT{Interval: c.Health.Interval, MinFree: c.Health.MinFreeMB << 20}
+	if path := pathSQLite(c.Storage); path != "" {
+		cfg.DirDisk = filepath.Dir(path)
+	}
+
+	return cfg
+}
+
+// Readiness of the server: the storage is reachable, the current log tables of all types are created by Tables,
+// free disk space of the database is not less than minimal
+func checkReadiness(objDB db.ActionsDB, cfg healthConfigT) error {
+
+	parts, err := objDB.Partitions()
+	if err != nil {
+		return fmt.Errorf("storage is not reachable: {%v}", err)
+	}
+
+	active := make(map[string]bool)
+	for _, p := range parts {
+		if p.State == db.StateActive {
+			active[p.TypeTable] = true
+		}
+	}
+	for _, typeTable := range []string{"I", "W", "E"} {
+		if !active[typeTable] {
+			return fmt.Errorf("current log table of {%s} is not created", typeTable)
+		}
+	}
+
+	if cfg.DirDisk == "" {
+		return nil
+	}
+	free, err := freeDisk(cfg.DirDisk)
+	if err != nil {
+		return err
+	}
+	if free < cfg.MinFree {
+		return fmt.Errorf("free disk space {%d} bytes of {%s} is less than {%d}", free, cfg.DirDisk, cfg.MinFree)
+	}
+
+	return nil
+}
+
+// Setting the status of services by readiness checks every interval until ctx is done
+func runHealth(ctx context.Context, hs *health.Server, objDB db.ActionsDB, cfg healthConfigT) {
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var last error
+	for {
+		st := healthpb.HealthCheckResponse_SERVING
+		err := checkReadiness(objDB, cfg)
+		if err != nil {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		// changes are logged once
+		if err != nil && (last == nil || err.Error() != last.Error()) {
+			log.Printf("Health: not serving: {%v}", err)
+		}
+		if err == nil && last != nil {
+			log.Println("Health: serving")
+		}
+		last = err
+
+		for _, name := range healthServices {
+			hs.SetServingStatus(name, st)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Subcommand healthcheck: the status of the server on PORT by grpc.health.v1. Return exit code
+func healthcheck(cfg configT) int {
+
+	address := cfg.Server.Port
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+	creds, err := healthcheckCreds(cfg)
+	if err != nil {
+		log.Println(err)
+		return exitFault
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Printf("fault connect {%s}: %v", address, err)
+		return exitFault
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = checkHealth(ctx, conn)
+	if err != nil {
+		fmt.Println(err)
+		return exitFault
+	}
+	fmt.Println("SERVING")
+
+	return exitOk
+}
+
+// Credentials of the healthcheck: the certificate of the server PATH_PUBLIC_KEY, name HEALTHCHECK_SERVER_NAME;
+// with TLS_CLIENT_CA of the server - the certificate of the client HEALTHCHECK_CERT, HEALTHCHECK_KEY. Return credentials, error
+func healthcheckCreds(c configT) (credentials.TransportCredentials, error) {
+
+	pemCert, err := os.ReadFile(c.Server.PathPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("fault read sertificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCert) {
+		return nil, fmt.Errorf("fault read sertificate: {%s} has no certificates", c.Server.PathPublicKey)
+	}
+	cfg := &tls.Config{RootCAs: pool, ServerName: c.Health.ServerName}
+
+	if path := c.Health.PathCert; path != "" {
+		cert, err := tls.LoadX509KeyPair(path, c.Health.PathKey)
+		if err != nil {
+			return nil, fmt.Errorf("fault read sertificate of the client: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+// Check the server is serving by grpc.health.v1. Return error
+func checkHealth(ctx context.Context, conn *grpc.ClientConn) error {
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("fault check health: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("server is %s", resp.GetStatus())
+	}
+
+	return nil
+}
+
+// File of the SQLite database by DB_TYPE and DB_NAME. Empty for other storages and the in-memory database
+func pathSQLite(cfg storageCfgT) string {
+
+	if cfg.Type != "sqlite" {
+		return ""
+	}
+	name, _, _ := strings.Cut(cfg.Name, "?")
+	name = strings.TrimPrefix(name, "file:")
+	if name == ":memory:" {
+		return ""
+	}
+
+	return name
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Settings of the storage for tests
+func testStorageConfig(maxI, maxW, maxE int64) db.ConfigT {
+	return db.ConfigT{MaxIdNumbI: maxI, MaxIdNumbW: maxW, MaxIdNumbE: maxE, DedupWindow: db.DefaultDedupWindow}
+}
+
+// Open the memory storage. tables - create the current log tables
+func openTestStorage(t *testing.T, tables bool) db.ActionsDB {
+	t.Helper()
+
+	objDB, closeDb, err := db.Open(db.DriverConfigT{Type: "memory", ConfigT: testStorageConfig(100, 100, 100)})
+	require.NoError(t, err)
+	t.Cleanup(func() { closeDb() })
+	if tables {
+		require.NoError(t, objDB.Tables())
+	}
+
+	return objDB
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Readiness of the initialised storage with free disk space
+func Test_checkReadiness_SUCCESS(t *testing.T) {
+
+	objDB := openTestStorage(t, true)
+
+	require.NoError(t, checkReadiness(objDB, healthConfigT{}))
+	require.NoError(t, checkReadiness(objDB, healthConfigT{DirDisk: t.TempDir(), MinFree: 1}))
+}
+
+// Test - Status of the health service by readiness, NOT_SERVING after shutdown
+func Test_checkHealth_SUCCESS(t *testing.T) {
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runHealth(ctx, hs, openTestStorage(t, true), healthConfigT{Interval: 10 * time.Millisecond})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool { return checkHealth(context.Background(), conn) == nil }, 5*time.Second, 10*time.Millisecond)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "apigrps.iwe"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	hs.Shutdown()
+	err = checkHealth(context.Background(), conn)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NOT_SERVING")
+}
+
+// Test - File of the SQLite database by the config
+func Test_pathSQLite_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		typeDB   string
+		name     string
+		want     string
+	}{
+		{nameTest: "File", typeDB: "sqlite", name: "./db/iwe.db", want: "./db/iwe.db"},
+		{nameTest: "URI with options", typeDB: "sqlite", name: "file:/var/lib/iwe.db?_pragma=journal_mode(WAL)", want: "/var/lib/iwe.db"},
+		{nameTest: "Memory", typeDB: "sqlite", name: ":memory:", want: ""},
+		{nameTest: "PostgreSQL", typeDB: "postgres", name: "host=db", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			assert.Equal(t, tt.want, pathSQLite(storageCfgT{Type: tt.typeDB, Name: tt.name}))
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Readiness of the server. Return error
+func Test_checkReadiness_FAULT(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		tables   bool
+		cfg      healthConfigT
+		wantErr  string
+	}{
+		{
+			nameTest: "Tables are not created",
+			tables:   false,
+			wantErr:  "current log table of {I} is not created",
+		},
+		{
+			nameTest: "Free disk space is less",
+			tables:   true,
+			cfg:      healthConfigT{DirDisk: ".", MinFree: math.MaxUint64},
+			wantErr:  "free disk space",
+		},
+		{
+			nameTest: "Directory is missed",
+			tables:   true,
+			cfg:      healthConfigT{DirDisk: "/not/exists/dir", MinFree: 1},
+			wantErr:  "fault read free disk space",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			err := checkReadiness(openTestStorage(t, tt.tables), tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/Part001-R/netlogiwe/pkg/metrics"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Key of limits of projects not listed in RATE_LIMITS_FILE
+const defaultLimits = "*"
+
+// Interval of removing idle usage of projects and clients
+const sweepInterval = time.Minute
+
+// Key of the context value of the stream: reporting of results of the saved chunk
+type ctxSavedKey struct{}
+
+// Limits of saving messages of the project. Zero - no limit
+type limitT struct {
+	Rate          float64 `json:"rate"`          // messages per second, the token bucket
+	Burst         int     `json:"burst"`         // size of the bucket. Zero - rate, at least 1
+	DailyMessages int64   `json:"dailyMessages"` // messages per day (UTC)
+	DailyBytes    int64   `json:"dailyBytes"`    // bytes of messages per day (UTC)
+}
+
+// Rate limits and daily quotas of saving messages by project, optionally by project and client
+type limiterT struct {
+	limits   map[string]limitT // by project, defaultLimits - other projects
+	byClient bool              // separate buckets and quotas of each client of the project
+	now      func() time.Time
+
+	mu    sync.Mutex
+	usage map[string]*usageT // by project or project and client
+	swept time.Time          // last removing of idle usage
+}
+
+// Usage of limits of the project (and client)
+type usageT struct {
+	project  string
+	tokens   float64   // tokens of the bucket, negative - borrowed by the request over the burst
+	last     time.Time // last refill of the bucket
+	day      time.Time // day of the quota counters
+	messages int64
+	bytes    int64
+}
+
+// Messages of the project in the request
+type projectLoadT struct {
+	messages []*pb.MessageRequest
+	bytes    int64
+}
+
+// Limiter by the config: RATE_LIMITS_FILE - JSON {"project": {"rate": 100, "burst": 200, "dailyMessages": 1000000, "dailyBytes": 1073741824}},
+// "*" - other projects; RATE_LIMIT_BY_CLIENT=true - limits of each client of the project. Empty file - nil, no limits.
+// Return limiter, error
+func newLimiter(cfg limitsCfgT) (*limiterT, error) {
+
+	path := cfg.File
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fault read RATE_LIMITS_FILE: %v", err)
+	}
+	limits := make(map[string]limitT)
+	err = json.Unmarshal(data, &limits)
+	if err != nil {
+		return nil, fmt.Errorf("fault parse RATE_LIMITS_FILE: {%s}: %v", path, err)
+	}
+	for project, limit := range limits {
+		if limit.Rate < 0 || limit.Burst < 0 || limit.DailyMessages < 0 || limit.DailyBytes < 0 {
+			return nil, fmt.Errorf("fault parse RATE_LIMITS_FILE: negative limit of project {%s}", project)
+		}
+	}
+
+	return &limiterT{
+		limits:   limits,
+		byClient: cfg.ByClient,
+		now:      time.Now,
+		usage:    make(map[string]*usageT),
+	}, nil
+}
+
+// Interceptor of unary RPCs: limits of saved messages, not stored messages are returned to limits
+func (l *limiterT) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	var msgs []*pb.MessageRequest
+	switch r := req.(type) {
+	case *pb.MessageRequest:
+		msgs = []*pb.MessageRequest{r}
+	case *pb.BatchRequest:
+		msgs = r.GetMessages()
+	default:
+		return handler(ctx, req)
+	}
+
+	client := clientOf(ctx)
+	err := l.allow(client, msgs)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	l.refund(client, notStored(msgs, resp, err))
+
+	return resp, err
+}
+
+// Interceptor of stream RPCs: limits of each received message, messages not stored when the handler returns are returned to limits
+func (l *limiterT) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	s := &limitStreamT{ServerStream: ss, limiter: l, client: clientOf(ss.Context())}
+	s.ctx = context.WithValue(ss.Context(), ctxSavedKey{}, s.saved)
+
+	err := handler(srv, s)
+	l.refund(s.client, s.pending)
+
+	return err
+}
+
+// Stream with limits of received messages
+type limitStreamT struct {
+	grpc.ServerStream
+	ctx     context.Context // with reporting of results of saved chunks
+	limiter *limiterT
+	client  string
+	pending []*pb.MessageRequest // received messages without results, taken from limits
+	first   int32                // index of the first pending message in the stream
+}
+
+// Context of the stream with reporting of results of saved chunks
+func (s *limitStreamT) Context() context.Context {
+	return s.ctx
+}
+
+// Receiving the message. Over the limit - ResourceExhausted, the stream is finished
+func (s *limitStreamT) RecvMsg(msg any) error {
+
+	err := s.ServerStream.RecvMsg(msg)
+	if err != nil {
+		return err
+	}
+	if m, ok := msg.(*pb.MessageRequest); ok {
+		err = s.limiter.allow(s.client, []*pb.MessageRequest{m})
+		if err != nil {
+			return err
+		}
+		s.pending = append(s.pending, m)
+	}
+
+	return nil
+}
+
+// Sending the response. Messages not stored by results of the batch are returned to limits
+func (s *limitStreamT) SendMsg(msg any) error {
+
+	if resp, ok := msg.(*pb.BatchResponse); ok {
+		s.saved(resp.GetResults())
+	}
+
+	return s.ServerStream.SendMsg(msg)
+}
+
+// Results of saved messages of the stream: not stored messages are returned to limits, messages with results are not pending
+func (s *limitStreamT) saved(results []*pb.MessageResult) {
+
+	var refunds []*pb.MessageRequest
+	done := 0
+	for _, res := range results {
+		i := int(res.GetIndex() - s.first)
+		if i < 0 || i >= len(s.pending) {
+			continue
+		}
+		if res.GetCode() != pb.SaveStatus_SAVE_STATUS_STORED {
+			refunds = append(refunds, s.pending[i])
+		}
+		done = max(done, i+1)
+	}
+	s.limiter.refund(s.client, refunds)
+
+	s.pending = slices.Delete(s.pending, 0, done)
+	s.first += int32(done)
+}
+
+// Reporting results of the saved chunk of the stream to the limiter
+func reportSaved(ctx context.Context, results []*pb.MessageResult) {
+
+	if saved, ok := ctx.Value(ctxSavedKey{}).(func([]*pb.MessageResult)); ok {
+		saved(results)
+	}
+}
+
+// Taking limits of messages of the request. The request is allowed as a whole: if a project is over the limit,
+// nothing is taken. The request over the burst is allowed with the full bucket and borrows tokens of next requests.
+// T messages are not saved and not limited. Return ResourceExhausted with RetryInfo
+func (l *limiterT) allow(client string, msgs []*pb.MessageRequest) error {
+
+	loads := loadsOf(msgs)
+	if len(loads) == 0 {
+		return nil
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	for project, load := range loads {
+		limit, ok := l.limitOf(project)
+		if !ok {
+			continue
+		}
+		err := l.usageOf(project, client, limit, now).check(project, limit, load, now)
+		if err != nil {
+			return err
+		}
+	}
+	for project, load := range loads {
+		limit, ok := l.limitOf(project)
+		if !ok {
+			continue
+		}
+		u := l.usageOf(project, client, limit, now)
+		if limit.Rate > 0 {
+			u.tokens -= float64(len(load.messages))
+		}
+		u.messages += int64(len(load.messages))
+		u.bytes += load.bytes
+	}
+
+	return nil
+}
+
+// Returning limits of not stored messages: rejected, duplicates, faults of the storage
+func (l *limiterT) refund(client string, msgs []*pb.MessageRequest) {
+
+	loads := loadsOf(msgs)
+	if len(loads) == 0 {
+		return
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for project, load := range loads {
+		limit, ok := l.limitOf(project)
+		if !ok {
+			continue
+		}
+		u := l.usageOf(project, client, limit, now)
+		if limit.Rate > 0 {
+			u.tokens = math.Min(float64(limit.burst()), u.tokens+float64(len(load.messages)))
+		}
+		u.messages = max(0, u.messages-int64(len(load.messages)))
+		u.bytes = max(0, u.bytes-load.bytes)
+	}
+}
+
+// Removing usage with the full bucket and without quotas of the day: it is the same as the new one. Called under the lock
+func (l *limiterT) sweep(now time.Time) {
+
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	l.swept = now
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	for key, u := range l.usage {
+		limit, _ := l.limitOf(u.project)
+		if limit.Rate > 0 && u.tokens+now.Sub(u.last).Seconds()*limit.Rate < float64(limit.burst()) {
+			continue
+		}
+		if u.day.Equal(day) && (u.messages != 0 || u.bytes != 0) {
+			continue
+		}
+		delete(l.usage, key)
+	}
+}
+
+// Messages of the request by project, T messages are skipped
+func loadsOf(msgs []*pb.MessageRequest) map[string]*projectLoadT {
+
+	loads := make(map[string]*projectLoadT)
+	for _, msg := range msgs {
+		if msg.GetTypeMessage() == "T" {
+			continue
+		}
+		load := loads[msg.GetNameProject()]
+		if load == nil {
+			load = &projectLoadT{}
+			loads[msg.GetNameProject()] = load
+		}
+		load.messages = append(load.messages, msg)
+		load.bytes += int64(proto.Size(msg))
+	}
+
+	return loads
+}
+
+// Messages of the request not stored by the response of the handler: all on the error
+func notStored(msgs []*pb.MessageRequest, resp any, err error) []*pb.MessageRequest {
+
+	if err != nil {
+		return msgs
+	}
+
+	switch r := resp.(type) {
+	case *pb.MessageResponse:
+		if r.GetCode() != pb.SaveStatus_SAVE_STATUS_STORED {
+			return msgs
+		}
+	case *pb.BatchResponse:
+		var refunds []*pb.MessageRequest
+		for _, res := range r.GetResults() {
+			if res.GetCode() != pb.SaveStatus_SAVE_STATUS_STORED && int(res.GetIndex()) < len(msgs) {
+				refunds = append(refunds, msgs[res.GetIndex()])
+			}
+		}
+		return refunds
+	}
+
+	return nil
+}
+
+// Limits of the project. Return limits, false - no limits
+func (l *limiterT) limitOf(project string) (limitT, bool) {
+
+	limit, ok := l.limits[project]
+	if !ok {
+		limit, ok = l.limits[defaultLimits]
+	}
+
+	return limit, ok
+}
+
+// Usage of the project (and client) refilled at the time. Called under the lock
+func (l *limiterT) usageOf(project, client string, limit limitT, now time.Time) *usageT {
+
+	key := project
+	if l.byClient && client != "" {
+		key = project + "\x00" + client
+	}
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	u := l.usage[key]
+	if u == nil {
+		u = &usageT{project: project, tokens: float64(limit.burst()), last: now, day: day}
+		l.usage[key] = u
+	}
+	if limit.Rate > 0 {
+		u.tokens = math.Min(float64(limit.burst()), u.tokens+now.Sub(u.last).Seconds()*limit.Rate)
+	}
+	u.last = now
+	if !u.day.Equal(day) {
+		u.day, u.messages, u.bytes = day, 0, 0
+	}
+
+	return u
+}
+
+// Check the load of the project against its limits. Return ResourceExhausted with the time to retry
+func (u *usageT) check(project string, limit limitT, load *projectLoadT, now time.Time) error {
+
+	count := len(load.messages)
+	untilTomorrow := u.day.Add(24 * time.Hour).Sub(now)
+
+	if limit.DailyMessages > 0 && u.messages+int64(count) > limit.DailyMessages {
+		return throttled(project, metrics.LimitDailyMessages, load.messages, untilTomorrow,
+			fmt.Sprintf("daily quota of {%d} messages is exhausted", limit.DailyMessages))
+	}
+	if limit.DailyBytes > 0 && u.bytes+load.bytes > limit.DailyBytes {
+		return throttled(project, metrics.LimitDailyBytes, load.messages, untilTomorrow,
+			fmt.Sprintf("daily quota of {%d} bytes is exhausted", limit.DailyBytes))
+	}
+	if limit.Rate > 0 {
+		// over the burst the request waits for the full bucket
+		need := float64(min(count, limit.burst()))
+		if u.tokens < need {
+			wait := time.Duration((need - u.tokens) / limit.Rate * float64(time.Second))
+			return throttled(project, metrics.LimitRate, load.messages, wait,
+				fmt.Sprintf("rate of {%g} messages per second is exceeded", limit.Rate))
+		}
+	}
+
+	return nil
+}
+
+// Size of the bucket
+func (l limitT) burst() int {
+
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return max(1, int(math.Ceil(l.Rate)))
+}
+
+// Error of the exceeded limit with RetryInfo (retry > 0), counting of throttled messages
+func throttled(project, limit string, msgs []*pb.MessageRequest, retry time.Duration, description string) error {
+
+	for _, msg := range msgs {
+		metrics.MessageThrottled(msg.GetTypeMessage(), project, limit)
+	}
+
+	st := status.Newf(codes.ResourceExhausted, "project {%s}: %s", project, description)
+	if retry > 0 {
+		withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retry)})
+		if err == nil {
+			st = withRetry
+		}
+	}
+
+	return st.Err()
+}
+
+// Client of the request: the identity of authentication, else the host of the peer
+func clientOf(ctx context.Context) string {
+
+	if access, ok := ctx.Value(ctxAccessKey{}).(accessT); ok && access.identity != "" {
+		return access.identity
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return p.Addr.String()
+		}
+		return host
+	}
+
+	return ""
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	client "github.com/Part001-R/netlogiwe/pkg/client"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	spool "github.com/Part001-R/netlogiwe/pkg/spool"
+)
+
+// Limiter of tests by the JSON of limits with the clock. Return limiter, pointer of the clock
+func newTestLimiter(t *testing.T, limits string, byClient bool) (*limiterT, *time.Time) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(limits), 0o600))
+
+	l, err := newLimiter(limitsCfgT{File: path, ByClient: byClient})
+	require.NoError(t, err)
+	require.NotNil(t, l)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	return l, &now
+}
+
+// Messages of the project for tests
+func testMessages(project string, n int) []*pb.MessageRequest {
+
+	msgs := make([]*pb.MessageRequest, n)
+	for i := range msgs {
+		msgs[i] = testMessage(project)
+	}
+
+	return msgs
+}
+
+// Time to retry of the error. Zero - without RetryInfo
+func retryDelay(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+
+	return 0
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Token bucket of the project is refilled by the rate, other projects take default limits or have no limits
+func Test_limiterT_Rate_SUCCESS(t *testing.T) {
+
+	l, now := newTestLimiter(t, `{"alpha": {"rate": 2, "burst": 4}, "*": {"rate": 1}}`, false)
+
+	require.NoError(t, l.allow("", testMessages("alpha", 4)))
+	err := l.allow("", testMessages("alpha", 1))
+	assert.Equal(t, 500*time.Millisecond, retryDelay(t, err))
+
+	*now = now.Add(time.Second)
+	require.NoError(t, l.allow("", testMessages("alpha", 2)))
+
+	// default limits of other projects: burst is the rate
+	require.NoError(t, l.allow("", testMessages("beta", 1)))
+	err = l.allow("", testMessages("beta", 1))
+	assert.Equal(t, time.Second, retryDelay(t, err))
+
+	// the batch is allowed as a whole
+	*now = now.Add(time.Second)
+	require.NoError(t, l.allow("", testMessages("beta", 1)))
+	err = l.allow("", append(testMessages("alpha", 1), testMessages("beta", 1)...))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.NoError(t, l.allow("", testMessages("alpha", 2)))
+}
+
+// Test - Daily quotas of messages and bytes are reset at the next day, limits by client are separate
+func Test_limiterT_Quota_SUCCESS(t *testing.T) {
+
+	l, now := newTestLimiter(t, `{"alpha": {"dailyMessages": 3}, "beta": {"dailyBytes": 100}}`, true)
+
+	require.NoError(t, l.allow("svc-1", testMessages("alpha", 3)))
+	err := l.allow("svc-1", testMessages("alpha", 1))
+	assert.Equal(t, 12*time.Hour, retryDelay(t, err))
+	require.NoError(t, l.allow("svc-2", testMessages("alpha", 1)))
+
+	err = l.allow("svc-1", testMessages("beta", 10))
+	assert.Equal(t, 12*time.Hour, retryDelay(t, err))
+	require.NoError(t, l.allow("svc-1", testMessages("beta", 1)))
+
+	*now = now.Add(12 * time.Hour)
+	require.NoError(t, l.allow("svc-1", testMessages("alpha", 3)))
+}
+
+// Test - Interceptors reject messages over the limit with ResourceExhausted
+func Test_limiterT_Interceptors_SUCCESS(t *testing.T) {
+
+	l, _ := newTestLimiter(t, `{"alpha": {"dailyMessages": 1}}`, false)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &pb.MessageResponse{Status: "Ok", Code: pb.SaveStatus_SAVE_STATUS_STORED}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: pb.Iwe_SaveMessage_FullMethodName}
+
+	_, err := l.unaryInterceptor(context.Background(), testMessage("alpha"), info, handler)
+	require.NoError(t, err)
+	_, err = l.unaryInterceptor(context.Background(), testMessage("alpha"), info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = l.unaryInterceptor(context.Background(), &pb.BatchRequest{Messages: testMessages("alpha", 1)}, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other requests are not limited
+	_, err = l.unaryInterceptor(context.Background(), &pb.QueryRequest{NameProject: "alpha"}, info, handler)
+	require.NoError(t, err)
+}
+
+// Test - The request over the burst waits for the full bucket and borrows tokens of next requests
+func Test_limiterT_Burst_SUCCESS(t *testing.T) {
+
+	l, now := newTestLimiter(t, `{"alpha": {"rate": 10, "burst": 5}}`, false)
+
+	require.NoError(t, l.allow("", testMessages("alpha", 2)))
+	err := l.allow("", testMessages("alpha", 8))
+	assert.Equal(t, 200*time.Millisecond, retryDelay(t, err))
+
+	*now = now.Add(200 * time.Millisecond)
+	require.NoError(t, l.allow("", testMessages("alpha", 8)))
+	err = l.allow("", testMessages("alpha", 1))
+	assert.Equal(t, 400*time.Millisecond, retryDelay(t, err))
+}
+
+// Test - Only stored messages take limits: T messages, rejected messages and faults of the handler are returned
+func Test_limiterT_Refund_SUCCESS(t *testing.T) {
+
+	l, _ := newTestLimiter(t, `{"alpha": {"rate": 1, "burst": 2, "dailyMessages": 2}}`, false)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.Iwe_SaveMessages_FullMethodName}
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: openTestStorage(t, true), broker: brk}
+	handler := func(ctx context.Context, req any) (any, error) { return s.SaveMessages(ctx, req.(*pb.BatchRequest)) }
+
+	invalid := testMessage("alpha")
+	invalid.BodyMessage = ""
+	test := testMessage("alpha")
+	test.TypeMessage = "T"
+	for range 3 {
+		_, err = l.unaryInterceptor(context.Background(), &pb.BatchRequest{Messages: []*pb.MessageRequest{invalid, test, test}}, info, handler)
+		require.NoError(t, err)
+	}
+	faulty := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Unavailable, "storage")
+	}
+	_, err = l.unaryInterceptor(context.Background(), &pb.BatchRequest{Messages: testMessages("alpha", 2)}, info, faulty)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	resp, err := l.unaryInterceptor(context.Background(), &pb.BatchRequest{Messages: testMessages("alpha", 2)}, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.(*pb.BatchResponse).GetSaved())
+	err = l.allow("", testMessages("alpha", 1))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// Test - Messages of the stream not stored when the handler fails are returned, only committed stored messages take limits
+func Test_limiterT_StreamRefund_SUCCESS(t *testing.T) {
+
+	l, _ := newTestLimiter(t, `{"alpha": {"dailyMessages": 600}}`, false)
+	info := &grpc.StreamServerInfo{FullMethod: pb.Iwe_StreamMessages_FullMethodName}
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: &failingStorageT{ActionsDB: openTestStorage(t, true), batches: 1}, broker: brk}
+	handler := func(srv any, ss grpc.ServerStream) error {
+		return s.StreamMessages(&grpc.GenericServerStream[pb.MessageRequest, pb.BatchResponse]{ServerStream: ss})
+	}
+
+	// the first chunk is committed with one rejected message, the second one fails
+	msgs := testMessages("alpha", sizeChunkStream+10)
+	msgs[0].BodyMessage = ""
+	err = l.streamInterceptor(nil, &testStreamT{reqs: msgs}, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, l.allow("", testMessages("alpha", 600-(sizeChunkStream-1))))
+	err = l.allow("", testMessages("alpha", 1))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// Test - Idle usage is removed: the full bucket and no quotas of the day
+func Test_limiterT_Sweep_SUCCESS(t *testing.T) {
+
+	l, now := newTestLimiter(t, `{"*": {"rate": 10, "dailyMessages": 100}}`, true)
+
+	for _, client := range []string{"svc-1", "svc-2", "svc-3"} {
+		require.NoError(t, l.allow(client, testMessages("alpha", 1)))
+	}
+	require.Len(t, l.usage, 3)
+
+	// the bucket is full, but quotas of the day are used
+	*now = now.Add(sweepInterval)
+	require.NoError(t, l.allow("svc-1", testMessages("beta", 1)))
+	assert.Len(t, l.usage, 4)
+
+	*now = now.Add(12 * time.Hour)
+	require.NoError(t, l.allow("svc-1", testMessages("alpha", 1)))
+	assert.Len(t, l.usage, 1)
+}
+
+// Test - The spool of the client is delivered through the limiter with the burst less than batches of the spool
+func Test_limiterT_Spool_SUCCESS(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"alpha": {"rate": 500, "burst": 20}}`), 0o600))
+	l, err := newLimiter(limitsCfgT{File: path})
+	require.NoError(t, err)
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	objDB := openTestStorage(t, true)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(l.unaryInterceptor), grpc.ChainStreamInterceptor(l.streamInterceptor))
+	pb.RegisterIweServer(srv, &server{db: objDB, broker: brk})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	c, err := client.New(client.ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Options: []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})},
+		Spool: spool.ConfigT{Dir: t.TempDir()},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	for range 250 {
+		require.NoError(t, c.Info("main.go:1", "spooled"))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, c.Flush(ctx))
+
+	assert.Equal(t, uint64(0), c.Lost())
+	msgs, err := objDB.ReadingMessages(db.FilterT{NameProject: "alpha", Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 250)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Not allowed files of limits
+func Test_newLimiter_FAULT(t *testing.T) {
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		nameTest string
+		data     string
+	}{
+		{nameTest: "not JSON", data: "rate: 1"},
+		{nameTest: "negative", data: `{"alpha": {"rate": -1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			path := filepath.Join(dir, "limits.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+
+			_, err := newLimiter(limitsCfgT{File: path})
+			require.Error(t, err)
+		})
+	}
+
+	_, err := newLimiter(limitsCfgT{File: filepath.Join(dir, "none.json")})
+	require.Error(t, err)
+}
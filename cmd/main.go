@@ -3,123 +3,277 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
 
 	pb "github.com/Part001-R/netlogiwe/pkg/api"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-
-	"github.com/joho/godotenv"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
 	db "github.com/Part001-R/netlogiwe/pkg/db"
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
 )
 
 type server struct {
 	pb.UnimplementedIweServer
-	db db.ActionsDB
+	db        db.ActionsDB
+	broker    *broker.Broker
+	eventTime db.EventTimePolicyT
 }
 
 func main() {
+	os.Exit(run())
+}
 
-	// Preparatory actions
-	objDB, closeDb, err := preparAct()
+// Run of the server until SIGTERM or SIGINT. Return exit code
+func run() int {
+
+	cfgFlags := newConfigFlags(flag.CommandLine)
+	dryRunMigrate := flag.Bool("migrate-dry-run", false, "print pending migrations of the schema and exit, the database is not changed")
+	flag.Parse()
+
+	// Config: flags, env, file
+	cfg, err := cfgFlags.load()
 	if err != nil {
-		log.Fatalf("fault preparatory actions: %v", err)
+		log.Printf("fault read config: %v", err)
+		return exitFault
 	}
-	defer func() {
-		err := closeDb()
+
+	switch flag.Arg(0) {
+	case "healthcheck":
+		return healthcheck(cfg)
+	case "token":
+		return tokenCommand(cfg, flag.Args()[1:])
+	}
+
+	if *dryRunMigrate {
+		err := migrateDryRun(cfg)
 		if err != nil {
-			fmt.Println("fault close DB")
+			log.Printf("fault dry run of migrations: %v", err)
+			return exitFault
 		}
-	}()
+		return exitOk
+	}
 
-	// gRPCS
-	srvImpl := &server{
-		db: objDB,
+	// Preparatory actions
+	objDB, closeDb, err := preparAct(cfg)
+	if err != nil {
+		log.Printf("fault preparatory actions: %v", err)
+		if closeDb != nil {
+			closeDb()
+		}
+		return exitFault
 	}
-	err = startUpServer(srvImpl)
+
+	code := serveStorage(cfg, objDB)
+
+	// The storage is closed after the last writer
+	err = closeDb()
 	if err != nil {
-		log.Fatalf("fault start up IWE server: %v", err)
+		log.Printf("fault close DB: %v", err)
+		return exitFault
 	}
+	log.Println("Stop IWE server, code:", code)
+
+	return code
 }
 
-// Handler
-func (s *server) SaveMessage(ctx context.Context, req *pb.MessageRequest) (*pb.MessageResponse, error) {
+// Serving of the storage by the config until the signal. Return exit code
+func serveStorage(cfg configT, objDB db.ActionsDB) int {
 
-	var msg = db.MessageT{}
+	// Broker of accepted messages
+	brk, err := newBroker(cfg.Tail)
+	if err != nil {
+		log.Printf("fault create broker: %v", err)
+		return exitFault
+	}
+
+	// Retention of log tables
+	policy, interval := newRetentionPolicy(cfg.Retention)
 
-	msg.TypeMessage = req.GetTypeMessage()
-	msg.NameProject = req.GetNameProject()
-	msg.LocationEvent = req.GetLocationEvent()
-	msg.BodyMessage = req.GetBodyMessage()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	if msg.TypeMessage == "T" {
-		return &pb.MessageResponse{Status: "Ok"}, nil
+	// Metrics on the separate HTTP port
+	metrics.Registry.MustRegister(newStorageCollector(objDB, pathSQLite(cfg.Storage), cfg.storage().ConfigT))
+	if addr := cfg.Server.MetricsPort; addr != "" {
+		go func() {
+			err := runMetrics(ctx, addr)
+			if err != nil {
+				log.Printf("error metrics: {%v}", err)
+			}
+		}()
 	}
 
-	err := s.db.SavingMessage(msg)
+	doneRetention := make(chan struct{})
+	go func() {
+		defer close(doneRetention)
+		runRetention(ctx, objDB, policy, interval)
+	}()
 
-	//err := db.StoreMessage(s.db, msg)
-	if err != nil {
-		fmt.Printf("error: {%v}\n", err)
-		return nil, err
+	// gRPCS
+	srvImpl := &server{
+		db:        objDB,
+		broker:    brk,
+		eventTime: newEventTimePolicy(cfg.EventTime),
 	}
+	admImpl := &adminServer{
+		db:        objDB,
+		retention: policy,
+	}
+	err = startUpServer(ctx, cfg, srvImpl, admImpl)
+
+	// the scheduled retention is finished before close of the storage
+	stop()
+	<-doneRetention
 
-	return &pb.MessageResponse{Status: "Ok"}, nil
+	switch {
+	case err == nil:
+		return exitOk
+	case errors.Is(err, errShutdownTimeout):
+		log.Printf("fault graceful stop: %v", err)
+		return exitForced
+	default:
+		log.Printf("fault start up IWE server: %v", err)
+		return exitFault
+	}
 }
 
 // preparatory actions. Returns: db pointer, function close db connect, error
-func preparAct() (db.ActionsDB, func() error, error) {
+func preparAct(cfg configT) (db.ActionsDB, func() error, error) {
 
-	// ENV
-	err := godotenv.Load(".env")
+	objDB, close, err := openStorage(cfg)
 	if err != nil {
-		log.Fatal("Fault read env file")
+		return nil, nil, err
 	}
 
-	// DB
-	ptrDb, close, err := db.ConDb(os.Getenv("DB_TYPE"), os.Getenv("DB_NAME"))
+	// Tables
+	err = objDB.Tables()
 	if err != nil {
-		return nil, nil, fmt.Errorf("fault connect DB: %v", err)
+		return nil, close, fmt.Errorf("fault create tables: %v", err)
 	}
-	objDB, err := db.RepoDB(ptrDb)
+	return objDB, close, nil
+}
+
+// Dry run of migrations of the schema: pending migrations are printed, the database is not changed
+func migrateDryRun(cfg configT) error {
+
+	objDB, close, err := openStorage(cfg)
 	if err != nil {
-		log.Fatalf("an error create object instance: '%v'", err)
+		return err
 	}
+	defer close()
 
-	// Tables
-	err = objDB.Tables()
+	migrator, ok := objDB.(db.MigratorDB)
+	if !ok {
+		return fmt.Errorf("storage {%s} has no versioned migrations", cfg.Storage.Type)
+	}
+
+	pending, err := migrator.Migrate(true)
 	if err != nil {
-		return nil, close, fmt.Errorf("fault create tables: %v", err)
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("schema is up to date")
+		return nil
 	}
+	for _, m := range pending {
+		fmt.Printf("migration {%d} {%s}, log tables: %v\n", m.Version, m.Name, m.Tables)
+	}
+
+	return nil
+}
+
+// Open the storage by the config. Returns: db pointer, function close db connect, error
+func openStorage(cfg configT) (db.ActionsDB, func() error, error) {
+
+	objDB, close, err := db.Open(cfg.storage())
+	if err != nil {
+		return nil, nil, fmt.Errorf("fault connect DB: %v", err)
+	}
+
 	return objDB, close, nil
 }
 
-// Start up IWE server. Return error.
-func startUpServer(s *server) error {
+// Create the broker of accepted messages by the config. Return pointer, error
+func newBroker(cfg tailCfgT) (*broker.Broker, error) {
+
+	policy, err := broker.ParsePolicy(cfg.SlowPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("fault parse TAIL_SLOW_POLICY: %v", err)
+	}
+
+	return broker.New(cfg.BufferSize, policy)
+}
+
+// Bounds of the event time by the config
+func newEventTimePolicy(cfg eventTimeCfgT) db.EventTimePolicyT {
+	return db.EventTimePolicyT{MaxFuture: cfg.MaxFuture, MaxPast: cfg.MaxPast, Reject: cfg.Skew == "reject"}
+}
+
+// Start up IWE server until ctx is done. Return error, errShutdownTimeout if in-flight requests are cancelled
+func startUpServer(ctx context.Context, cfg configT, s *server, adm *adminServer) error {
 
-	creds, err := credentials.NewServerTLSFromFile(os.Getenv("PATH_PUBLIC_KEY"), os.Getenv("PATH_PRIVATE_KEY"))
+	creds, mtls, err := newServerCreds(cfg)
+	if err != nil {
+		return err
+	}
+	unary := []grpc.UnaryServerInterceptor{metrics.UnaryServerInterceptor}
+	stream := []grpc.StreamServerInterceptor{metrics.StreamServerInterceptor}
+	auth, err := newAuth(cfg.Auth, mtls, s.db)
 	if err != nil {
-		return fmt.Errorf("fault read sertificats: %v", err)
+		return err
+	}
+	if auth != nil {
+		unary = append(unary, auth.unaryInterceptor)
+		stream = append(stream, auth.streamInterceptor)
+		log.Printf("Clients are authenticated: certificates {%t} (identities with projects: %d), tokens {%t}",
+			auth.certs, len(auth.projects), auth.tokens != nil)
+	} else {
+		unary = append(unary, adminDeniedInterceptor)
+		stream = append(stream, adminDeniedStreamInterceptor)
+		log.Println("Clients are not authenticated: the admin service is denied")
+	}
+	limiter, err := newLimiter(cfg.Limits)
+	if err != nil {
+		return err
+	}
+	if limiter != nil {
+		unary = append(unary, limiter.unaryInterceptor)
+		stream = append(stream, limiter.streamInterceptor)
+		log.Printf("Saving messages is limited: projects %d, by client {%t}", len(limiter.limits), limiter.byClient)
 	}
 
-	ipAndPort := os.Getenv("PORT")
+	ipAndPort := cfg.Server.Port
 	listener, err := net.Listen("tcp", ipAndPort)
 	if err != nil {
 		return fmt.Errorf("fault create listener tcp port %s: %v", ipAndPort, err)
 	}
 
-	srv := grpc.NewServer(grpc.Creds(creds))
+	srv := grpc.NewServer(grpc.Creds(creds), grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
 	pb.RegisterIweServer(srv, s)
-	log.Println("Start up IWE server:", ipAndPort)
+	pb.RegisterAdminServer(srv, adm)
 
-	err = srv.Serve(listener)
-	if err != nil {
-		return fmt.Errorf("fault start up IWE server: %v ", err)
+	// Health by readiness of the storage, NOT_SERVING from the start of shutdown
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go runHealth(ctx, hs, s.db, newHealthConfig(cfg))
+
+	if cfg.Server.Reflection {
+		reflection.Register(srv)
 	}
+	log.Println("Start up IWE server:", ipAndPort)
 
-	return errors.New("plug error")
+	return serveUntilDone(ctx, srv, listener, cfg.Server.ShutdownTimeout, func() {
+		hs.Shutdown()
+		s.broker.Close()
+	})
 }
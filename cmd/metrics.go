@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
+)
+
+// Collector of the state of the storage on scrape: fill of the current log tables, size of the database file
+type storageCollector struct {
+	db     db.ActionsDB
+	pathDB string     // file of SQLite. Empty - the size is not collected
+	maxIds db.ConfigT // messages of log tables before rotation
+	fill   *prometheus.Desc
+	size   *prometheus.Desc
+}
+
+// Create the collector of the storage. pathDB - file of SQLite, empty for other storages
+func newStorageCollector(objDB db.ActionsDB, pathDB string, maxIds db.ConfigT) *storageCollector {
+	return &storageCollector{
+		db:     objDB,
+		pathDB: pathDB,
+		maxIds: maxIds,
+		fill: prometheus.NewDesc("netlogiwe_partition_fill_ratio",
+			"Messages of the current log table of the type against MAX_IDNUMB_LOG*, rotation after 1.",
+			[]string{"type", "table"}, nil),
+		size: prometheus.NewDesc("netlogiwe_db_file_bytes",
+			"Size of the SQLite database file.", nil, nil),
+	}
+}
+
+// Describe of the metrics
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.fill
+	ch <- c.size
+}
+
+// Collect of the metrics
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+
+	parts, err := c.db.Partitions()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.fill, err)
+	}
+	for _, p := range parts {
+		if p.State != db.StateActive {
+			continue
+		}
+		maxId := c.maxIds.MaxIdNumb(p.TypeTable)
+		if maxId <= 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.fill, prometheus.GaugeValue, float64(p.RowCount)/float64(maxId), p.TypeTable, p.NameTable)
+	}
+
+	if c.pathDB == "" {
+		return
+	}
+	info, err := os.Stat(c.pathDB)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.size, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(info.Size()))
+}
+
+// Serving of /metrics on addr until ctx is done. Return error
+func runMetrics(ctx context.Context, addr string) error {
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- srv.ListenAndServe()
+	}()
+	log.Println("Start up metrics:", addr)
+
+	select {
+	case err := <-errServe:
+		return fmt.Errorf("fault serve metrics on %s: %v", addr, err)
+	case <-ctx.Done():
+	}
+
+	ctxStop, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := srv.Shutdown(ctxStop)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("fault stop metrics: %v", err)
+	}
+
+	return nil
+}
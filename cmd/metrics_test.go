@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
+)
+
+// Value of the series of the registry with the labels. 0 - not found
+func metricValue(t *testing.T, reg prometheus.Gatherer, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	next:
+		for _, m := range f.GetMetric() {
+			got := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				got[l.GetName()] = l.GetValue()
+			}
+			for k, v := range labels {
+				if got[k] != v {
+					continue next
+				}
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+
+	return 0
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Messages are counted by handlers: received, stored, rejected; rotation by the storage
+func Test_metrics_Handlers_SUCCESS(t *testing.T) {
+
+	objDB, closeDb, err := db.Open(db.DriverConfigT{Type: "memory", ConfigT: testStorageConfig(2, 10, 10)})
+	require.NoError(t, err)
+	defer closeDb()
+	require.NoError(t, objDB.Tables())
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: objDB, broker: brk}
+
+	project := map[string]string{"type": "I", "project": "metrics"}
+	invalid := map[string]string{"type": "I", "project": "metrics", "reason": metrics.ReasonInvalid}
+	rotationI := map[string]string{"type": "I"}
+	received := metricValue(t, metrics.Registry, "netlogiwe_messages_received_total", project)
+	stored := metricValue(t, metrics.Registry, "netlogiwe_messages_stored_total", project)
+	rejected := metricValue(t, metrics.Registry, "netlogiwe_messages_rejected_total", invalid)
+	rotations := metricValue(t, metrics.Registry, "netlogiwe_rotations_total", rotationI)
+	unknown := map[string]string{"type": metrics.LabelInvalid, "project": metrics.LabelInvalid}
+	receivedUnknown := metricValue(t, metrics.Registry, "netlogiwe_messages_received_total", unknown)
+
+	for _, body := range []string{"one", "two", "three", ""} {
+		_, _ = s.SaveMessage(context.Background(), &pb.MessageRequest{TypeMessage: "I", NameProject: "metrics", LocationEvent: "l", BodyMessage: body})
+	}
+	_, err = s.SaveMessages(context.Background(), &pb.BatchRequest{Messages: []*pb.MessageRequest{
+		{TypeMessage: "I", NameProject: "metrics", LocationEvent: "l", BodyMessage: "four"},
+		{TypeMessage: "I", NameProject: "metrics", LocationEvent: "l", BodyMessage: ""},
+		{TypeMessage: "random", NameProject: "random", LocationEvent: "l", BodyMessage: "five"},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, received+6, metricValue(t, metrics.Registry, "netlogiwe_messages_received_total", project))
+	assert.Equal(t, stored+4, metricValue(t, metrics.Registry, "netlogiwe_messages_stored_total", project))
+	assert.Equal(t, rejected+2, metricValue(t, metrics.Registry, "netlogiwe_messages_rejected_total", invalid))
+	assert.Equal(t, rotations+1, metricValue(t, metrics.Registry, "netlogiwe_rotations_total", rotationI))
+	assert.Equal(t, receivedUnknown+1, metricValue(t, metrics.Registry, "netlogiwe_messages_received_total", unknown))
+}
+
+// Test - Rotations of SQLite are counted after the commit, once per change of the log table
+func Test_metrics_Rotations_SUCCESS(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "iwe.db")
+	objDB, closeDb, err := db.Open(db.DriverConfigT{Type: "sqlite", Name: path, ConfigT: testStorageConfig(10, 2, 10)})
+	require.NoError(t, err)
+	defer closeDb()
+	require.NoError(t, objDB.Tables())
+
+	rotationW := map[string]string{"type": "W"}
+	rotations := metricValue(t, metrics.Registry, "netlogiwe_rotations_total", rotationW)
+
+	msg := db.MessageT{TypeMessage: "W", NameProject: "metrics", LocationEvent: "l", BodyMessage: "one"}
+	for range 3 {
+		_, err = objDB.SavingMessage(msg)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, rotations+1, metricValue(t, metrics.Registry, "netlogiwe_rotations_total", rotationW))
+
+	_, errs, err := objDB.SavingMessages([]db.MessageT{msg, msg, msg})
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, rotations+2, metricValue(t, metrics.Registry, "netlogiwe_rotations_total", rotationW))
+}
+
+// Test - Fill of the current log tables and size of the database file
+func Test_storageCollector_SUCCESS(t *testing.T) {
+
+	maxIds := testStorageConfig(10, 10, 4)
+	path := filepath.Join(t.TempDir(), "iwe.db")
+	objDB, closeDb, err := db.Open(db.DriverConfigT{Type: "sqlite", Name: path, ConfigT: maxIds})
+	require.NoError(t, err)
+	defer closeDb()
+	require.NoError(t, objDB.Tables())
+	_, err = objDB.SavingMessage(db.MessageT{TypeMessage: "E", NameProject: "p", LocationEvent: "l", BodyMessage: "b"})
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newStorageCollector(objDB, path, maxIds))
+
+	assert.Equal(t, 0.25, metricValue(t, reg, "netlogiwe_partition_fill_ratio", map[string]string{"type": "E", "table": "logE_1"}))
+	assert.Equal(t, float64(0), metricValue(t, reg, "netlogiwe_partition_fill_ratio", map[string]string{"type": "I"}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, float64(info.Size()), metricValue(t, reg, "netlogiwe_db_file_bytes", nil))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Retention policy by the config. Return policy, interval of the scheduled run (0 - only by admin RPC)
+func newRetentionPolicy(cfg retentionCfgT) (db.RetentionPolicyT, time.Duration) {
+
+	policy := db.RetentionPolicyT{
+		ByType:     make(map[string]db.RetentionT),
+		DirArchive: cfg.DirArchive,
+	}
+
+	for typeTable, rule := range map[string]db.RetentionT{
+		"I": {KeepLast: cfg.KeepI, MaxAge: time.Duration(cfg.DaysI) * 24 * time.Hour},
+		"W": {KeepLast: cfg.KeepW, MaxAge: time.Duration(cfg.DaysW) * 24 * time.Hour},
+		"E": {KeepLast: cfg.KeepE, MaxAge: time.Duration(cfg.DaysE) * 24 * time.Hour},
+	} {
+		if rule != (db.RetentionT{}) {
+			policy.ByType[typeTable] = rule
+		}
+	}
+
+	return policy, cfg.Interval
+}
+
+// Scheduled run of the retention policy until ctx is done: at start, then every interval. The current run is completed
+func runRetention(ctx context.Context, objDB db.ActionsDB, policy db.RetentionPolicyT, interval time.Duration) {
+
+	if interval == 0 || len(policy.ByType) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := objDB.ApplyRetention(policy, false)
+		for _, p := range removed {
+			log.Println("Retention: removed log table", p.NameTable)
+		}
+		if err != nil {
+			log.Printf("fault retention: {%v}", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
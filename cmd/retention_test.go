@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - The policy is run at start, not after the first interval
+func Test_runRetention_SUCCESS(t *testing.T) {
+
+	objDB, closeDb, err := db.Open(db.DriverConfigT{Type: "memory", ConfigT: testStorageConfig(1, 100, 100)})
+	require.NoError(t, err)
+	defer closeDb()
+	require.NoError(t, objDB.Tables())
+	for range 3 {
+		_, err := objDB.SavingMessage(db.MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "b"})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	policy := db.RetentionPolicyT{ByType: map[string]db.RetentionT{"I": {KeepLast: 1}}}
+	go runRetention(ctx, objDB, policy, 24*time.Hour)
+
+	require.Eventually(t, func() bool {
+		parts, err := objDB.Partitions()
+		require.NoError(t, err)
+		kept := 0
+		for _, p := range parts {
+			if p.TypeTable == "I" && p.State != db.StateDropped {
+				kept++
+			}
+		}
+		return kept == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
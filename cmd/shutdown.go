@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Exit codes of the server
+const (
+	exitOk     = 0 // stopped by the signal, in-flight requests are completed
+	exitFault  = 1 // fault of start up, serving or close of the storage
+	exitForced = 2 // in-flight requests are cancelled by the deadline of shutdown
+)
+
+var errShutdownTimeout = errors.New("in-flight requests are not completed within the shutdown timeout")
+
+// Serving of gRPC until ctx is done. Then new connections are not accepted, drain stops long streams
+// (subscribers of tail), in-flight requests are completed within timeout, after it they are cancelled.
+// Return error of serving, errShutdownTimeout
+func serveUntilDone(ctx context.Context, srv *grpc.Server, listener net.Listener, timeout time.Duration, drain func()) error {
+
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errServe:
+		return fmt.Errorf("fault serve: %v", err)
+	case <-ctx.Done():
+	}
+	log.Println("Shutdown IWE server: new connections are not accepted")
+
+	drain()
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		srv.Stop()
+		<-stopped
+		err = errShutdownTimeout
+	}
+
+	errS := <-errServe
+	if errS != nil && err == nil {
+		return fmt.Errorf("fault serve: %v", errS)
+	}
+
+	return err
+}
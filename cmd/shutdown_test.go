@@ -0,0 +1,161 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"net"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Start up the in-process IWE server until ctx is done. Return connection to it, channel of result of serving
+func startTestServer(t *testing.T, ctx context.Context, s *server, timeout time.Duration, drain func()) (*grpc.ClientConn, <-chan error) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterIweServer(srv, s)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveUntilDone(ctx, srv, lis, timeout, drain)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, done
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - SIGTERM during saving: in-flight requests are completed, every stored message is in the database after close
+func Test_serveUntilDone_SIGTERM_SUCCESS(t *testing.T) {
+
+	cfg := db.DriverConfigT{Type: "sqlite", Name: filepath.Join(t.TempDir(), "iwe.db"), ConfigT: testStorageConfig(50, 50, 50)}
+	objDB, closeDb, err := db.Open(cfg)
+	require.NoError(t, err)
+	require.NoError(t, objDB.Tables())
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{db: objDB, broker: brk}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	conn, done := startTestServer(t, ctx, s, 5*time.Second, brk.Close)
+	client := pb.NewIweClient(conn)
+
+	// the subscriber of tail is disconnected by shutdown
+	tail, err := client.TailMessages(context.Background(), &pb.TailRequest{})
+	require.NoError(t, err)
+
+	var (
+		stored atomic.Int64
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				resp, err := client.SaveMessage(context.Background(), &pb.MessageRequest{
+					TypeMessage: "I", NameProject: "load", LocationEvent: "shutdown_test.go", BodyMessage: "message under load",
+				})
+				if err != nil {
+					return
+				}
+				if resp.GetCode() == pb.SaveStatus_SAVE_STATUS_STORED {
+					stored.Add(1)
+				}
+			}
+		}()
+	}
+
+	require.Eventually(t, func() bool { return stored.Load() >= 200 }, 10*time.Second, time.Millisecond)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server is not stopped")
+	}
+	wg.Wait()
+
+	for {
+		_, err := tail.Recv()
+		if err != nil {
+			assert.Equal(t, codes.Unavailable, status.Code(err), err)
+			break
+		}
+	}
+
+	require.NoError(t, closeDb())
+
+	objDB, closeDb, err = db.Open(cfg)
+	require.NoError(t, err)
+	defer closeDb()
+	parts, err := objDB.Partitions()
+	require.NoError(t, err)
+	var rows int64
+	for _, p := range parts {
+		rows += p.RowCount
+	}
+	assert.Equal(t, stored.Load(), rows, "every acknowledged message is saved")
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Shutdown with the stream longer than the timeout. Return errShutdownTimeout
+func Test_serveUntilDone_FAULT(t *testing.T) {
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	s := &server{broker: brk}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, done := startTestServer(t, ctx, s, 100*time.Millisecond, func() {})
+
+	tail, err := pb.NewIweClient(conn).TailMessages(context.Background(), &pb.TailRequest{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return brk.Len() == 1 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, errShutdownTimeout)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not stopped")
+	}
+
+	_, err = tail.Recv()
+	require.Error(t, err)
+}
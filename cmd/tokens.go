@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Subcommand token: tokens of the storage by the config are created, listed or revoked. Return exit code
+func tokenCommand(cfg configT, args []string) int {
+
+	objDB, closeDb, err := preparAct(cfg)
+	if err != nil {
+		log.Printf("fault preparatory actions: %v", err)
+		if closeDb != nil {
+			closeDb()
+		}
+		return exitFault
+	}
+	defer closeDb()
+
+	err = runTokenCommand(objDB, args, os.Stdout)
+	if err != nil {
+		log.Println(err)
+		return exitFault
+	}
+
+	return exitOk
+}
+
+// Command of tokens by arguments:
+//
+//	create -name NAME -projects alpha,beta -operations write,read [-ttl 720h]
+//	list
+//	revoke ID
+func runTokenCommand(objDB db.ActionsDB, args []string, out io.Writer) error {
+
+	if len(args) == 0 {
+		return errors.New("want token create, list or revoke")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		fs.SetOutput(out)
+		name := fs.String("name", "", "owner or service of the token")
+		projects := fs.String("projects", "", "projects separated by commas, * - any project")
+		operations := fs.String("operations", "", "operations separated by commas: write, read, admin")
+		ttl := fs.Duration("ttl", 0, "time to live of the token, 0 - never expires")
+		err := fs.Parse(args[1:])
+		if err != nil {
+			return err
+		}
+
+		token := db.TokenT{Name: *name, Projects: splitList(*projects), Operations: splitList(*operations)}
+		if *ttl > 0 {
+			token.TimeExpire = time.Now().Add(*ttl)
+		}
+		token, secret, err := objDB.CreateToken(token)
+		if err != nil {
+			return fmt.Errorf("fault create token: {%v}", err)
+		}
+		fmt.Fprintf(out, "id: %s\nsecret: %s\n", token.Id, secret)
+
+	case "list":
+		tokens, err := objDB.ListTokens()
+		if err != nil {
+			return fmt.Errorf("fault read tokens: {%v}", err)
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPROJECTS\tOPERATIONS\tCREATED\tEXPIRES\tREVOKED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Id, t.Name, strings.Join(t.Projects, ","), strings.Join(t.Operations, ","),
+				formatTime(t.TimeCreate), formatTime(t.TimeExpire), formatTime(t.TimeRevoke))
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("want token revoke ID")
+		}
+		token, err := objDB.RevokeToken(args[1])
+		if err != nil {
+			return fmt.Errorf("fault revoke token: {%v}", err)
+		}
+		fmt.Fprintf(out, "revoked: %s %s\n", token.Id, formatTime(token.TimeRevoke))
+
+	default:
+		return fmt.Errorf("not supported command of tokens: {%s}", args[0])
+	}
+
+	return nil
+}
+
+// Not empty items of the list separated by commas
+func splitList(s string) []string {
+
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Time for the output. Zero - "-"
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
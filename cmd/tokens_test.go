@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	broker "github.com/Part001-R/netlogiwe/pkg/broker"
+)
+
+// Start up the in-process server with authentication by tokens. Return the connection, the storage
+func startTokenServer(t *testing.T) (*grpc.ClientConn, db.ActionsDB) {
+	t.Helper()
+
+	brk, err := broker.New(16, broker.PolicyDrop)
+	require.NoError(t, err)
+	objDB := openTestStorage(t, true)
+	auth, err := newAuth(authCfgT{Tokens: true}, false, objDB)
+	require.NoError(t, err)
+	require.NotNil(t, auth)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unaryInterceptor),
+		grpc.ChainStreamInterceptor(auth.streamInterceptor))
+	pb.RegisterIweServer(srv, &server{db: objDB, broker: brk})
+	pb.RegisterAdminServer(srv, &adminServer{db: objDB})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, objDB
+}
+
+// Storage of tests: tokens of previous versions - admin of the project
+type scopedAdminStorageT struct {
+	db.ActionsDB
+}
+
+// Token by the secret with the admin operation of one project
+func (s scopedAdminStorageT) TokenBySecret(secret string) (db.TokenT, error) {
+	return db.TokenT{Id: "old", Name: "old", Projects: []string{"alpha"}, Operations: []string{db.OperationAdmin}}, nil
+}
+
+// Context of calls with the header authorization
+func withToken(secret string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+secret)
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Tokens are created by the admin, scoped to projects and operations, saved with messages and revoked
+func Test_Tokens_SUCCESS(t *testing.T) {
+
+	conn, objDB := startTokenServer(t)
+	iwe := pb.NewIweClient(conn)
+	admin := pb.NewAdminClient(conn)
+
+	_, secretAdmin, err := objDB.CreateToken(db.TokenT{Name: "ops", Projects: []string{db.AllProjects}, Operations: []string{db.OperationRead, db.OperationAdmin}})
+	require.NoError(t, err)
+	ctxAdmin := withToken(secretAdmin)
+
+	created, err := admin.CreateToken(ctxAdmin, &pb.CreateTokenRequest{Name: "ci", Projects: []string{"alpha"}, Operations: []string{db.OperationWrite}})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetSecret())
+	ctxWrite := withToken(created.GetSecret())
+
+	// write only to own projects
+	_, err = iwe.SaveMessage(ctxWrite, testMessage("alpha"))
+	require.NoError(t, err)
+	_, err = iwe.SaveMessage(ctxWrite, testMessage("beta"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = iwe.QueryMessages(ctxWrite, &pb.QueryRequest{NameProject: "alpha"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = admin.ListTokens(ctxWrite, &pb.ListTokensRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the id of token is saved with the message
+	resp, err := iwe.QueryMessages(ctxAdmin, &pb.QueryRequest{NameProject: "alpha"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetMessages(), 1)
+	assert.Equal(t, created.GetToken().GetId(), resp.GetMessages()[0].GetTokenId())
+
+	list, err := admin.ListTokens(ctxAdmin, &pb.ListTokensRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetTokens(), 2)
+
+	// the revoked token is not accepted at once
+	revoked, err := admin.RevokeToken(ctxAdmin, &pb.RevokeTokenRequest{Id: created.GetToken().GetId()})
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.GetToken().GetTimeRevoke())
+	_, err = iwe.SaveMessage(ctxWrite, testMessage("alpha"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// health is open without token
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+}
+
+// Test - Tokens are created, listed and revoked by the subcommand
+func Test_runTokenCommand_SUCCESS(t *testing.T) {
+
+	objDB := openTestStorage(t, true)
+	var out bytes.Buffer
+
+	require.NoError(t, runTokenCommand(objDB, []string{"create", "-name", "ci", "-projects", "alpha, beta", "-operations", "write", "-ttl", "24h"}, &out))
+	assert.Contains(t, out.String(), "secret: iwe_")
+
+	tokens, err := objDB.ListTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, []string{"alpha", "beta"}, tokens[0].Projects)
+	assert.False(t, tokens[0].TimeExpire.IsZero())
+
+	out.Reset()
+	require.NoError(t, runTokenCommand(objDB, []string{"list"}, &out))
+	assert.Contains(t, out.String(), tokens[0].Id)
+	assert.Contains(t, out.String(), "alpha,beta")
+
+	out.Reset()
+	require.NoError(t, runTokenCommand(objDB, []string{"revoke", tokens[0].Id}, &out))
+	assert.True(t, strings.HasPrefix(out.String(), "revoked: "+tokens[0].Id))
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Calls without token, with not valid token or header are not authenticated
+func Test_Tokens_FAULT(t *testing.T) {
+
+	conn, objDB := startTokenServer(t)
+	iwe := pb.NewIweClient(conn)
+
+	_, secret, err := objDB.CreateToken(db.TokenT{Name: "ci", Projects: []string{"alpha"}, Operations: []string{db.OperationWrite}})
+	require.NoError(t, err)
+
+	type tCase struct {
+		nameTest string
+		ctx      context.Context
+	}
+
+	tests := []tCase{
+		{nameTest: "without token", ctx: context.Background()},
+		{nameTest: "unknown token", ctx: withToken(secret + "x")},
+		{nameTest: "not bearer", ctx: metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+secret)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, err := iwe.SaveMessage(tt.ctx, testMessage("alpha"))
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+
+	// admin only with all projects: not created, stored tokens do not get it
+	_, secretAdmin, err := objDB.CreateToken(db.TokenT{Name: "ops", Projects: []string{db.AllProjects}, Operations: []string{db.OperationAdmin}})
+	require.NoError(t, err)
+	_, err = pb.NewAdminClient(conn).CreateToken(withToken(secretAdmin),
+		&pb.CreateTokenRequest{Name: "alpha-ops", Projects: []string{"alpha"}, Operations: []string{db.OperationAdmin}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	auth, err := newAuth(authCfgT{Tokens: true}, false, scopedAdminStorageT{ActionsDB: objDB})
+	require.NoError(t, err)
+	access, err := auth.accessByToken("Bearer old")
+	require.NoError(t, err)
+	err = authorize(access, pb.Admin_ListTokens_FullMethodName, &pb.ListTokensRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// Test - Not allowed arguments of the subcommand
+func Test_runTokenCommand_FAULT(t *testing.T) {
+
+	objDB := openTestStorage(t, true)
+
+	tests := []struct {
+		nameTest string
+		args     []string
+	}{
+		{nameTest: "no command", args: nil},
+		{nameTest: "unknown command", args: []string{"delete"}},
+		{nameTest: "no name", args: []string{"create", "-projects", "alpha", "-operations", "write"}},
+		{nameTest: "unknown operation", args: []string{"create", "-name", "ci", "-projects", "alpha", "-operations", "delete"}},
+		{nameTest: "revoke without id", args: []string{"revoke"}},
+		{nameTest: "revoke unknown", args: []string{"revoke", "unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			require.Error(t, runTokenCommand(objDB, tt.args, &bytes.Buffer{}))
+		})
+	}
+}
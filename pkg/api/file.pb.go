@@ -9,6 +9,7 @@ package api
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,12 +22,116 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SaveStatus int32
+
+const (
+	SaveStatus_SAVE_STATUS_UNSPECIFIED SaveStatus = 0
+	SaveStatus_SAVE_STATUS_STORED      SaveStatus = 1
+	SaveStatus_SAVE_STATUS_DUPLICATE   SaveStatus = 2 // the message was saved before and is not stored again
+	SaveStatus_SAVE_STATUS_SKIPPED     SaveStatus = 3 // the test message (T) is not stored
+	SaveStatus_SAVE_STATUS_FAILED      SaveStatus = 4 // the message of the batch is not stored, see status
+)
+
+// Enum value maps for SaveStatus.
+var (
+	SaveStatus_name = map[int32]string{
+		0: "SAVE_STATUS_UNSPECIFIED",
+		1: "SAVE_STATUS_STORED",
+		2: "SAVE_STATUS_DUPLICATE",
+		3: "SAVE_STATUS_SKIPPED",
+		4: "SAVE_STATUS_FAILED",
+	}
+	SaveStatus_value = map[string]int32{
+		"SAVE_STATUS_UNSPECIFIED": 0,
+		"SAVE_STATUS_STORED":      1,
+		"SAVE_STATUS_DUPLICATE":   2,
+		"SAVE_STATUS_SKIPPED":     3,
+		"SAVE_STATUS_FAILED":      4,
+	}
+)
+
+func (x SaveStatus) Enum() *SaveStatus {
+	p := new(SaveStatus)
+	*p = x
+	return p
+}
+
+func (x SaveStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SaveStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_file_proto_enumTypes[0].Descriptor()
+}
+
+func (SaveStatus) Type() protoreflect.EnumType {
+	return &file_file_proto_enumTypes[0]
+}
+
+func (x SaveStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SaveStatus.Descriptor instead.
+func (SaveStatus) EnumDescriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{0}
+}
+
+type TimeOrder int32
+
+const (
+	TimeOrder_TIME_ORDER_RECEIVED TimeOrder = 0 // time of receiving by the server
+	TimeOrder_TIME_ORDER_EVENT    TimeOrder = 1 // time of the event by the client, else the time of receiving
+)
+
+// Enum value maps for TimeOrder.
+var (
+	TimeOrder_name = map[int32]string{
+		0: "TIME_ORDER_RECEIVED",
+		1: "TIME_ORDER_EVENT",
+	}
+	TimeOrder_value = map[string]int32{
+		"TIME_ORDER_RECEIVED": 0,
+		"TIME_ORDER_EVENT":    1,
+	}
+)
+
+func (x TimeOrder) Enum() *TimeOrder {
+	p := new(TimeOrder)
+	*p = x
+	return p
+}
+
+func (x TimeOrder) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TimeOrder) Descriptor() protoreflect.EnumDescriptor {
+	return file_file_proto_enumTypes[1].Descriptor()
+}
+
+func (TimeOrder) Type() protoreflect.EnumType {
+	return &file_file_proto_enumTypes[1]
+}
+
+func (x TimeOrder) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TimeOrder.Descriptor instead.
+func (TimeOrder) EnumDescriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{1}
+}
+
 type MessageRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TypeMessage   string                 `protobuf:"bytes,1,opt,name=typeMessage,proto3" json:"typeMessage,omitempty"` // I, W, E
 	NameProject   string                 `protobuf:"bytes,2,opt,name=nameProject,proto3" json:"nameProject,omitempty"`
 	LocationEvent string                 `protobuf:"bytes,3,opt,name=locationEvent,proto3" json:"locationEvent,omitempty"`
 	BodyMessage   string                 `protobuf:"bytes,4,opt,name=bodyMessage,proto3" json:"bodyMessage,omitempty"`
+	MessageId     string                 `protobuf:"bytes,5,opt,name=messageId,proto3" json:"messageId,omitempty"`                                                                             // optional: UUID or producer:sequence. Repeats of the project within the window are not saved
+	EventTime     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=eventTime,proto3" json:"eventTime,omitempty"`                                                                             // optional: time of the event by the client. Not set - the time of receiving
+	Attributes    map[string]string      `protobuf:"bytes,7,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // optional: request id, host, version... Keys of A-Z, a-z, 0-9, _, ., -
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,16 +194,93 @@ func (x *MessageRequest) GetBodyMessage() string {
 	return ""
 }
 
+func (x *MessageRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *MessageRequest) GetEventTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EventTime
+	}
+	return nil
+}
+
+func (x *MessageRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type MessageRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NameTable     string                 `protobuf:"bytes,1,opt,name=nameTable,proto3" json:"nameTable,omitempty"` // log table (partition). Names are not reused
+	Id            int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`              // id in the log table
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageRef) Reset() {
+	*x = MessageRef{}
+	mi := &file_file_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageRef) ProtoMessage() {}
+
+func (x *MessageRef) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageRef.ProtoReflect.Descriptor instead.
+func (*MessageRef) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MessageRef) GetNameTable() string {
+	if x != nil {
+		return x.NameTable
+	}
+	return ""
+}
+
+func (x *MessageRef) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
 type MessageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`        // Ok. Kept for old clients, see code
+	Duplicate     bool                   `protobuf:"varint,2,opt,name=duplicate,proto3" json:"duplicate,omitempty"` // the message was saved before and is not stored again
+	Code          SaveStatus             `protobuf:"varint,3,opt,name=code,proto3,enum=apigrps.SaveStatus" json:"code,omitempty"`
+	Ref           *MessageRef            `protobuf:"bytes,4,opt,name=ref,proto3" json:"ref,omitempty"`           // stored message. Not set - nothing is stored by the request
+	Received      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=received,proto3" json:"received,omitempty"` // time of receiving by the server
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *MessageResponse) Reset() {
 	*x = MessageResponse{}
-	mi := &file_file_proto_msgTypes[1]
+	mi := &file_file_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -110,7 +292,7 @@ func (x *MessageResponse) String() string {
 func (*MessageResponse) ProtoMessage() {}
 
 func (x *MessageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_file_proto_msgTypes[1]
+	mi := &file_file_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -123,7 +305,7 @@ func (x *MessageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MessageResponse.ProtoReflect.Descriptor instead.
 func (*MessageResponse) Descriptor() ([]byte, []int) {
-	return file_file_proto_rawDescGZIP(), []int{1}
+	return file_file_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *MessageResponse) GetStatus() string {
@@ -133,21 +315,1656 @@ func (x *MessageResponse) GetStatus() string {
 	return ""
 }
 
-var File_file_proto protoreflect.FileDescriptor
+func (x *MessageResponse) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
 
-const file_file_proto_rawDesc = "" +
-	"\n" +
-	"\n" +
-	"file.proto\x12\aapigrps\"\x9c\x01\n" +
-	"\x0eMessageRequest\x12 \n" +
-	"\vtypeMessage\x18\x01 \x01(\tR\vtypeMessage\x12 \n" +
-	"\vnameProject\x18\x02 \x01(\tR\vnameProject\x12$\n" +
-	"\rlocationEvent\x18\x03 \x01(\tR\rlocationEvent\x12 \n" +
-	"\vbodyMessage\x18\x04 \x01(\tR\vbodyMessage\")\n" +
-	"\x0fMessageResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2I\n" +
-	"\x03iwe\x12B\n" +
-	"\vSaveMessage\x12\x17.apigrps.MessageRequest\x1a\x18.apigrps.MessageResponse\"\x00B$Z\"github.com/Part001-R/grpcs/pkg/apib\x06proto3"
+func (x *MessageResponse) GetCode() SaveStatus {
+	if x != nil {
+		return x.Code
+	}
+	return SaveStatus_SAVE_STATUS_UNSPECIFIED
+}
+
+func (x *MessageResponse) GetRef() *MessageRef {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+func (x *MessageResponse) GetReceived() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Received
+	}
+	return nil
+}
+
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*MessageRequest      `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_file_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchRequest) GetMessages() []*MessageRequest {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type MessageResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`         // index of the message in the batch or stream
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`        // Ok or the error
+	Duplicate     bool                   `protobuf:"varint,3,opt,name=duplicate,proto3" json:"duplicate,omitempty"` // the message was saved before and is not stored again
+	Code          SaveStatus             `protobuf:"varint,4,opt,name=code,proto3,enum=apigrps.SaveStatus" json:"code,omitempty"`
+	Ref           *MessageRef            `protobuf:"bytes,5,opt,name=ref,proto3" json:"ref,omitempty"` // stored message. Not set - the message is not stored
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageResult) Reset() {
+	*x = MessageResult{}
+	mi := &file_file_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageResult) ProtoMessage() {}
+
+func (x *MessageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageResult.ProtoReflect.Descriptor instead.
+func (*MessageResult) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MessageResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *MessageResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *MessageResult) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+func (x *MessageResult) GetCode() SaveStatus {
+	if x != nil {
+		return x.Code
+	}
+	return SaveStatus_SAVE_STATUS_UNSPECIFIED
+}
+
+func (x *MessageResult) GetRef() *MessageRef {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*MessageResult       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Saved         int32                  `protobuf:"varint,2,opt,name=saved,proto3" json:"saved,omitempty"` // stored, skipped T messages are not counted
+	Failed        int32                  `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	Duplicates    int32                  `protobuf:"varint,4,opt,name=duplicates,proto3" json:"duplicates,omitempty"` // not counted in saved
+	Received      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=received,proto3" json:"received,omitempty"`      // time of receiving by the server (the first message of the stream)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_file_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchResponse) GetResults() []*MessageResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *BatchResponse) GetSaved() int32 {
+	if x != nil {
+		return x.Saved
+	}
+	return 0
+}
+
+func (x *BatchResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *BatchResponse) GetDuplicates() int32 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *BatchResponse) GetReceived() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Received
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TypeMessage   string                 `protobuf:"bytes,1,opt,name=typeMessage,proto3" json:"typeMessage,omitempty"`     // I, W, E. Empty - all types
+	NameProject   string                 `protobuf:"bytes,2,opt,name=nameProject,proto3" json:"nameProject,omitempty"`     // exact match. Empty - any
+	LocationEvent string                 `protobuf:"bytes,3,opt,name=locationEvent,proto3" json:"locationEvent,omitempty"` // exact match. Empty - any
+	BodySubstring string                 `protobuf:"bytes,4,opt,name=bodySubstring,proto3" json:"bodySubstring,omitempty"` // substring of bodyMessage. Empty - any
+	TimeFrom      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timeFrom,proto3" json:"timeFrom,omitempty"`           // inclusive. Not set - no bound
+	TimeTo        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timeTo,proto3" json:"timeTo,omitempty"`               // inclusive. Not set - no bound
+	Limit         int32                  `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`                // 0 - default
+	Offset        int32                  `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
+	Order         TimeOrder              `protobuf:"varint,9,opt,name=order,proto3,enum=apigrps.TimeOrder" json:"order,omitempty"`                                                              // time of ordering and of timeFrom/timeTo
+	Attributes    map[string]string      `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // all attributes are matched exactly. Empty - any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_file_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *QueryRequest) GetTypeMessage() string {
+	if x != nil {
+		return x.TypeMessage
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetNameProject() string {
+	if x != nil {
+		return x.NameProject
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetLocationEvent() string {
+	if x != nil {
+		return x.LocationEvent
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetBodySubstring() string {
+	if x != nil {
+		return x.BodySubstring
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetTimeFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeFrom
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetTimeTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeTo
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetOrder() TimeOrder {
+	if x != nil {
+		return x.Order
+	}
+	return TimeOrder_TIME_ORDER_RECEIVED
+}
+
+func (x *QueryRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type StoredMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TypeMessage   string                 `protobuf:"bytes,1,opt,name=typeMessage,proto3" json:"typeMessage,omitempty"`
+	NameProject   string                 `protobuf:"bytes,2,opt,name=nameProject,proto3" json:"nameProject,omitempty"`
+	LocationEvent string                 `protobuf:"bytes,3,opt,name=locationEvent,proto3" json:"locationEvent,omitempty"`
+	BodyMessage   string                 `protobuf:"bytes,4,opt,name=bodyMessage,proto3" json:"bodyMessage,omitempty"`
+	Id            int64                  `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`              // id in the log table
+	NameTable     string                 `protobuf:"bytes,6,opt,name=nameTable,proto3" json:"nameTable,omitempty"` // log table (partition) that holds the message
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // time of receiving
+	EventTime     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=eventTime,proto3" json:"eventTime,omitempty"` // time of the event by the client, else the time of receiving
+	Skewed        bool                   `protobuf:"varint,9,opt,name=skewed,proto3" json:"skewed,omitempty"`      // eventTime is out of bounds of the server clock
+	Attributes    map[string]string      `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	TokenId       string                 `protobuf:"bytes,11,opt,name=tokenId,proto3" json:"tokenId,omitempty"` // id of the token of the client that saved the message. Empty - without token
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredMessage) Reset() {
+	*x = StoredMessage{}
+	mi := &file_file_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredMessage) ProtoMessage() {}
+
+func (x *StoredMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredMessage.ProtoReflect.Descriptor instead.
+func (*StoredMessage) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StoredMessage) GetTypeMessage() string {
+	if x != nil {
+		return x.TypeMessage
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetNameProject() string {
+	if x != nil {
+		return x.NameProject
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetLocationEvent() string {
+	if x != nil {
+		return x.LocationEvent
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetBodyMessage() string {
+	if x != nil {
+		return x.BodyMessage
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StoredMessage) GetNameTable() string {
+	if x != nil {
+		return x.NameTable
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *StoredMessage) GetEventTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EventTime
+	}
+	return nil
+}
+
+func (x *StoredMessage) GetSkewed() bool {
+	if x != nil {
+		return x.Skewed
+	}
+	return false
+}
+
+func (x *StoredMessage) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *StoredMessage) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*StoredMessage       `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_file_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QueryResponse) GetMessages() []*StoredMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Query          string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`                                                                                     // words, "phrases", prefix*, AND, OR, NOT of bodyMessage and locationEvent
+	TypeMessage    string                 `protobuf:"bytes,2,opt,name=typeMessage,proto3" json:"typeMessage,omitempty"`                                                                         // I, W, E. Empty - all types
+	NameProject    string                 `protobuf:"bytes,3,opt,name=nameProject,proto3" json:"nameProject,omitempty"`                                                                         // exact match. Empty - any
+	TimeFrom       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timeFrom,proto3" json:"timeFrom,omitempty"`                                                                               // inclusive. Not set - no bound
+	TimeTo         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timeTo,proto3" json:"timeTo,omitempty"`                                                                                   // inclusive. Not set - no bound
+	Attributes     map[string]string      `protobuf:"bytes,6,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // all attributes are matched exactly. Empty - any
+	Limit          int32                  `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`                                                                                    // 0 - default
+	Offset         int32                  `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
+	HighlightStart string                 `protobuf:"bytes,9,opt,name=highlightStart,proto3" json:"highlightStart,omitempty"` // before found words in the snippet. Empty - <b>
+	HighlightEnd   string                 `protobuf:"bytes,10,opt,name=highlightEnd,proto3" json:"highlightEnd,omitempty"`    // after found words in the snippet. Empty - </b>
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_file_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTypeMessage() string {
+	if x != nil {
+		return x.TypeMessage
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetNameProject() string {
+	if x != nil {
+		return x.NameProject
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTimeFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeFrom
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetTimeTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeTo
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetHighlightStart() string {
+	if x != nil {
+		return x.HighlightStart
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetHighlightEnd() string {
+	if x != nil {
+		return x.HighlightEnd
+	}
+	return ""
+}
+
+type FoundMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *StoredMessage         `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Rank          float64                `protobuf:"fixed64,2,opt,name=rank,proto3" json:"rank,omitempty"`     // relevance to the query, greater is better
+	Snippet       string                 `protobuf:"bytes,3,opt,name=snippet,proto3" json:"snippet,omitempty"` // part of bodyMessage with highlighted words
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FoundMessage) Reset() {
+	*x = FoundMessage{}
+	mi := &file_file_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FoundMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FoundMessage) ProtoMessage() {}
+
+func (x *FoundMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FoundMessage.ProtoReflect.Descriptor instead.
+func (*FoundMessage) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FoundMessage) GetMessage() *StoredMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *FoundMessage) GetRank() float64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *FoundMessage) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*FoundMessage        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"` // by relevance, then newest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_file_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchResponse) GetMessages() []*FoundMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type TailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TypeMessage   string                 `protobuf:"bytes,1,opt,name=typeMessage,proto3" json:"typeMessage,omitempty"` // I, W, E. Empty - all types
+	NameProject   string                 `protobuf:"bytes,2,opt,name=nameProject,proto3" json:"nameProject,omitempty"` // exact match. Empty - any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailRequest) Reset() {
+	*x = TailRequest{}
+	mi := &file_file_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailRequest) ProtoMessage() {}
+
+func (x *TailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailRequest.ProtoReflect.Descriptor instead.
+func (*TailRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TailRequest) GetTypeMessage() string {
+	if x != nil {
+		return x.TypeMessage
+	}
+	return ""
+}
+
+func (x *TailRequest) GetNameProject() string {
+	if x != nil {
+		return x.NameProject
+	}
+	return ""
+}
+
+type Partition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TypeTable     string                 `protobuf:"bytes,1,opt,name=typeTable,proto3" json:"typeTable,omitempty"` // I, W, E
+	NameTable     string                 `protobuf:"bytes,2,opt,name=nameTable,proto3" json:"nameTable,omitempty"`
+	Seq           int32                  `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"` // index in the name of log table
+	TimeOpen      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timeOpen,proto3" json:"timeOpen,omitempty"`
+	TimeClose     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timeClose,proto3" json:"timeClose,omitempty"` // not set - the current log table
+	State         string                 `protobuf:"bytes,6,opt,name=state,proto3" json:"state,omitempty"`         // active, closed, archived, dropped
+	FirstId       int64                  `protobuf:"varint,7,opt,name=firstId,proto3" json:"firstId,omitempty"`
+	LastId        int64                  `protobuf:"varint,8,opt,name=lastId,proto3" json:"lastId,omitempty"`
+	FirstTime     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=firstTime,proto3" json:"firstTime,omitempty"` // not set - no messages
+	LastTime      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=lastTime,proto3" json:"lastTime,omitempty"`
+	RowCount      int64                  `protobuf:"varint,11,opt,name=rowCount,proto3" json:"rowCount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Partition) Reset() {
+	*x = Partition{}
+	mi := &file_file_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Partition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Partition) ProtoMessage() {}
+
+func (x *Partition) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Partition.ProtoReflect.Descriptor instead.
+func (*Partition) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Partition) GetTypeTable() string {
+	if x != nil {
+		return x.TypeTable
+	}
+	return ""
+}
+
+func (x *Partition) GetNameTable() string {
+	if x != nil {
+		return x.NameTable
+	}
+	return ""
+}
+
+func (x *Partition) GetSeq() int32 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Partition) GetTimeOpen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeOpen
+	}
+	return nil
+}
+
+func (x *Partition) GetTimeClose() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeClose
+	}
+	return nil
+}
+
+func (x *Partition) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Partition) GetFirstId() int64 {
+	if x != nil {
+		return x.FirstId
+	}
+	return 0
+}
+
+func (x *Partition) GetLastId() int64 {
+	if x != nil {
+		return x.LastId
+	}
+	return 0
+}
+
+func (x *Partition) GetFirstTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstTime
+	}
+	return nil
+}
+
+func (x *Partition) GetLastTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTime
+	}
+	return nil
+}
+
+func (x *Partition) GetRowCount() int64 {
+	if x != nil {
+		return x.RowCount
+	}
+	return 0
+}
+
+type RetentionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DryRun        bool                   `protobuf:"varint,1,opt,name=dryRun,proto3" json:"dryRun,omitempty"` // only return log tables to be removed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetentionRequest) Reset() {
+	*x = RetentionRequest{}
+	mi := &file_file_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetentionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetentionRequest) ProtoMessage() {}
+
+func (x *RetentionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetentionRequest.ProtoReflect.Descriptor instead.
+func (*RetentionRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RetentionRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type RetentionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       []*Partition           `protobuf:"bytes,1,rep,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetentionResponse) Reset() {
+	*x = RetentionResponse{}
+	mi := &file_file_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetentionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetentionResponse) ProtoMessage() {}
+
+func (x *RetentionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetentionResponse.ProtoReflect.Descriptor instead.
+func (*RetentionResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RetentionResponse) GetRemoved() []*Partition {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+type PartitionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TypeTable     string                 `protobuf:"bytes,1,opt,name=typeTable,proto3" json:"typeTable,omitempty"` // I, W, E. Empty - all types
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PartitionsRequest) Reset() {
+	*x = PartitionsRequest{}
+	mi := &file_file_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartitionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartitionsRequest) ProtoMessage() {}
+
+func (x *PartitionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartitionsRequest.ProtoReflect.Descriptor instead.
+func (*PartitionsRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PartitionsRequest) GetTypeTable() string {
+	if x != nil {
+		return x.TypeTable
+	}
+	return ""
+}
+
+type PartitionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Partitions    []*Partition           `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PartitionsResponse) Reset() {
+	*x = PartitionsResponse{}
+	mi := &file_file_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartitionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartitionsResponse) ProtoMessage() {}
+
+func (x *PartitionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartitionsResponse.ProtoReflect.Descriptor instead.
+func (*PartitionsResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PartitionsResponse) GetPartitions() []*Partition {
+	if x != nil {
+		return x.Partitions
+	}
+	return nil
+}
+
+type Token struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                 // saved with messages of the token
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`             // owner or service of the token
+	Projects      []string               `protobuf:"bytes,3,rep,name=projects,proto3" json:"projects,omitempty"`     // * - any project
+	Operations    []string               `protobuf:"bytes,4,rep,name=operations,proto3" json:"operations,omitempty"` // write, read, admin
+	TimeCreate    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timeCreate,proto3" json:"timeCreate,omitempty"`
+	TimeExpire    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timeExpire,proto3" json:"timeExpire,omitempty"` // not set - never
+	TimeRevoke    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=timeRevoke,proto3" json:"timeRevoke,omitempty"` // not set - not revoked
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Token) Reset() {
+	*x = Token{}
+	mi := &file_file_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Token) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Token) ProtoMessage() {}
+
+func (x *Token) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Token.ProtoReflect.Descriptor instead.
+func (*Token) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Token) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Token) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Token) GetProjects() []string {
+	if x != nil {
+		return x.Projects
+	}
+	return nil
+}
+
+func (x *Token) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *Token) GetTimeCreate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeCreate
+	}
+	return nil
+}
+
+func (x *Token) GetTimeExpire() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeExpire
+	}
+	return nil
+}
+
+func (x *Token) GetTimeRevoke() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeRevoke
+	}
+	return nil
+}
+
+type CreateTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Projects      []string               `protobuf:"bytes,2,rep,name=projects,proto3" json:"projects,omitempty"`     // * - any project
+	Operations    []string               `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"` // write, read, admin
+	TimeExpire    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timeExpire,proto3" json:"timeExpire,omitempty"` // not set - never
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTokenRequest) Reset() {
+	*x = CreateTokenRequest{}
+	mi := &file_file_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTokenRequest) ProtoMessage() {}
+
+func (x *CreateTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTokenRequest.ProtoReflect.Descriptor instead.
+func (*CreateTokenRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CreateTokenRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateTokenRequest) GetProjects() []string {
+	if x != nil {
+		return x.Projects
+	}
+	return nil
+}
+
+func (x *CreateTokenRequest) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *CreateTokenRequest) GetTimeExpire() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeExpire
+	}
+	return nil
+}
+
+type CreateTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         *Token                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Secret        string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"` // value of the header "authorization: Bearer <secret>". Only its hash is stored, it is shown once
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTokenResponse) Reset() {
+	*x = CreateTokenResponse{}
+	mi := &file_file_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTokenResponse) ProtoMessage() {}
+
+func (x *CreateTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTokenResponse.ProtoReflect.Descriptor instead.
+func (*CreateTokenResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CreateTokenResponse) GetToken() *Token {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+func (x *CreateTokenResponse) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type ListTokensRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensRequest) Reset() {
+	*x = ListTokensRequest{}
+	mi := &file_file_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensRequest) ProtoMessage() {}
+
+func (x *ListTokensRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensRequest.ProtoReflect.Descriptor instead.
+func (*ListTokensRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{21}
+}
+
+type ListTokensResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tokens        []*Token               `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTokensResponse) Reset() {
+	*x = ListTokensResponse{}
+	mi := &file_file_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTokensResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTokensResponse) ProtoMessage() {}
+
+func (x *ListTokensResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTokensResponse.ProtoReflect.Descriptor instead.
+func (*ListTokensResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListTokensResponse) GetTokens() []*Token {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+type RevokeTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeTokenRequest) Reset() {
+	*x = RevokeTokenRequest{}
+	mi := &file_file_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeTokenRequest) ProtoMessage() {}
+
+func (x *RevokeTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeTokenRequest.ProtoReflect.Descriptor instead.
+func (*RevokeTokenRequest) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RevokeTokenRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RevokeTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         *Token                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeTokenResponse) Reset() {
+	*x = RevokeTokenResponse{}
+	mi := &file_file_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeTokenResponse) ProtoMessage() {}
+
+func (x *RevokeTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_file_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeTokenResponse.ProtoReflect.Descriptor instead.
+func (*RevokeTokenResponse) Descriptor() ([]byte, []int) {
+	return file_file_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RevokeTokenResponse) GetToken() *Token {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+var File_file_proto protoreflect.FileDescriptor
+
+const file_file_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"file.proto\x12\aapigrps\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfc\x02\n" +
+	"\x0eMessageRequest\x12 \n" +
+	"\vtypeMessage\x18\x01 \x01(\tR\vtypeMessage\x12 \n" +
+	"\vnameProject\x18\x02 \x01(\tR\vnameProject\x12$\n" +
+	"\rlocationEvent\x18\x03 \x01(\tR\rlocationEvent\x12 \n" +
+	"\vbodyMessage\x18\x04 \x01(\tR\vbodyMessage\x12\x1c\n" +
+	"\tmessageId\x18\x05 \x01(\tR\tmessageId\x128\n" +
+	"\teventTime\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\teventTime\x12G\n" +
+	"\n" +
+	"attributes\x18\a \x03(\v2'.apigrps.MessageRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\":\n" +
+	"\n" +
+	"MessageRef\x12\x1c\n" +
+	"\tnameTable\x18\x01 \x01(\tR\tnameTable\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\"\xcf\x01\n" +
+	"\x0fMessageResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1c\n" +
+	"\tduplicate\x18\x02 \x01(\bR\tduplicate\x12'\n" +
+	"\x04code\x18\x03 \x01(\x0e2\x13.apigrps.SaveStatusR\x04code\x12%\n" +
+	"\x03ref\x18\x04 \x01(\v2\x13.apigrps.MessageRefR\x03ref\x126\n" +
+	"\breceived\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\breceived\"C\n" +
+	"\fBatchRequest\x123\n" +
+	"\bmessages\x18\x01 \x03(\v2\x17.apigrps.MessageRequestR\bmessages\"\xab\x01\n" +
+	"\rMessageResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1c\n" +
+	"\tduplicate\x18\x03 \x01(\bR\tduplicate\x12'\n" +
+	"\x04code\x18\x04 \x01(\x0e2\x13.apigrps.SaveStatusR\x04code\x12%\n" +
+	"\x03ref\x18\x05 \x01(\v2\x13.apigrps.MessageRefR\x03ref\"\xc7\x01\n" +
+	"\rBatchResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.apigrps.MessageResultR\aresults\x12\x14\n" +
+	"\x05saved\x18\x02 \x01(\x05R\x05saved\x12\x16\n" +
+	"\x06failed\x18\x03 \x01(\x05R\x06failed\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x04 \x01(\x05R\n" +
+	"duplicates\x126\n" +
+	"\breceived\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\breceived\"\xe8\x03\n" +
+	"\fQueryRequest\x12 \n" +
+	"\vtypeMessage\x18\x01 \x01(\tR\vtypeMessage\x12 \n" +
+	"\vnameProject\x18\x02 \x01(\tR\vnameProject\x12$\n" +
+	"\rlocationEvent\x18\x03 \x01(\tR\rlocationEvent\x12$\n" +
+	"\rbodySubstring\x18\x04 \x01(\tR\rbodySubstring\x126\n" +
+	"\btimeFrom\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\btimeFrom\x122\n" +
+	"\x06timeTo\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x06timeTo\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\b \x01(\x05R\x06offset\x12(\n" +
+	"\x05order\x18\t \x01(\x0e2\x12.apigrps.TimeOrderR\x05order\x12E\n" +
+	"\n" +
+	"attributes\x18\n" +
+	" \x03(\v2%.apigrps.QueryRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xf6\x03\n" +
+	"\rStoredMessage\x12 \n" +
+	"\vtypeMessage\x18\x01 \x01(\tR\vtypeMessage\x12 \n" +
+	"\vnameProject\x18\x02 \x01(\tR\vnameProject\x12$\n" +
+	"\rlocationEvent\x18\x03 \x01(\tR\rlocationEvent\x12 \n" +
+	"\vbodyMessage\x18\x04 \x01(\tR\vbodyMessage\x12\x0e\n" +
+	"\x02id\x18\x05 \x01(\x03R\x02id\x12\x1c\n" +
+	"\tnameTable\x18\x06 \x01(\tR\tnameTable\x128\n" +
+	"\ttimestamp\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x128\n" +
+	"\teventTime\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\teventTime\x12\x16\n" +
+	"\x06skewed\x18\t \x01(\bR\x06skewed\x12F\n" +
+	"\n" +
+	"attributes\x18\n" +
+	" \x03(\v2&.apigrps.StoredMessage.AttributesEntryR\n" +
+	"attributes\x12\x18\n" +
+	"\atokenId\x18\v \x01(\tR\atokenId\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"C\n" +
+	"\rQueryResponse\x122\n" +
+	"\bmessages\x18\x01 \x03(\v2\x16.apigrps.StoredMessageR\bmessages\"\xd6\x03\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12 \n" +
+	"\vtypeMessage\x18\x02 \x01(\tR\vtypeMessage\x12 \n" +
+	"\vnameProject\x18\x03 \x01(\tR\vnameProject\x126\n" +
+	"\btimeFrom\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\btimeFrom\x122\n" +
+	"\x06timeTo\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x06timeTo\x12F\n" +
+	"\n" +
+	"attributes\x18\x06 \x03(\v2&.apigrps.SearchRequest.AttributesEntryR\n" +
+	"attributes\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\b \x01(\x05R\x06offset\x12&\n" +
+	"\x0ehighlightStart\x18\t \x01(\tR\x0ehighlightStart\x12\"\n" +
+	"\fhighlightEnd\x18\n" +
+	" \x01(\tR\fhighlightEnd\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"n\n" +
+	"\fFoundMessage\x120\n" +
+	"\amessage\x18\x01 \x01(\v2\x16.apigrps.StoredMessageR\amessage\x12\x12\n" +
+	"\x04rank\x18\x02 \x01(\x01R\x04rank\x12\x18\n" +
+	"\asnippet\x18\x03 \x01(\tR\asnippet\"C\n" +
+	"\x0eSearchResponse\x121\n" +
+	"\bmessages\x18\x01 \x03(\v2\x15.apigrps.FoundMessageR\bmessages\"Q\n" +
+	"\vTailRequest\x12 \n" +
+	"\vtypeMessage\x18\x01 \x01(\tR\vtypeMessage\x12 \n" +
+	"\vnameProject\x18\x02 \x01(\tR\vnameProject\"\xa1\x03\n" +
+	"\tPartition\x12\x1c\n" +
+	"\ttypeTable\x18\x01 \x01(\tR\ttypeTable\x12\x1c\n" +
+	"\tnameTable\x18\x02 \x01(\tR\tnameTable\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x05R\x03seq\x126\n" +
+	"\btimeOpen\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\btimeOpen\x128\n" +
+	"\ttimeClose\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimeClose\x12\x14\n" +
+	"\x05state\x18\x06 \x01(\tR\x05state\x12\x18\n" +
+	"\afirstId\x18\a \x01(\x03R\afirstId\x12\x16\n" +
+	"\x06lastId\x18\b \x01(\x03R\x06lastId\x128\n" +
+	"\tfirstTime\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tfirstTime\x126\n" +
+	"\blastTime\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\blastTime\x12\x1a\n" +
+	"\browCount\x18\v \x01(\x03R\browCount\"*\n" +
+	"\x10RetentionRequest\x12\x16\n" +
+	"\x06dryRun\x18\x01 \x01(\bR\x06dryRun\"A\n" +
+	"\x11RetentionResponse\x12,\n" +
+	"\aremoved\x18\x01 \x03(\v2\x12.apigrps.PartitionR\aremoved\"1\n" +
+	"\x11PartitionsRequest\x12\x1c\n" +
+	"\ttypeTable\x18\x01 \x01(\tR\ttypeTable\"H\n" +
+	"\x12PartitionsResponse\x122\n" +
+	"\n" +
+	"partitions\x18\x01 \x03(\v2\x12.apigrps.PartitionR\n" +
+	"partitions\"\x9b\x02\n" +
+	"\x05Token\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bprojects\x18\x03 \x03(\tR\bprojects\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x04 \x03(\tR\n" +
+	"operations\x12:\n" +
+	"\n" +
+	"timeCreate\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"timeCreate\x12:\n" +
+	"\n" +
+	"timeExpire\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"timeExpire\x12:\n" +
+	"\n" +
+	"timeRevoke\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"timeRevoke\"\xa0\x01\n" +
+	"\x12CreateTokenRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bprojects\x18\x02 \x03(\tR\bprojects\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x03 \x03(\tR\n" +
+	"operations\x12:\n" +
+	"\n" +
+	"timeExpire\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"timeExpire\"S\n" +
+	"\x13CreateTokenResponse\x12$\n" +
+	"\x05token\x18\x01 \x01(\v2\x0e.apigrps.TokenR\x05token\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"\x13\n" +
+	"\x11ListTokensRequest\"<\n" +
+	"\x12ListTokensResponse\x12&\n" +
+	"\x06tokens\x18\x01 \x03(\v2\x0e.apigrps.TokenR\x06tokens\"$\n" +
+	"\x12RevokeTokenRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\";\n" +
+	"\x13RevokeTokenResponse\x12$\n" +
+	"\x05token\x18\x01 \x01(\v2\x0e.apigrps.TokenR\x05token*\x8d\x01\n" +
+	"\n" +
+	"SaveStatus\x12\x1b\n" +
+	"\x17SAVE_STATUS_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12SAVE_STATUS_STORED\x10\x01\x12\x19\n" +
+	"\x15SAVE_STATUS_DUPLICATE\x10\x02\x12\x17\n" +
+	"\x13SAVE_STATUS_SKIPPED\x10\x03\x12\x16\n" +
+	"\x12SAVE_STATUS_FAILED\x10\x04*:\n" +
+	"\tTimeOrder\x12\x17\n" +
+	"\x13TIME_ORDER_RECEIVED\x10\x00\x12\x14\n" +
+	"\x10TIME_ORDER_EVENT\x10\x012\x9a\x03\n" +
+	"\x03iwe\x12B\n" +
+	"\vSaveMessage\x12\x17.apigrps.MessageRequest\x1a\x18.apigrps.MessageResponse\"\x00\x12?\n" +
+	"\fSaveMessages\x12\x15.apigrps.BatchRequest\x1a\x16.apigrps.BatchResponse\"\x00\x12E\n" +
+	"\x0eStreamMessages\x12\x17.apigrps.MessageRequest\x1a\x16.apigrps.BatchResponse\"\x00(\x01\x12@\n" +
+	"\rQueryMessages\x12\x15.apigrps.QueryRequest\x1a\x16.apigrps.QueryResponse\"\x00\x12@\n" +
+	"\fTailMessages\x12\x14.apigrps.TailRequest\x1a\x16.apigrps.StoredMessage\"\x000\x01\x12C\n" +
+	"\x0eSearchMessages\x12\x16.apigrps.SearchRequest\x1a\x17.apigrps.SearchResponse\"\x002\x80\x03\n" +
+	"\x05admin\x12I\n" +
+	"\x0eApplyRetention\x12\x19.apigrps.RetentionRequest\x1a\x1a.apigrps.RetentionResponse\"\x00\x12K\n" +
+	"\x0eListPartitions\x12\x1a.apigrps.PartitionsRequest\x1a\x1b.apigrps.PartitionsResponse\"\x00\x12J\n" +
+	"\vCreateToken\x12\x1b.apigrps.CreateTokenRequest\x1a\x1c.apigrps.CreateTokenResponse\"\x00\x12G\n" +
+	"\n" +
+	"ListTokens\x12\x1a.apigrps.ListTokensRequest\x1a\x1b.apigrps.ListTokensResponse\"\x00\x12J\n" +
+	"\vRevokeToken\x12\x1b.apigrps.RevokeTokenRequest\x1a\x1c.apigrps.RevokeTokenResponse\"\x00B$Z\"github.com/Part001-R/grpcs/pkg/apib\x06proto3"
 
 var (
 	file_file_proto_rawDescOnce sync.Once
@@ -161,19 +1978,106 @@ func file_file_proto_rawDescGZIP() []byte {
 	return file_file_proto_rawDescData
 }
 
-var file_file_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_file_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_file_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_file_proto_goTypes = []any{
-	(*MessageRequest)(nil),  // 0: apigrps.MessageRequest
-	(*MessageResponse)(nil), // 1: apigrps.MessageResponse
+	(SaveStatus)(0),               // 0: apigrps.SaveStatus
+	(TimeOrder)(0),                // 1: apigrps.TimeOrder
+	(*MessageRequest)(nil),        // 2: apigrps.MessageRequest
+	(*MessageRef)(nil),            // 3: apigrps.MessageRef
+	(*MessageResponse)(nil),       // 4: apigrps.MessageResponse
+	(*BatchRequest)(nil),          // 5: apigrps.BatchRequest
+	(*MessageResult)(nil),         // 6: apigrps.MessageResult
+	(*BatchResponse)(nil),         // 7: apigrps.BatchResponse
+	(*QueryRequest)(nil),          // 8: apigrps.QueryRequest
+	(*StoredMessage)(nil),         // 9: apigrps.StoredMessage
+	(*QueryResponse)(nil),         // 10: apigrps.QueryResponse
+	(*SearchRequest)(nil),         // 11: apigrps.SearchRequest
+	(*FoundMessage)(nil),          // 12: apigrps.FoundMessage
+	(*SearchResponse)(nil),        // 13: apigrps.SearchResponse
+	(*TailRequest)(nil),           // 14: apigrps.TailRequest
+	(*Partition)(nil),             // 15: apigrps.Partition
+	(*RetentionRequest)(nil),      // 16: apigrps.RetentionRequest
+	(*RetentionResponse)(nil),     // 17: apigrps.RetentionResponse
+	(*PartitionsRequest)(nil),     // 18: apigrps.PartitionsRequest
+	(*PartitionsResponse)(nil),    // 19: apigrps.PartitionsResponse
+	(*Token)(nil),                 // 20: apigrps.Token
+	(*CreateTokenRequest)(nil),    // 21: apigrps.CreateTokenRequest
+	(*CreateTokenResponse)(nil),   // 22: apigrps.CreateTokenResponse
+	(*ListTokensRequest)(nil),     // 23: apigrps.ListTokensRequest
+	(*ListTokensResponse)(nil),    // 24: apigrps.ListTokensResponse
+	(*RevokeTokenRequest)(nil),    // 25: apigrps.RevokeTokenRequest
+	(*RevokeTokenResponse)(nil),   // 26: apigrps.RevokeTokenResponse
+	nil,                           // 27: apigrps.MessageRequest.AttributesEntry
+	nil,                           // 28: apigrps.QueryRequest.AttributesEntry
+	nil,                           // 29: apigrps.StoredMessage.AttributesEntry
+	nil,                           // 30: apigrps.SearchRequest.AttributesEntry
+	(*timestamppb.Timestamp)(nil), // 31: google.protobuf.Timestamp
 }
 var file_file_proto_depIdxs = []int32{
-	0, // 0: apigrps.iwe.SaveMessage:input_type -> apigrps.MessageRequest
-	1, // 1: apigrps.iwe.SaveMessage:output_type -> apigrps.MessageResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	31, // 0: apigrps.MessageRequest.eventTime:type_name -> google.protobuf.Timestamp
+	27, // 1: apigrps.MessageRequest.attributes:type_name -> apigrps.MessageRequest.AttributesEntry
+	0,  // 2: apigrps.MessageResponse.code:type_name -> apigrps.SaveStatus
+	3,  // 3: apigrps.MessageResponse.ref:type_name -> apigrps.MessageRef
+	31, // 4: apigrps.MessageResponse.received:type_name -> google.protobuf.Timestamp
+	2,  // 5: apigrps.BatchRequest.messages:type_name -> apigrps.MessageRequest
+	0,  // 6: apigrps.MessageResult.code:type_name -> apigrps.SaveStatus
+	3,  // 7: apigrps.MessageResult.ref:type_name -> apigrps.MessageRef
+	6,  // 8: apigrps.BatchResponse.results:type_name -> apigrps.MessageResult
+	31, // 9: apigrps.BatchResponse.received:type_name -> google.protobuf.Timestamp
+	31, // 10: apigrps.QueryRequest.timeFrom:type_name -> google.protobuf.Timestamp
+	31, // 11: apigrps.QueryRequest.timeTo:type_name -> google.protobuf.Timestamp
+	1,  // 12: apigrps.QueryRequest.order:type_name -> apigrps.TimeOrder
+	28, // 13: apigrps.QueryRequest.attributes:type_name -> apigrps.QueryRequest.AttributesEntry
+	31, // 14: apigrps.StoredMessage.timestamp:type_name -> google.protobuf.Timestamp
+	31, // 15: apigrps.StoredMessage.eventTime:type_name -> google.protobuf.Timestamp
+	29, // 16: apigrps.StoredMessage.attributes:type_name -> apigrps.StoredMessage.AttributesEntry
+	9,  // 17: apigrps.QueryResponse.messages:type_name -> apigrps.StoredMessage
+	31, // 18: apigrps.SearchRequest.timeFrom:type_name -> google.protobuf.Timestamp
+	31, // 19: apigrps.SearchRequest.timeTo:type_name -> google.protobuf.Timestamp
+	30, // 20: apigrps.SearchRequest.attributes:type_name -> apigrps.SearchRequest.AttributesEntry
+	9,  // 21: apigrps.FoundMessage.message:type_name -> apigrps.StoredMessage
+	12, // 22: apigrps.SearchResponse.messages:type_name -> apigrps.FoundMessage
+	31, // 23: apigrps.Partition.timeOpen:type_name -> google.protobuf.Timestamp
+	31, // 24: apigrps.Partition.timeClose:type_name -> google.protobuf.Timestamp
+	31, // 25: apigrps.Partition.firstTime:type_name -> google.protobuf.Timestamp
+	31, // 26: apigrps.Partition.lastTime:type_name -> google.protobuf.Timestamp
+	15, // 27: apigrps.RetentionResponse.removed:type_name -> apigrps.Partition
+	15, // 28: apigrps.PartitionsResponse.partitions:type_name -> apigrps.Partition
+	31, // 29: apigrps.Token.timeCreate:type_name -> google.protobuf.Timestamp
+	31, // 30: apigrps.Token.timeExpire:type_name -> google.protobuf.Timestamp
+	31, // 31: apigrps.Token.timeRevoke:type_name -> google.protobuf.Timestamp
+	31, // 32: apigrps.CreateTokenRequest.timeExpire:type_name -> google.protobuf.Timestamp
+	20, // 33: apigrps.CreateTokenResponse.token:type_name -> apigrps.Token
+	20, // 34: apigrps.ListTokensResponse.tokens:type_name -> apigrps.Token
+	20, // 35: apigrps.RevokeTokenResponse.token:type_name -> apigrps.Token
+	2,  // 36: apigrps.iwe.SaveMessage:input_type -> apigrps.MessageRequest
+	5,  // 37: apigrps.iwe.SaveMessages:input_type -> apigrps.BatchRequest
+	2,  // 38: apigrps.iwe.StreamMessages:input_type -> apigrps.MessageRequest
+	8,  // 39: apigrps.iwe.QueryMessages:input_type -> apigrps.QueryRequest
+	14, // 40: apigrps.iwe.TailMessages:input_type -> apigrps.TailRequest
+	11, // 41: apigrps.iwe.SearchMessages:input_type -> apigrps.SearchRequest
+	16, // 42: apigrps.admin.ApplyRetention:input_type -> apigrps.RetentionRequest
+	18, // 43: apigrps.admin.ListPartitions:input_type -> apigrps.PartitionsRequest
+	21, // 44: apigrps.admin.CreateToken:input_type -> apigrps.CreateTokenRequest
+	23, // 45: apigrps.admin.ListTokens:input_type -> apigrps.ListTokensRequest
+	25, // 46: apigrps.admin.RevokeToken:input_type -> apigrps.RevokeTokenRequest
+	4,  // 47: apigrps.iwe.SaveMessage:output_type -> apigrps.MessageResponse
+	7,  // 48: apigrps.iwe.SaveMessages:output_type -> apigrps.BatchResponse
+	7,  // 49: apigrps.iwe.StreamMessages:output_type -> apigrps.BatchResponse
+	10, // 50: apigrps.iwe.QueryMessages:output_type -> apigrps.QueryResponse
+	9,  // 51: apigrps.iwe.TailMessages:output_type -> apigrps.StoredMessage
+	13, // 52: apigrps.iwe.SearchMessages:output_type -> apigrps.SearchResponse
+	17, // 53: apigrps.admin.ApplyRetention:output_type -> apigrps.RetentionResponse
+	19, // 54: apigrps.admin.ListPartitions:output_type -> apigrps.PartitionsResponse
+	22, // 55: apigrps.admin.CreateToken:output_type -> apigrps.CreateTokenResponse
+	24, // 56: apigrps.admin.ListTokens:output_type -> apigrps.ListTokensResponse
+	26, // 57: apigrps.admin.RevokeToken:output_type -> apigrps.RevokeTokenResponse
+	47, // [47:58] is the sub-list for method output_type
+	36, // [36:47] is the sub-list for method input_type
+	36, // [36:36] is the sub-list for extension type_name
+	36, // [36:36] is the sub-list for extension extendee
+	0,  // [0:36] is the sub-list for field type_name
 }
 
 func init() { file_file_proto_init() }
@@ -186,13 +2090,14 @@ func file_file_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_file_proto_rawDesc), len(file_file_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   29,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_file_proto_goTypes,
 		DependencyIndexes: file_file_proto_depIdxs,
+		EnumInfos:         file_file_proto_enumTypes,
 		MessageInfos:      file_file_proto_msgTypes,
 	}.Build()
 	File_file_proto = out.File
@@ -0,0 +1,189 @@
+package broker
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+)
+
+// Policy for a subscriber that does not read its buffer in time
+type Policy int
+
+const (
+	PolicyDrop       Policy = iota // new messages are dropped while the buffer is full
+	PolicyDisconnect               // the subscriber is disconnected when the buffer is full
+)
+
+// Filter of subscriber. Empty fields are not used.
+type FilterT struct {
+	TypeMessage string // I, W, E
+	NameProject string
+}
+
+// Message accepted by the server
+type EventT struct {
+	db.MessageT
+	Received time.Time
+}
+
+type Subscriber struct {
+	C       <-chan EventT // closed when the subscriber is disconnected
+	ch      chan EventT
+	filter  FilterT
+	dropped atomic.Uint64
+	slow    atomic.Bool
+}
+
+type Broker struct {
+	mu      sync.RWMutex
+	subs    map[*Subscriber]struct{}
+	sizeBuf int
+	policy  Policy
+	closed  bool
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Create the broker. Return pointer, error
+func New(sizeBuf int, policy Policy) (*Broker, error) {
+	if sizeBuf < 1 {
+		return nil, fmt.Errorf("size of buffer must be more 0, recieve: {%d}", sizeBuf)
+	}
+	if policy != PolicyDrop && policy != PolicyDisconnect {
+		return nil, fmt.Errorf("not supported policy: {%d}", policy)
+	}
+
+	return &Broker{
+		subs:    make(map[*Subscriber]struct{}),
+		sizeBuf: sizeBuf,
+		policy:  policy,
+	}, nil
+}
+
+// Parse the name of policy: drop, disconnect
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "drop":
+		return PolicyDrop, nil
+	case "disconnect":
+		return PolicyDisconnect, nil
+	default:
+		return 0, fmt.Errorf("not supported policy: {%s}", name)
+	}
+}
+
+// Subscribe to accepted messages
+func (b *Broker) Subscribe(filter FilterT) *Subscriber {
+
+	ch := make(chan EventT, b.sizeBuf)
+	s := &Subscriber{C: ch, ch: ch, filter: filter}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+
+	return s
+}
+
+// Unsubscribe and close the channel of subscriber. Safe to call several times
+func (b *Broker) Unsubscribe(s *Subscriber) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish the message to all subscribers. Never blocks
+func (b *Broker) Publish(msg db.MessageT) {
+
+	ev := EventT{MessageT: msg, Received: time.Now()}
+	var slow []*Subscriber
+
+	b.mu.RLock()
+	for s := range b.subs {
+		if !s.match(msg) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+			if b.policy == PolicyDisconnect {
+				slow = append(slow, s)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range slow {
+		s.slow.Store(true)
+		b.Unsubscribe(s)
+	}
+}
+
+// Close the broker: channels of all subscribers are closed, new subscribers get the closed channel.
+// Safe to call several times
+func (b *Broker) Close() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// The broker is closed: subscribers are disconnected by shutdown, not as slow
+func (b *Broker) Closed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.closed
+}
+
+// Number of subscribers
+func (b *Broker) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Number of messages dropped for the subscriber
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// The subscriber was disconnected by the broker as slow
+func (s *Subscriber) Slow() bool {
+	return s.slow.Load()
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Check the message by filter of subscriber
+func (s *Subscriber) match(msg db.MessageT) bool {
+	if s.filter.TypeMessage != "" && s.filter.TypeMessage != msg.TypeMessage {
+		return false
+	}
+	if s.filter.NameProject != "" && s.filter.NameProject != msg.NameProject {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package broker
+
+import (
+	"sync"
+	"testing"
+
+	db "github.com/Part001-R/netlogiwe/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Publish the message to all subscribers
+func Test_Publish_SUCCESS(t *testing.T) {
+
+	b, err := New(4, PolicyDrop)
+	require.NoError(t, err)
+
+	all := b.Subscribe(FilterT{})
+	onlyE := b.Subscribe(FilterT{TypeMessage: "E"})
+	onlyBeta := b.Subscribe(FilterT{NameProject: "beta"})
+
+	b.Publish(db.MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: "one"})
+	b.Publish(db.MessageT{TypeMessage: "E", NameProject: "beta", LocationEvent: "main.go:2", BodyMessage: "two"})
+
+	require.Len(t, all.C, 2)
+	require.Len(t, onlyE.C, 1)
+	require.Len(t, onlyBeta.C, 1)
+
+	ev := <-all.C
+	assert.Equal(t, "one", ev.BodyMessage)
+	assert.False(t, ev.Received.IsZero())
+	assert.Equal(t, "two", (<-onlyE.C).BodyMessage)
+	assert.Equal(t, "two", (<-onlyBeta.C).BodyMessage)
+}
+
+// Test - Slow subscriber with the drop policy
+func Test_Publish_Drop_SUCCESS(t *testing.T) {
+
+	b, err := New(2, PolicyDrop)
+	require.NoError(t, err)
+
+	s := b.Subscribe(FilterT{})
+	for i := 0; i < 5; i++ {
+		b.Publish(db.MessageT{TypeMessage: "I", BodyMessage: "msg"})
+	}
+
+	assert.Len(t, s.C, 2)
+	assert.Equal(t, uint64(3), s.Dropped())
+	assert.False(t, s.Slow())
+	assert.Equal(t, 1, b.Len())
+}
+
+// Test - Slow subscriber with the disconnect policy
+func Test_Publish_Disconnect_SUCCESS(t *testing.T) {
+
+	b, err := New(2, PolicyDisconnect)
+	require.NoError(t, err)
+
+	s := b.Subscribe(FilterT{})
+	for i := 0; i < 3; i++ {
+		b.Publish(db.MessageT{TypeMessage: "I", BodyMessage: "msg"})
+	}
+
+	assert.True(t, s.Slow())
+	assert.Equal(t, 0, b.Len())
+
+	// buffered messages are readable, then the channel is closed
+	<-s.C
+	<-s.C
+	_, ok := <-s.C
+	assert.False(t, ok)
+
+	// repeated unsubscribe is safe
+	b.Unsubscribe(s)
+}
+
+// Test - Publish and unsubscribe from several goroutines
+func Test_Publish_Concurrent_SUCCESS(t *testing.T) {
+
+	b, err := New(1, PolicyDisconnect)
+	require.NoError(t, err)
+
+	var wgPub, wgSub sync.WaitGroup
+	var subs []*Subscriber
+	for i := 0; i < 8; i++ {
+		s := b.Subscribe(FilterT{})
+		subs = append(subs, s)
+		wgPub.Add(1)
+		go func() {
+			defer wgPub.Done()
+			for j := 0; j < 100; j++ {
+				b.Publish(db.MessageT{TypeMessage: "W", BodyMessage: "msg"})
+			}
+		}()
+		wgSub.Add(1)
+		go func() {
+			defer wgSub.Done()
+			for range s.C {
+			}
+		}()
+	}
+	wgPub.Wait()
+	for _, s := range subs {
+		b.Unsubscribe(s)
+	}
+	wgSub.Wait()
+
+	assert.Equal(t, 0, b.Len())
+}
+
+// Test - Close the broker: subscribers are disconnected, not as slow
+func Test_Close_SUCCESS(t *testing.T) {
+
+	b, err := New(4, PolicyDrop)
+	require.NoError(t, err)
+
+	s := b.Subscribe(FilterT{})
+	b.Close()
+	b.Close()
+
+	_, ok := <-s.C
+	assert.False(t, ok, "channel of subscriber is closed")
+	assert.False(t, s.Slow())
+	assert.True(t, b.Closed())
+	assert.Equal(t, 0, b.Len())
+
+	late := b.Subscribe(FilterT{})
+	_, ok = <-late.C
+	assert.False(t, ok, "new subscriber gets the closed channel")
+	b.Publish(db.MessageT{TypeMessage: "I", BodyMessage: "after close"})
+	b.Unsubscribe(late)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Create the broker
+func Test_New_FAULT(t *testing.T) {
+
+	_, err := New(0, PolicyDrop)
+	require.Error(t, err)
+
+	_, err = New(1, Policy(5))
+	require.Error(t, err)
+
+	_, err = ParsePolicy("block")
+	require.Error(t, err)
+}
@@ -0,0 +1,465 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/Part001-R/netlogiwe/pkg/spool"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultTimeout    = 5 * time.Second
+	defaultRetries    = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+	sizeReplay        = 100 // messages of the spool in one SaveMessages call
+)
+
+// Config of the client. Zero fields take defaults
+type ConfigT struct {
+	Address    string            // host:port of the server
+	Project    string            // nameProject of all messages
+	PathCert   string            // certificate of the server (PATH_PUBLIC_KEY of the server). Empty - without TLS
+	ServerName string            // name in the certificate. Empty - host of Address
+	PathClient string            // certificate of the client for mTLS (signed by TLS_CLIENT_CA of the server). Empty - not presented
+	PathKey    string            // private key of the certificate of the client
+	Token      string            // secret of the token of the server (AUTH_TOKENS=true). Empty - not presented
+	Timeout    time.Duration     // of one attempt
+	Retries    int               // attempts after the first fault. Negative - no retries
+	Backoff    time.Duration     // pause before the first retry, doubled for next ones
+	MaxBackoff time.Duration     // limit of the pause
+	Options    []grpc.DialOption // extra options of the connection
+	Spool      spool.ConfigT     // disk queue of messages. Empty Dir - messages are sent directly
+	Attributes map[string]string // attributes of all messages: host, version...
+}
+
+// Client of the IWE server bound to the project. Safe for concurrent use, the connection is shared.
+// With the spool messages are appended to the disk queue and sent in order in the background,
+// so they survive outages of the server and restarts of the service (delivery is at least once).
+type Client struct {
+	cfg  ConfigT
+	conn *grpc.ClientConn
+	iwe  pb.IweClient
+
+	sp     *spool.Spool
+	wake   chan struct{} // new messages in the spool
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the sender of spool is stopped
+	lost   atomic.Uint64 // messages of spool not accepted by the server
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Create the client. The connection is established on the first message. Return pointer, error
+func New(cfg ConfigT) (*Client, error) {
+
+	if cfg.Address == "" {
+		return nil, errors.New("empty address of server")
+	}
+	if cfg.Project == "" {
+		return nil, errors.New("empty name of project")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = defaultRetries
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+
+	creds, err := transportCreds(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if cfg.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCreds{token: cfg.Token, secure: cfg.PathCert != ""}))
+	}
+	opts = append(opts, cfg.Options...)
+	conn, err := grpc.NewClient(cfg.Address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("fault create connection to {%s}: %v", cfg.Address, err)
+	}
+
+	c := &Client{cfg: cfg, conn: conn, iwe: pb.NewIweClient(conn)}
+
+	if cfg.Spool.Dir != "" {
+		c.sp, err = spool.Open(cfg.Spool)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("fault open spool: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		c.wake = make(chan struct{}, 1)
+		c.cancel = cancel
+		c.done = make(chan struct{})
+		go c.replay(ctx)
+	}
+
+	return c, nil
+}
+
+// Sending the information message
+func (c *Client) Info(location, body string) error {
+	return c.Send(context.Background(), "I", location, body)
+}
+
+// Sending the warning message
+func (c *Client) Warn(location, body string) error {
+	return c.Send(context.Background(), "W", location, body)
+}
+
+// Sending the error message
+func (c *Client) Error(location, body string) error {
+	return c.Send(context.Background(), "E", location, body)
+}
+
+// Check of the connection by the test message. It is not stored by the server
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Send(ctx, "T", "ping", "ping")
+}
+
+// Sending the message of the type: I, W, E, T. Temporary faults are retried with backoff
+// up to Retries or the end of ctx. The message has the random id, so the server does not save
+// it twice on retries. The event time is the time of calling, so it is kept by the spool and retries.
+// With the spool the message is only appended to it (except T)
+func (c *Client) Send(ctx context.Context, typeMessage, location, body string) error {
+	return c.SendAttributes(ctx, typeMessage, location, body, nil)
+}
+
+// Sending the message with attributes (request id, user id...). They are added to Attributes of the config
+func (c *Client) SendAttributes(ctx context.Context, typeMessage, location, body string, attrs map[string]string) error {
+
+	req := &pb.MessageRequest{
+		TypeMessage:   typeMessage,
+		NameProject:   c.cfg.Project,
+		LocationEvent: location,
+		BodyMessage:   body,
+		MessageId:     newMessageId(),
+		EventTime:     timestamppb.Now(),
+		Attributes:    c.attributes(attrs),
+	}
+
+	if c.sp != nil && typeMessage != "T" {
+		return c.spooling(req)
+	}
+
+	err := c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.iwe.SaveMessage(ctx, req)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fault send {%s} message: %w", typeMessage, err)
+	}
+
+	return nil
+}
+
+// Number of messages in the spool waiting for sending
+func (c *Client) Pending() int {
+	if c.sp == nil {
+		return 0
+	}
+	return c.sp.Len()
+}
+
+// Number of messages lost by the spool: overflow, corruption, not accepted by the server
+func (c *Client) Lost() uint64 {
+	if c.sp == nil {
+		return 0
+	}
+	return c.sp.Dropped() + c.lost.Load()
+}
+
+// Waiting until the spool is sent. Return error if ctx is done before
+func (c *Client) Flush(ctx context.Context) error {
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for c.Pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Close the connection. Not sent messages stay in the spool
+func (c *Client) Close() error {
+
+	if c.sp != nil {
+		c.cancel()
+		<-c.done
+		err := c.sp.Close()
+		if err != nil {
+			c.conn.Close()
+			return fmt.Errorf("fault close spool: %v", err)
+		}
+	}
+
+	return c.conn.Close()
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Attributes of the message: of the config and of the message (over the config). Empty - nil
+func (c *Client) attributes(attrs map[string]string) map[string]string {
+
+	if len(c.cfg.Attributes) == 0 && len(attrs) == 0 {
+		return nil
+	}
+
+	all := make(map[string]string, len(c.cfg.Attributes)+len(attrs))
+	maps.Copy(all, c.cfg.Attributes)
+	maps.Copy(all, attrs)
+
+	return all
+}
+
+// Execution of call with Timeout of each attempt. Temporary faults are retried with backoff
+// up to Retries or the end of ctx. Return the last error
+func (c *Client) retry(ctx context.Context, call func(ctx context.Context) error) error {
+
+	pause := c.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+
+		ctxAttempt, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+		err := call(ctxAttempt)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) || attempt >= c.cfg.Retries {
+			return err
+		}
+
+		wait := pause
+		if delay := retryDelay(err); delay > wait {
+			wait = min(delay, c.cfg.MaxBackoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		pause = min(2*pause, c.cfg.MaxBackoff)
+	}
+}
+
+// Appending the message to the spool and waking the sender
+func (c *Client) spooling(req *pb.MessageRequest) error {
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("fault marshal {%s} message: %v", req.GetTypeMessage(), err)
+	}
+
+	err = c.sp.Append(data)
+	if err != nil {
+		return fmt.Errorf("fault spool {%s} message: %w", req.GetTypeMessage(), err)
+	}
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Sender of the spool: messages are sent by batches in order. A batch is removed from the spool
+// after the server answered; on faults the batch is repeated with backoff
+func (c *Client) replay(ctx context.Context) {
+	defer close(c.done)
+
+	pause := c.cfg.Backoff
+	for {
+		n, err := c.replayBatch(ctx)
+
+		var wait <-chan time.Time
+		switch {
+		case err != nil:
+			wait = time.After(pause)
+			pause = min(2*pause, c.cfg.MaxBackoff)
+		case n == 0:
+			pause = c.cfg.Backoff
+		default:
+			pause = c.cfg.Backoff
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.wake:
+		case <-wait:
+		}
+	}
+}
+
+// Sending the batch from the spool. Return number of sent messages, error
+func (c *Client) replayBatch(ctx context.Context) (int, error) {
+
+	recs, err := c.sp.Peek(sizeReplay)
+	if err != nil || len(recs) == 0 {
+		return 0, err
+	}
+
+	batch := &pb.BatchRequest{Messages: make([]*pb.MessageRequest, 0, len(recs))}
+	for _, rec := range recs {
+		req := &pb.MessageRequest{}
+		if err := proto.Unmarshal(rec, req); err != nil {
+			c.lost.Add(1)
+			continue
+		}
+		batch.Messages = append(batch.Messages, req)
+	}
+
+	var resp *pb.BatchResponse
+	err = c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.iwe.SaveMessages(ctx, batch)
+		return err
+	})
+	if err != nil && !rejected(err) || ctx.Err() != nil {
+		// the batch stays in the spool: temporary faults, authentication, faults of the server
+		return 0, err
+	}
+	if err != nil {
+		// the batch is never accepted
+		c.lost.Add(uint64(len(batch.Messages)))
+	} else {
+		c.lost.Add(uint64(resp.GetFailed()))
+	}
+
+	return len(recs), c.sp.Ack(len(recs))
+}
+
+// Credentials of the connection: TLS by the certificate of server (with the certificate of the client - mTLS) or insecure
+func transportCreds(cfg ConfigT) (credentials.TransportCredentials, error) {
+
+	if cfg.PathCert == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	name := cfg.ServerName
+	if name == "" {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("fault parse address {%s}: %v", cfg.Address, err)
+		}
+		name = host
+	}
+
+	pemCert, err := os.ReadFile(cfg.PathCert)
+	if err != nil {
+		return nil, fmt.Errorf("fault read sertificate {%s}: %v", cfg.PathCert, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCert) {
+		return nil, fmt.Errorf("fault read sertificate {%s}: no certificates", cfg.PathCert)
+	}
+	tlsCfg := &tls.Config{RootCAs: pool, ServerName: name}
+
+	if cfg.PathClient != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.PathClient, cfg.PathKey)
+		if err != nil {
+			return nil, fmt.Errorf("fault read sertificate of the client {%s}: %v", cfg.PathClient, err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// Credentials of calls by the token: the header authorization: Bearer <token>
+type tokenCreds struct {
+	token  string
+	secure bool // the token is sent only over TLS
+}
+
+// Metadata of the call
+func (c tokenCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+// Require TLS when the connection is secure
+func (c tokenCreds) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// Random id of the message: UUID version 4
+func newMessageId() string {
+
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Check the fault is temporary
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Check the request is rejected by the server: it is not accepted on retries
+func rejected(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return true
+	default:
+		return false
+	}
+}
+
+// Time to retry set by the server (RetryInfo of the status). Zero - not set
+func retryDelay(err error) time.Duration {
+
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,484 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"github.com/Part001-R/netlogiwe/pkg/spool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Server of tests: the first fails calls return failCode, then messages are accepted
+type testServer struct {
+	pb.UnimplementedIweServer
+	mu       sync.Mutex
+	fails    int
+	failCode codes.Code
+	failWait time.Duration // RetryInfo of faults. Zero - not set
+	calls    int
+	received []*pb.MessageRequest
+}
+
+// Handler
+func (s *testServer) SaveMessage(ctx context.Context, req *pb.MessageRequest) (*pb.MessageResponse, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= s.fails {
+		st := status.New(s.failCode, "test fault")
+		if s.failWait > 0 {
+			st, _ = st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(s.failWait)})
+		}
+		return nil, st.Err()
+	}
+	s.received = append(s.received, req)
+
+	return &pb.MessageResponse{Status: "Ok"}, nil
+}
+
+// Handler
+func (s *testServer) SaveMessages(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= s.fails {
+		return nil, status.Error(s.failCode, "test fault")
+	}
+	s.received = append(s.received, req.GetMessages()...)
+
+	return &pb.BatchResponse{Saved: int32(len(req.GetMessages()))}, nil
+}
+
+// Messages received by the server
+func (s *testServer) messages() []*pb.MessageRequest {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*pb.MessageRequest{}, s.received...)
+}
+
+// Start up the in-process server. Return options of connection to it
+func startTestServer(t *testing.T, srv *testServer, opts ...grpc.ServerOption) []grpc.DialOption {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	pb.RegisterIweServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	})}
+}
+
+// Create the self-signed certificate for localhost. Return paths of certificate and key
+func createTestCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	pathCert := filepath.Join(dir, "cert.pem")
+	pathKey := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(pathCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(pathKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return pathCert, pathKey
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Sending messages of all types bound to the project
+func Test_Send_SUCCESS(t *testing.T) {
+
+	srv := &testServer{}
+	c, err := New(ConfigT{Address: "passthrough:///bufnet", Project: "alpha", Options: startTestServer(t, srv)})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Info("main.go:1", "one"))
+	require.NoError(t, c.Warn("main.go:2", "two"))
+	require.NoError(t, c.Error("main.go:3", "three"))
+	require.NoError(t, c.Ping(context.Background()))
+
+	require.Len(t, srv.received, 4)
+	for i, typeMessage := range []string{"I", "W", "E", "T"} {
+		assert.Equal(t, typeMessage, srv.received[i].GetTypeMessage())
+		assert.Equal(t, "alpha", srv.received[i].GetNameProject())
+	}
+	assert.Equal(t, "main.go:3", srv.received[2].GetLocationEvent())
+	assert.Equal(t, "three", srv.received[2].GetBodyMessage())
+}
+
+// Test - Attributes of the config and of the message
+func Test_SendAttributes_SUCCESS(t *testing.T) {
+
+	srv := &testServer{}
+	c, err := New(ConfigT{
+		Address:    "passthrough:///bufnet",
+		Project:    "alpha",
+		Attributes: map[string]string{"host": "web-1", "version": "1.0"},
+		Options:    startTestServer(t, srv),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Info("main.go:1", "one"))
+	require.NoError(t, c.SendAttributes(context.Background(), "E", "main.go:2", "two", map[string]string{"requestId": "r-1", "version": "1.1"}))
+
+	require.Len(t, srv.received, 2)
+	assert.Equal(t, map[string]string{"host": "web-1", "version": "1.0"}, srv.received[0].GetAttributes())
+	assert.Equal(t, map[string]string{"host": "web-1", "version": "1.1", "requestId": "r-1"}, srv.received[1].GetAttributes())
+}
+
+// Test - Retries of temporary faults
+func Test_Send_Retry_SUCCESS(t *testing.T) {
+
+	srv := &testServer{fails: 2, failCode: codes.Unavailable}
+	c, err := New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Backoff: time.Millisecond,
+		Options: startTestServer(t, srv),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Info("main.go:1", "one"))
+	assert.Equal(t, 3, srv.calls)
+	require.Len(t, srv.received, 1)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, srv.received[0].GetMessageId())
+}
+
+// Test - The pause before the retry is taken from RetryInfo of the server, limited by MaxBackoff
+func Test_Send_RetryInfo_SUCCESS(t *testing.T) {
+
+	srv := &testServer{fails: 1, failCode: codes.ResourceExhausted, failWait: 50 * time.Millisecond}
+	c, err := New(ConfigT{
+		Address:    "passthrough:///bufnet",
+		Project:    "alpha",
+		Backoff:    time.Millisecond,
+		MaxBackoff: time.Second,
+		Options:    startTestServer(t, srv),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	start := time.Now()
+	require.NoError(t, c.Info("main.go:1", "throttled"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, 2, srv.calls)
+}
+
+// Test - Sending over TLS with the certificate of the server
+func Test_Send_TLS_SUCCESS(t *testing.T) {
+
+	pathCert, pathKey := createTestCert(t)
+	cert, err := tls.LoadX509KeyPair(pathCert, pathKey)
+	require.NoError(t, err)
+
+	srv := &testServer{}
+	opts := startTestServer(t, srv, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+
+	c, err := New(ConfigT{
+		Address:    "passthrough:///bufnet",
+		Project:    "alpha",
+		PathCert:   pathCert,
+		ServerName: "localhost",
+		Options:    opts,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Warn("main.go:1", "over TLS"))
+	require.Len(t, srv.received, 1)
+}
+
+// Test - The token is sent in the authorization header of calls
+func Test_Send_Token_SUCCESS(t *testing.T) {
+
+	var header []string
+	srv := &testServer{}
+	opts := startTestServer(t, srv, grpc.UnaryInterceptor(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			header = md.Get("authorization")
+			return handler(ctx, req)
+		}))
+
+	c, err := New(ConfigT{Address: "passthrough:///bufnet", Project: "alpha", Token: "iwe_secret", Options: opts})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Info("main.go:1", "with token"))
+	assert.Equal(t, []string{"Bearer iwe_secret"}, header)
+}
+
+// Test - Messages of the spool are delivered in order after the outage of the server
+func Test_Send_Spool_SUCCESS(t *testing.T) {
+
+	srv := &testServer{fails: 3, failCode: codes.Unavailable}
+	c, err := New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Retries: -1,
+		Backoff: time.Millisecond,
+		Options: startTestServer(t, srv),
+		Spool:   spool.ConfigT{Dir: t.TempDir()},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	for _, body := range []string{"one", "two", "three"} {
+		require.NoError(t, c.Info("main.go:1", body))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.Flush(ctx))
+
+	got := []string{}
+	for _, msg := range srv.messages() {
+		got = append(got, msg.GetBodyMessage())
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, got)
+	assert.Equal(t, uint64(0), c.Lost())
+}
+
+// Test - The batch of the spool is removed only if it is rejected, on other faults it is sent again
+func Test_Send_SpoolFaults_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		fails    int
+		failCode codes.Code
+		wantLost uint64
+		wantSent int
+	}{
+		{nameTest: "internal", fails: 2, failCode: codes.Internal, wantSent: 3},
+		{nameTest: "unauthenticated", fails: 2, failCode: codes.Unauthenticated, wantSent: 3},
+		{nameTest: "permission denied", fails: 2, failCode: codes.PermissionDenied, wantSent: 3},
+		{nameTest: "rejected", fails: 100, failCode: codes.InvalidArgument, wantLost: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			srv := &testServer{fails: tt.fails, failCode: tt.failCode}
+			c, err := New(ConfigT{
+				Address: "passthrough:///bufnet",
+				Project: "alpha",
+				Retries: -1,
+				Backoff: time.Millisecond,
+				Options: startTestServer(t, srv),
+				Spool:   spool.ConfigT{Dir: t.TempDir()},
+			})
+			require.NoError(t, err)
+			defer c.Close()
+
+			for _, body := range []string{"one", "two", "three"} {
+				require.NoError(t, c.Info("main.go:1", body))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, c.Flush(ctx))
+
+			assert.Len(t, srv.messages(), tt.wantSent)
+			assert.Equal(t, tt.wantLost, c.Lost())
+		})
+	}
+}
+
+// Test - Messages of the spool survive the restart of the client
+func Test_Send_SpoolReopen_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// server is not available: messages stay in the spool
+	down := &testServer{fails: 1000, failCode: codes.Unavailable}
+	c, err := New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Retries: -1,
+		Backoff: time.Hour,
+		Options: startTestServer(t, down),
+		Spool:   spool.ConfigT{Dir: dir},
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Warn("main.go:1", "one"))
+	require.NoError(t, c.Error("main.go:2", "two"))
+	require.NoError(t, c.Close())
+
+	srv := &testServer{}
+	c, err = New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Options: startTestServer(t, srv),
+		Spool:   spool.ConfigT{Dir: dir},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.Flush(ctx))
+
+	msgs := srv.messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "W", msgs[0].GetTypeMessage())
+	assert.Equal(t, "two", msgs[1].GetBodyMessage())
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Sending. Not temporary fault is not retried, retries are limited
+func Test_Send_FAULT(t *testing.T) {
+
+	tests := []struct {
+		nameTest  string
+		srv       *testServer
+		retries   int
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{
+			nameTest:  "Not temporary",
+			srv:       &testServer{fails: 5, failCode: codes.InvalidArgument},
+			retries:   3,
+			wantCalls: 1,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			nameTest:  "Retries are over",
+			srv:       &testServer{fails: 5, failCode: codes.Unavailable},
+			retries:   2,
+			wantCalls: 3,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			nameTest:  "No retries",
+			srv:       &testServer{fails: 5, failCode: codes.Unavailable},
+			retries:   -1,
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			c, err := New(ConfigT{
+				Address: "passthrough:///bufnet",
+				Project: "alpha",
+				Retries: tt.retries,
+				Backoff: time.Millisecond,
+				Options: startTestServer(t, tt.srv),
+			})
+			require.NoError(t, err)
+			defer c.Close()
+
+			err = c.Error("main.go:1", "msg")
+			require.Error(t, err)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCalls, tt.srv.calls)
+		})
+	}
+}
+
+// Test - Sending. The context is cancelled during backoff
+func Test_Send_Cancel_FAULT(t *testing.T) {
+
+	srv := &testServer{fails: 100, failCode: codes.Unavailable}
+	c, err := New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Retries: 100,
+		Backoff: time.Hour,
+		Options: startTestServer(t, srv),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = c.Send(ctx, "I", "main.go:1", "msg")
+	require.Error(t, err)
+	assert.Equal(t, 1, srv.calls)
+}
+
+// Test - Create the client. Not allowed config
+func Test_New_FAULT(t *testing.T) {
+
+	pathCert, _ := createTestCert(t)
+
+	tests := []struct {
+		nameTest string
+		cfg      ConfigT
+	}{
+		{nameTest: "Address", cfg: ConfigT{Project: "alpha"}},
+		{nameTest: "Project", cfg: ConfigT{Address: "localhost:50200"}},
+		{nameTest: "Certificate", cfg: ConfigT{Address: "localhost:50200", Project: "alpha", PathCert: "/not/exists.pem"}},
+		{nameTest: "Address without port", cfg: ConfigT{Address: "localhost", Project: "alpha", PathCert: "cert.pem"}},
+		{nameTest: "Certificate of the client", cfg: ConfigT{Address: "localhost:50200", Project: "alpha", PathCert: pathCert, PathClient: "/not/exists.pem", PathKey: "/not/exists.key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			require.Error(t, err)
+		})
+	}
+}
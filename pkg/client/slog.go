@@ -0,0 +1,348 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/Part001-R/netlogiwe/pkg/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultSizeQueue = 4096
+	defaultSizeBatch = 100
+	defaultInterval  = time.Second
+
+	// limits of attributes of the message by the server
+	maxAttributes         = 32
+	maxSizeAttributeKey   = 64
+	maxSizeAttributeValue = 1024
+)
+
+// Options of the slog handler. Zero fields take defaults
+type HandlerOptionsT struct {
+	Level     slog.Leveler  // minimal level of records. nil - Info
+	SizeQueue int           // records waiting for sending. New records are dropped when the queue is full
+	SizeBatch int           // records in one SaveMessages call
+	Interval  time.Duration // sending of the not full batch
+}
+
+// Handler of log/slog sending records to the server. Levels: below Warn - I, below Error - W, others - E.
+// Source of the record is locationEvent, the message is bodyMessage, attributes (keys of groups are joined by dots)
+// are attributes of the message. Attributes over limits of the server are added to bodyMessage as key=value.
+// Records are queued and sent by batches in the background, Handle never waits for the network.
+type Handler struct {
+	core   *handlerCore
+	level  slog.Leveler
+	prefix string      // group of next attributes: "g1.g2."
+	attrs  []keyValueT // attributes of WithAttrs
+}
+
+// Attribute of the record with the key of groups
+type keyValueT struct {
+	key   string
+	value string
+}
+
+// Queue and sender shared by the handler and its derived handlers
+type handlerCore struct {
+	c         *Client
+	sizeBatch int
+	interval  time.Duration
+
+	mu     sync.RWMutex // closed and sending to the queue
+	closed bool
+	queue  chan *pb.MessageRequest
+	done   chan struct{} // closed when the sender is stopped
+
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Create the slog handler over the client. The sender is stopped by Close
+func NewHandler(c *Client, opts HandlerOptionsT) *Handler {
+
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+	if opts.SizeQueue <= 0 {
+		opts.SizeQueue = defaultSizeQueue
+	}
+	if opts.SizeBatch <= 0 {
+		opts.SizeBatch = defaultSizeBatch
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+
+	core := &handlerCore{
+		c:         c,
+		sizeBatch: opts.SizeBatch,
+		interval:  opts.Interval,
+		queue:     make(chan *pb.MessageRequest, opts.SizeQueue),
+		done:      make(chan struct{}),
+	}
+	go core.run()
+
+	return &Handler{core: core, level: opts.Level}
+}
+
+// Check the level is handled
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Queue the record for sending. Dropped if the queue is full or the handler is closed
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+
+	kvs := slices.Clone(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, h.prefix, a)
+		return true
+	})
+	attrs := h.core.c.attributes(nil)
+	var body strings.Builder
+	body.WriteString(r.Message)
+	for _, kv := range kvs {
+		if !fitAttribute(attrs, kv) {
+			body.WriteByte(' ')
+			body.WriteString(kv.key)
+			body.WriteByte('=')
+			body.WriteString(quoteValue(kv.value))
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[kv.key] = kv.value
+	}
+
+	req := &pb.MessageRequest{
+		TypeMessage:   typeByLevel(r.Level),
+		NameProject:   h.core.c.cfg.Project,
+		LocationEvent: locationByPC(r.PC),
+		BodyMessage:   body.String(),
+		MessageId:     newMessageId(),
+		Attributes:    attrs,
+	}
+	// the time of the record, not of sending the batch
+	if !r.Time.IsZero() {
+		req.EventTime = timestamppb.New(r.Time)
+	}
+	h.core.push(req)
+
+	return nil
+}
+
+// Handler with the attributes in all records
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+
+	kvs := slices.Clip(h.attrs)
+	for _, a := range attrs {
+		kvs = appendAttr(kvs, h.prefix, a)
+	}
+
+	h2 := *h
+	h2.attrs = kvs
+	return &h2
+}
+
+// Handler with the group of next attributes
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Number of records dropped by the full queue or after Close
+func (h *Handler) Dropped() uint64 {
+	return h.core.dropped.Load()
+}
+
+// Number of records not accepted by the server
+func (h *Handler) Failed() uint64 {
+	return h.core.failed.Load()
+}
+
+// Stop the handler: queued records are sent. Return error if ctx is done before
+func (h *Handler) Close(ctx context.Context) error {
+
+	h.core.mu.Lock()
+	if !h.core.closed {
+		h.core.closed = true
+		close(h.core.queue)
+	}
+	h.core.mu.Unlock()
+
+	select {
+	case <-h.core.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Queue the message without waiting
+func (hc *handlerCore) push(req *pb.MessageRequest) {
+
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if hc.closed {
+		hc.dropped.Add(1)
+		return
+	}
+	select {
+	case hc.queue <- req:
+	default:
+		hc.dropped.Add(1)
+	}
+}
+
+// Sender: the batch is sent when full, by interval and on close
+func (hc *handlerCore) run() {
+	defer close(hc.done)
+
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+
+	batch := make([]*pb.MessageRequest, 0, hc.sizeBatch)
+	for {
+		select {
+		case req, ok := <-hc.queue:
+			if !ok {
+				hc.send(batch)
+				return
+			}
+			batch = append(batch, req)
+			if len(batch) >= hc.sizeBatch {
+				hc.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			hc.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// Sending the batch with retries or appending it to the spool of client
+func (hc *handlerCore) send(batch []*pb.MessageRequest) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if hc.c.sp != nil {
+		for _, req := range batch {
+			if hc.c.spooling(req) != nil {
+				hc.failed.Add(1)
+			}
+		}
+		return
+	}
+
+	var resp *pb.BatchResponse
+	err := hc.c.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		resp, err = hc.c.iwe.SaveMessages(ctx, &pb.BatchRequest{Messages: batch})
+		return err
+	})
+	if err != nil {
+		hc.failed.Add(uint64(len(batch)))
+		return
+	}
+	hc.failed.Add(uint64(resp.GetFailed()))
+}
+
+// Type of message by the level
+func typeByLevel(level slog.Level) string {
+	switch {
+	case level < slog.LevelWarn:
+		return "I"
+	case level < slog.LevelError:
+		return "W"
+	default:
+		return "E"
+	}
+}
+
+// Location of the record: file:line function
+func locationByPC(pc uintptr) string {
+	if pc == 0 {
+		return "unknown"
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return "unknown"
+	}
+
+	return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line) + " " + frame.Function
+}
+
+// Appending the attribute as key and value. Keys of groups are joined by dots
+func appendAttr(kvs []keyValueT, prefix string, a slog.Attr) []keyValueT {
+
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+		return kvs
+	}
+
+	return append(kvs, keyValueT{key: prefix + a.Key, value: a.Value.String()})
+}
+
+// Check the attribute can be added to attributes of the message by limits of the server
+func fitAttribute(attrs map[string]string, kv keyValueT) bool {
+
+	if kv.key == "" || len(kv.key) > maxSizeAttributeKey || len(kv.value) > maxSizeAttributeValue {
+		return false
+	}
+	for _, r := range kv.key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-') {
+			return false
+		}
+	}
+	if _, ok := attrs[kv.key]; !ok && len(attrs) >= maxAttributes {
+		return false
+	}
+
+	return true
+}
+
+// Quote the value with spaces, quotes or '='
+func quoteValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
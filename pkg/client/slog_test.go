@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Records are sent by batches with types, location and attributes
+func Test_Handler_SUCCESS(t *testing.T) {
+
+	srv := &testServer{}
+	c, err := New(ConfigT{Address: "passthrough:///bufnet", Project: "alpha", Options: startTestServer(t, srv)})
+	require.NoError(t, err)
+	defer c.Close()
+
+	h := NewHandler(c, HandlerOptionsT{Level: slog.LevelDebug, SizeBatch: 2, Interval: time.Hour})
+	logger := slog.New(h).With("host", "web-1").WithGroup("req")
+
+	logger.Debug("debug")
+	logger.Info("started", "port", 50200)
+	logger.Warn("slow query", slog.Group("db", "table", "logI_1", "ms", 1500))
+	logger.Error("fault", "err", "connection refused")
+
+	require.NoError(t, h.Close(context.Background()))
+
+	msgs := srv.messages()
+	require.Len(t, msgs, 4)
+	assert.Equal(t, 2, srv.calls, "2 batches")
+
+	assert.Equal(t, "I", msgs[0].GetTypeMessage())
+	assert.Equal(t, "debug", msgs[0].GetBodyMessage())
+	assert.Equal(t, map[string]string{"host": "web-1"}, msgs[0].GetAttributes())
+	assert.Equal(t, "I", msgs[1].GetTypeMessage())
+	assert.Equal(t, "started", msgs[1].GetBodyMessage())
+	assert.Equal(t, map[string]string{"host": "web-1", "req.port": "50200"}, msgs[1].GetAttributes())
+	assert.Equal(t, "W", msgs[2].GetTypeMessage())
+	assert.Equal(t, "slow query", msgs[2].GetBodyMessage())
+	assert.Equal(t, map[string]string{"host": "web-1", "req.db.table": "logI_1", "req.db.ms": "1500"}, msgs[2].GetAttributes())
+	assert.Equal(t, "E", msgs[3].GetTypeMessage())
+	assert.Equal(t, "fault", msgs[3].GetBodyMessage())
+	assert.Equal(t, map[string]string{"host": "web-1", "req.err": "connection refused"}, msgs[3].GetAttributes())
+
+	for _, msg := range msgs {
+		assert.Equal(t, "alpha", msg.GetNameProject())
+		assert.Contains(t, msg.GetLocationEvent(), "slog_test.go:")
+		assert.Contains(t, msg.GetLocationEvent(), "Test_Handler_SUCCESS")
+	}
+	assert.Zero(t, h.Dropped())
+	assert.Zero(t, h.Failed())
+}
+
+// Test - Attributes of the client and of records are joined, attributes over limits of the server are in bodyMessage
+func Test_Handler_Attributes_SUCCESS(t *testing.T) {
+
+	srv := &testServer{}
+	c, err := New(ConfigT{Address: "passthrough:///bufnet", Project: "alpha", Attributes: map[string]string{"service": "api"},
+		Options: startTestServer(t, srv)})
+	require.NoError(t, err)
+	defer c.Close()
+
+	h := NewHandler(c, HandlerOptionsT{Interval: time.Hour})
+	args := []any{"user id", 7, "long", strings.Repeat("x", maxSizeAttributeValue+1)}
+	for i := range maxAttributes {
+		args = append(args, fmt.Sprintf("k%02d", i), i)
+	}
+	slog.New(h).Info("many", args...)
+	require.NoError(t, h.Close(context.Background()))
+
+	msgs := srv.messages()
+	require.Len(t, msgs, 1)
+	attrs := msgs[0].GetAttributes()
+	assert.Len(t, attrs, maxAttributes)
+	assert.Equal(t, "api", attrs["service"])
+	assert.Equal(t, "30", attrs["k30"])
+	assert.NotContains(t, attrs, "k31")
+	assert.Equal(t, `many user id=7 long=`+strings.Repeat("x", maxSizeAttributeValue+1)+` k31=31`, msgs[0].GetBodyMessage())
+}
+
+// Test - Not full batch is sent by interval, level filter
+func Test_Handler_Interval_SUCCESS(t *testing.T) {
+
+	srv := &testServer{}
+	c, err := New(ConfigT{Address: "passthrough:///bufnet", Project: "alpha", Options: startTestServer(t, srv)})
+	require.NoError(t, err)
+	defer c.Close()
+
+	h := NewHandler(c, HandlerOptionsT{Level: slog.LevelWarn, Interval: 10 * time.Millisecond})
+	defer h.Close(context.Background())
+	logger := slog.New(h)
+
+	logger.Info("not sent")
+	logger.Warn("sent")
+
+	require.Eventually(t, func() bool { return len(srv.messages()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "sent", srv.messages()[0].GetBodyMessage())
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - The server is not available: Handle is not blocked, records are counted
+func Test_Handler_FAULT(t *testing.T) {
+
+	srv := &testServer{fails: 100, failCode: codes.Unavailable}
+	c, err := New(ConfigT{
+		Address: "passthrough:///bufnet",
+		Project: "alpha",
+		Retries: 1,
+		Backoff: time.Millisecond,
+		Options: startTestServer(t, srv),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	h := NewHandler(c, HandlerOptionsT{SizeQueue: 2, SizeBatch: 10, Interval: time.Hour})
+	logger := slog.New(h)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		logger.Info("msg")
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, uint64(3), h.Dropped())
+
+	require.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, uint64(2), h.Failed())
+
+	// records after close are dropped
+	logger.Info("msg")
+	assert.Equal(t, uint64(4), h.Dropped())
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	maxAttributes         = 32
+	maxSizeAttributeKey   = 64
+	maxSizeAttributeValue = 1024
+)
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Check attributes of the message or filter: number, keys of [A-Za-z0-9_.-], length of values
+func checkAttributes(attrs map[string]string) error {
+
+	if len(attrs) > maxAttributes {
+		return &FieldError{Field: "attributes", Description: fmt.Sprintf("too many attributes: {%d}, allowed {%d}", len(attrs), maxAttributes)}
+	}
+	for key, value := range attrs {
+		if key == "" || len(key) > maxSizeAttributeKey {
+			return &FieldError{Field: "attributes", Description: fmt.Sprintf("not allowed length of the key {%s}: allowed 1..{%d} bytes", key, maxSizeAttributeKey)}
+		}
+		for _, r := range key {
+			if !isAttributeKeyRune(r) {
+				return &FieldError{Field: "attributes", Description: fmt.Sprintf("not allowed key {%s}: allowed A-Z, a-z, 0-9, _, ., -", key)}
+			}
+		}
+		if len(value) > maxSizeAttributeValue {
+			return &FieldError{Field: "attributes", Description: fmt.Sprintf("long value of {%s}: {%d} bytes, allowed {%d}", key, len(value), maxSizeAttributeValue)}
+		}
+	}
+
+	return nil
+}
+
+// Check the rune of the key of attribute. Keys are used in JSON paths unescaped
+func isAttributeKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-'
+}
+
+// Attributes as JSON object for the column. Empty - NULL
+func attributesJSON(attrs map[string]string) (any, error) {
+
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("fault encode attributes: {%v}", err)
+	}
+
+	return string(b), nil
+}
+
+// Attributes from the JSON column. NULL - nil
+func parseAttributes(v sql.NullString) (map[string]string, error) {
+
+	if !v.Valid || v.String == "" {
+		return nil, nil
+	}
+
+	var attrs map[string]string
+	err := json.Unmarshal([]byte(v.String), &attrs)
+	if err != nil {
+		return nil, fmt.Errorf("fault decode attributes {%s}: {%v}", v.String, err)
+	}
+
+	return attrs, nil
+}
+
+// Keys of attributes in sorted order, so conditions and arguments of the query match
+func attributeKeys(attrs map[string]string) []string {
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Check the message has all attributes of the filter
+func matchAttributes(attrs, filter map[string]string) bool {
+	for key, value := range filter {
+		if v, ok := attrs[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Attributes are stored and matched by the filter. SQLite and memory
+func Test_ReadingMessages_Attributes_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 10, 10)
+
+	tests := []struct {
+		nameTest string
+		repo     func(t *testing.T) ActionsDB
+	}{
+		{
+			nameTest: "SQLite",
+			repo: func(t *testing.T) ActionsDB {
+				instAct, err := RepoDB(openTestDB(t), cfg)
+				require.NoError(t, err)
+				return instAct
+			},
+		},
+		{
+			nameTest: "Memory",
+			repo: func(t *testing.T) ActionsDB {
+				return RepoMem(cfg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct := tt.repo(t)
+			require.NoError(t, instAct.Tables())
+
+			_, results, err := instAct.SavingMessages([]MessageT{
+				{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "one", Attributes: map[string]string{"requestId": "r-1", "host": "web-1"}},
+				{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "two", Attributes: map[string]string{"requestId": "r-2", "host": "web-1"}},
+				{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "three", Attributes: map[string]string{"requestId": "r-1", "host": "web-2"}},
+				{TypeMessage: "W", NameProject: "p", LocationEvent: "l", BodyMessage: "four"},
+			})
+			require.NoError(t, err)
+			for _, err := range results {
+				require.NoError(t, err)
+			}
+
+			msgs, err := instAct.ReadingMessages(FilterT{Attributes: map[string]string{"requestId": "r-1"}})
+			require.NoError(t, err)
+			require.Len(t, msgs, 2)
+			assert.Equal(t, map[string]string{"requestId": "r-1", "host": "web-1"}, msgs[0].Attributes)
+			assert.Equal(t, "three", msgs[1].BodyMessage)
+
+			msgs, err = instAct.ReadingMessages(FilterT{Attributes: map[string]string{"requestId": "r-1", "host": "web-2"}})
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+			assert.Equal(t, "three", msgs[0].BodyMessage)
+
+			msgs, err = instAct.ReadingMessages(FilterT{Attributes: map[string]string{"version": "1.0"}})
+			require.NoError(t, err)
+			assert.Len(t, msgs, 0)
+
+			msgs, err = instAct.ReadingMessages(FilterT{TypeMessage: "W"})
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+			assert.Nil(t, msgs[0].Attributes)
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Not allowed attributes of the message and the filter
+func Test_checkAttributes_FAULT(t *testing.T) {
+
+	many := make(map[string]string)
+	for i := range maxAttributes + 1 {
+		many[fmt.Sprintf("key%d", i)] = "v"
+	}
+
+	tests := []struct {
+		nameTest string
+		attrs    map[string]string
+	}{
+		{nameTest: "Number", attrs: many},
+		{nameTest: "Empty key", attrs: map[string]string{"": "v"}},
+		{nameTest: "Long key", attrs: map[string]string{strings.Repeat("k", maxSizeAttributeKey+1): "v"}},
+		{nameTest: "Quote in key", attrs: map[string]string{`a"b`: "v"}},
+		{nameTest: "Long value", attrs: map[string]string{"key": strings.Repeat("v", maxSizeAttributeValue+1)}},
+	}
+
+	instAct := RepoMem(testConfig(10, 10, 10))
+	require.NoError(t, instAct.Tables())
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			err := checkAttributes(tt.attrs)
+			var errField *FieldError
+			require.ErrorAs(t, err, &errField)
+			assert.Equal(t, "attributes", errField.Field)
+
+			_, err = instAct.ReadingMessages(FilterT{Attributes: tt.attrs})
+			require.ErrorIs(t, err, ErrInvalidArgument)
+		})
+	}
+}
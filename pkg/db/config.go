@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default window of deduplication
+const DefaultDedupWindow = 24 * time.Hour
+
+// Settings of the storage
+type ConfigT struct {
+	MaxIdNumbI  int64         // messages of the log table of info before rotation
+	MaxIdNumbW  int64         // messages of the log table of warnings before rotation
+	MaxIdNumbE  int64         // messages of the log table of errors before rotation
+	DedupWindow time.Duration // repeated messageId of the project is not saved within the window. 0 - off
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Check the settings. Return error
+func (c ConfigT) Check() error {
+
+	for _, typeTable := range []string{"I", "W", "E"} {
+		if c.MaxIdNumb(typeTable) <= 0 {
+			return fmt.Errorf("not allowed max of {%s} log table: {%d}, want > 0", typeTable, c.MaxIdNumb(typeTable))
+		}
+	}
+	if c.DedupWindow < 0 {
+		return fmt.Errorf("not allowed window of deduplication: {%s}", c.DedupWindow)
+	}
+
+	return nil
+}
+
+// Messages of the log table of the type before rotation. 0 - unknown type
+func (c ConfigT) MaxIdNumb(typeTable string) int64 {
+
+	switch typeTable {
+	case "I":
+		return c.MaxIdNumbI
+	case "W":
+		return c.MaxIdNumbW
+	case "E":
+		return c.MaxIdNumbE
+	default:
+		return 0
+	}
+}
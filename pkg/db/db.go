@@ -1,14 +1,17 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
 	_ "modernc.org/sqlite"
 )
 
@@ -17,15 +20,48 @@ type MessageT struct {
 	NameProject   string
 	LocationEvent string
 	BodyMessage   string
+	MessageId     string            // optional id of the client for deduplication
+	EventTime     time.Time         // time of the event by the client. Zero - the time of receiving
+	Skewed        bool              // EventTime is out of bounds of the server clock
+	Attributes    map[string]string // context of the message: request id, host, version... Filterable by queries
+	TokenId       string            // id of the token of the client that saved the message, for audit. Empty - without token
+}
+
+// Reference to the stored message: log table and id in it. Names of log tables are not reused
+type RefT struct {
+	NameTable string
+	Id        int64
+}
+
+// Executor of queries: *sql.DB or *sql.Tx
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 type ObjectDB struct {
-	DB *sql.DB
+	DB      *sql.DB
+	muWrite *sync.Mutex // serialises writing: saving + rotation of log tables
+	cfg     ConfigT
 }
 
 type ActionsDB interface {
 	Tables() error
-	SavingMessage(msg MessageT) error
+	SavingMessage(msg MessageT) (RefT, error)
+	SavingMessages(msgs []MessageT) ([]RefT, []error, error)
+	ReadingMessages(filter FilterT) ([]StoredMessageT, error)
+	SearchMessages(search SearchT) ([]FoundMessageT, error)
+	Partitions() ([]PartitionT, error)
+	ApplyRetention(policy RetentionPolicyT, dryRun bool) ([]PartitionT, error)
+	CreateToken(token TokenT) (TokenT, string, error)
+	ListTokens() ([]TokenT, error)
+	RevokeToken(id string) (TokenT, error)
+	TokenBySecret(secret string) (TokenT, error)
+}
+
+func init() {
+	Register("sqlite", factorySQLite)
 }
 
 // =======================
@@ -56,28 +92,35 @@ func ConDb(typeDB, nameDB string) (*sql.DB, func() error, error) {
 	return db, closeDB, nil
 }
 
-// Create the db object. Return interface.
-func RepoDB(db *sql.DB) (ActionsDB, error) {
+// Create the db object with the settings. Return interface.
+func RepoDB(db *sql.DB, cfg ConfigT) (ActionsDB, error) {
 	if db == nil {
 		return nil, errors.New("empty pinter db")
 	}
-	return &ObjectDB{DB: db}, nil
+	return &ObjectDB{DB: db, muWrite: &sync.Mutex{}, cfg: cfg}, nil
 }
 
 // Working with database tables
 func (o ObjectDB) Tables() error {
 
-	err := checkCreateMainTable(o.DB)
+	// The schema and log tables of previous versions are migrated
+	applied, err := o.Migrate(false)
 	if err != nil {
 		log.Fatal(err)
 	}
+	for _, m := range applied {
+		if len(m.Tables) != 0 {
+			log.Printf("migration {%d} {%s} is applied to log tables: %v", m.Version, m.Name, m.Tables)
+		}
+	}
 
 	nI, nW, nE, err := readLogTablesName(o.DB)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		nI = "logI_1"
-		nW = "logW_1"
-		nE = "logE_1"
-		_, err := initMainTable(o.DB, nI, nW, nE)
+		err := initPartitions(o.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		nI, nW, nE, err = readLogTablesName(o.DB)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -85,90 +128,284 @@ func (o ObjectDB) Tables() error {
 		log.Fatal(err)
 	}
 
-	err = checkCreateLogTable(o.DB, nI)
+	// The current log tables with the schema of the last version
+	for _, name := range []string{nI, nW, nE} {
+		err = checkCreateLogTable(o.DB, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = checkCreateFtsTable(o.DB, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return nil
+}
+
+// Saving the received message in the database. Return reference, error (ErrDuplicate if the id of message is repeated)
+func (o ObjectDB) SavingMessage(msg MessageT) (RefT, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	var (
+		ref     RefT
+		rotated bool
+	)
+	err := o.inWriteTx(func(tx *sql.Tx) error {
+		if msg.MessageId != "" {
+			err := purgeMessageIds(tx, window, now)
+			if err != nil {
+				return err
+			}
+		}
+		return savingUnique(tx, window, now, msg, func(db queryer) error {
+			var err error
+			ref, rotated, err = savingByType(db, o.cfg, msg)
+			return err
+		})
+	})
 	if err != nil {
-		log.Fatal(err)
+		return RefT{}, err
 	}
+	countRotations([]MessageT{msg}, []bool{rotated}, nil)
+
+	return ref, nil
+}
 
-	err = checkCreateLogTable(o.DB, nW)
+// Saving the batch of messages in one transaction. Return reference and error of each message (nil - saved, ErrDuplicate),
+// error of transaction
+func (o ObjectDB) SavingMessages(msgs []MessageT) ([]RefT, []error, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	refs := make([]RefT, len(msgs))
+	rotated := make([]bool, len(msgs))
+	results := make([]error, len(msgs))
+	err := o.inWriteTx(func(tx *sql.Tx) error {
+		if hasMessageIds(msgs) {
+			err := purgeMessageIds(tx, window, now)
+			if err != nil {
+				return err
+			}
+		}
+		for i, msg := range msgs {
+			results[i] = storageFault(savingInSavepoint(tx, func(db queryer) error {
+				return savingUnique(db, window, now, msg, func(db queryer) error {
+					var err error
+					refs[i], rotated[i], err = savingByType(db, o.cfg, msg)
+					return err
+				})
+			}))
+		}
+		return nil
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
+	countRotations(msgs, rotated, results)
+
+	return refs, results, nil
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Factory of the SQLite storage. Close waits for the current writer and checkpoints the WAL journal
+func factorySQLite(cfg DriverConfigT) (ActionsDB, func() error, error) {
 
-	err = checkCreateLogTable(o.DB, nE)
+	cfg.Name = sqliteDSN(cfg.Name)
+	obj, closeDB, err := sqlFactory(RepoDB)(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
+	o := obj.(*ObjectDB)
 
-	return nil
+	return obj, func() error { return o.close(closeDB) }, nil
 }
 
-// Saving the received message in the database. Return error
-func (o ObjectDB) SavingMessage(msg MessageT) error {
+// Close the database after the current writer. The WAL journal is moved into the database file,
+// so the file is complete without the journal. Without WAL the checkpoint does nothing
+func (o ObjectDB) close(closeDB func() error) error {
 
-	nameI, nameW, nameE, err := readLogTablesName(o.DB)
+	o.muWrite.Lock()
+	defer o.muWrite.Unlock()
+
+	_, err := o.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
 	if err != nil {
-		return fmt.Errorf("fault read name of tables: {%v}", err)
+		closeDB()
+		return fmt.Errorf("fault checkpoint of WAL: %v", err)
 	}
 
-	maxI := os.Getenv("MAX_IDNUMB_LOGI")
-	maxW := os.Getenv("MAX_IDNUMB_LOGW")
-	maxE := os.Getenv("MAX_IDNUMB_LOGE")
+	return closeDB()
+}
 
-	// Saving the message
-	switch msg.TypeMessage {
-	case "I":
-		err := savingMessageCheckResult(o.DB, nameI, maxI, maxW, maxE, msg)
-		if err != nil {
-			return fmt.Errorf("fault save I: {%v}", err)
-		}
-	case "W":
-		err := savingMessageCheckResult(o.DB, nameW, maxI, maxW, maxE, msg)
-		if err != nil {
-			return fmt.Errorf("fault save W: {%v}", err)
-		}
-	case "E":
-		err := savingMessageCheckResult(o.DB, nameE, maxI, maxW, maxE, msg)
-		if err != nil {
-			return fmt.Errorf("fault save E: {%v}", err)
+// DSN of SQLite with parameters of connections not set by the name: locks are waited instead of SQLITE_BUSY,
+// WAL - readers do not block the writer, transactions take the write lock at BEGIN (BEGIN IMMEDIATE)
+func sqliteDSN(name string) string {
+
+	sep := "?"
+	if strings.Contains(name, "?") {
+		sep = "&"
+	}
+	for _, p := range []struct{ key, param string }{
+		{key: "busy_timeout", param: "_pragma=busy_timeout(5000)"},
+		{key: "journal_mode", param: "_pragma=journal_mode(WAL)"},
+		{key: "_txlock", param: "_txlock=immediate"},
+	} {
+		if strings.Contains(name, p.key) {
+			continue
 		}
-	default:
-		return errors.New("not allowed type of message when saving")
+		name += sep + p.param
+		sep = "&"
+	}
+
+	return name
+}
+
+// Execution of fn in one transaction. Writers of the process are serialised,
+// so reading the name of log table, saving and rotation are atomic
+func (o ObjectDB) inWriteTx(fn func(tx *sql.Tx) error) error {
+
+	start := time.Now()
+	defer func() { metrics.ObserveDBWrite("sqlite", time.Since(start)) }()
+
+	o.muWrite.Lock()
+	defer o.muWrite.Unlock()
+
+	ctx := context.Background()
+	conn, err := o.DB.Conn(ctx)
+	if err != nil {
+		return storageFault(fmt.Errorf("fault get connection: {%w}", err))
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return storageFault(fmt.Errorf("fault begin transaction: {%w}", err))
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return storageFault(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		endTx(conn)
+		return storageFault(fmt.Errorf("fault commit transaction: {%w}", err))
 	}
 
 	return nil
 }
 
-// =======================
-// ==      INTERNAL     ==
-// =======================
+// Ending the transaction left open by the failed commit (SQLITE_BUSY, deferred constraints),
+// so next writers do not start a transaction within it. Not rolled back - the connection is discarded
+func endTx(conn *sql.Conn) {
 
-// Check overload the log table
-func checkOverloadLogTable(typeTable, maxI, maxW, maxE string, lastId int64) (bool, error) {
+	_, err := conn.ExecContext(context.Background(), "ROLLBACK")
+	if err != nil && !strings.Contains(err.Error(), "no transaction is active") {
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
 
-	// read env constant
-	var maxId int64
-	switch typeTable {
-	case "I":
-		maxId_t, err := strconv.ParseInt(maxI, 10, 64)
+// Saving the message of batch by save. Changes of the fault message are rolled back to the savepoint
+func savingInSavepoint(tx *sql.Tx, save func(db queryer) error) error {
+
+	_, err := tx.Exec("SAVEPOINT msg")
+	if err != nil {
+		return fmt.Errorf("fault create savepoint: {%w}", err)
+	}
+
+	errSave := save(tx)
+	if errSave != nil {
+		_, err = tx.Exec("ROLLBACK TO msg")
 		if err != nil {
-			return false, fmt.Errorf("fault parse string I: {%v}", err)
+			return fmt.Errorf("fault rollback to savepoint: {%v}: {%v}", err, errSave)
 		}
-		maxId = maxId_t
+	}
+
+	_, err = tx.Exec("RELEASE msg")
+	if err != nil {
+		return fmt.Errorf("fault release savepoint: {%w}", err)
+	}
+
+	return errSave
+}
+
+// Saving the message in the current log table of its type. Return reference, true if the log table is changed, error
+func savingByType(db queryer, cfg ConfigT, msg MessageT) (RefT, bool, error) {
+
+	nameI, nameW, nameE, err := readLogTablesName(db)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault read name of tables: {%w}", err)
+	}
+
+	var name string
+	switch msg.TypeMessage {
+	case "I":
+		name = nameI
 	case "W":
-		maxId_t, err := strconv.ParseInt(maxW, 10, 64)
-		if err != nil {
-			return false, fmt.Errorf("fault parse string W: {%v}", err)
-		}
-		maxId = maxId_t
+		name = nameW
 	case "E":
-		maxId_t, err := strconv.ParseInt(maxE, 10, 64)
-		if err != nil {
-			return false, fmt.Errorf("fault parse string E: {%v}", err)
+		name = nameE
+	default:
+		return RefT{}, false, &FieldError{Field: "typeMessage", Description: "not allowed type of message when saving"}
+	}
+
+	// Saving the message
+	id, over, err := savingMessageCheckResult(db, name, cfg, msg)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault save %s: {%w}", msg.TypeMessage, err)
+	}
+
+	return RefT{NameTable: name, Id: id}, over, nil
+}
+
+// Counting changes of log tables by saved messages after the commit. errs - results of messages, nil - all are saved
+func countRotations(msgs []MessageT, rotated []bool, errs []error) {
+	for i, msg := range msgs {
+		if rotated[i] && (errs == nil || errs[i] == nil) {
+			metrics.Rotated(msg.TypeMessage)
 		}
-		maxId = maxId_t
+	}
+}
+
+// Check fields of the message before saving
+func checkMessage(msg MessageT) error {
+
+	switch msg.TypeMessage {
+	case "I", "W", "E":
 	default:
-		return false, fmt.Errorf("not supported type of table: {%s}", typeTable)
+		return &FieldError{Field: "typeMessage", Description: "not allowed type of message when saving"}
+	}
+	if msg.BodyMessage == "" {
+		return &FieldError{Field: "bodyMessage", Description: "empty msg.BodyMessage"}
+	}
+	if msg.LocationEvent == "" {
+		return &FieldError{Field: "locationEvent", Description: "empty msg.LocationEvent"}
+	}
+	if msg.NameProject == "" {
+		return &FieldError{Field: "nameProject", Description: "empty msg.NameProject"}
+	}
+	err := checkAttributes(msg.Attributes)
+	if err != nil {
+		return err
+	}
+
+	return checkMessageId(msg.MessageId)
+}
+
+// Check overload the log table
+func checkOverloadLogTable(typeTable string, cfg ConfigT, lastId int64) (bool, error) {
+
+	maxId := cfg.MaxIdNumb(typeTable)
+	if maxId <= 0 {
+		return false, fmt.Errorf("not allowed max of {%s} table: {%d}", typeTable, maxId)
 	}
 
 	if lastId > maxId {
@@ -179,7 +416,7 @@ func checkOverloadLogTable(typeTable, maxI, maxW, maxE string, lastId int64) (bo
 }
 
 // TypeMessage
-func doSaving(db *sql.DB, tableName string, msg MessageT) (int64, error) {
+func doSaving(db queryer, tableName string, msg MessageT) (int64, error) {
 
 	if db == nil {
 		return 0, errors.New("empty pointer db")
@@ -188,57 +425,81 @@ func doSaving(db *sql.DB, tableName string, msg MessageT) (int64, error) {
 		return 0, errors.New("empty tableName")
 	}
 	if msg.BodyMessage == "" {
-		return 0, errors.New("empty msg.BodyMessage")
+		return 0, &FieldError{Field: "bodyMessage", Description: "empty msg.BodyMessage"}
 	}
 	if msg.LocationEvent == "" {
-		return 0, errors.New("empty msg.LocationEvent")
+		return 0, &FieldError{Field: "locationEvent", Description: "empty msg.LocationEvent"}
 	}
 	if msg.NameProject == "" {
-		return 0, errors.New("empty msg.NameProject")
+		return 0, &FieldError{Field: "nameProject", Description: "empty msg.NameProject"}
+	}
+	err := checkAttributes(msg.Attributes)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := attributesJSON(msg.Attributes)
+	if err != nil {
+		return 0, err
 	}
 
-	q := fmt.Sprintf("INSERT INTO %s (nameProject, locationEvent, bodyMessage) VALUES (:project, :location, :body)", tableName)
+	q := fmt.Sprintf(`INSERT INTO %s (nameProject, locationEvent, bodyMessage, eventTime, skewed, attributes, tokenId)
+	VALUES (:project, :location, :body, :event, :skewed, :attributes, :token)`, tableName)
 
 	result, err := db.Exec(q,
 		sql.Named("project", msg.NameProject),
 		sql.Named("location", msg.LocationEvent),
-		sql.Named("body", msg.BodyMessage))
+		sql.Named("body", msg.BodyMessage),
+		sql.Named("event", eventTimeText(msg.EventTime)),
+		sql.Named("skewed", msg.Skewed),
+		sql.Named("attributes", attrs),
+		sql.Named("token", tokenIdOrNull(msg.TokenId)))
 	if err != nil {
-		return 0, fmt.Errorf("store an information -> flt store %s message: %v", msg.TypeMessage, err)
+		return 0, fmt.Errorf("store an information -> flt store %s message: %w", msg.TypeMessage, err)
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("fault get information about id %s table", msg.TypeMessage)
 	}
 
+	err = indexMessage(db, tableName, id, msg)
+	if err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
-// Save message + check overload log table + update name log table + create new table
-func savingMessageCheckResult(db *sql.DB, nameTable, maxI, maxW, maxE string, msg MessageT) error {
+// Save message + update catalog + check overload log table + create new log table.
+// Return id of the message, true if the log table is changed, error
+func savingMessageCheckResult(db queryer, nameTable string, cfg ConfigT, msg MessageT) (int64, bool, error) {
 
 	id, err := doSaving(db, nameTable, msg)
 	if err != nil {
-		return fmt.Errorf("fault saving {%s} message: {%v}", msg.TypeMessage, err)
+		return 0, false, fmt.Errorf("fault saving {%s} message: {%w}", msg.TypeMessage, err)
 	}
 
-	over, err := checkOverloadLogTable(msg.TypeMessage, maxI, maxW, maxE, id)
+	err = updatePartitionStats(db, nameTable, id)
 	if err != nil {
-		return fmt.Errorf("fault check overload {%s} table: {%v}", msg.TypeMessage, err)
+		return 0, false, fmt.Errorf("fault update catalog of {%s} table: {%w}", nameTable, err)
+	}
+
+	over, err := checkOverloadLogTable(msg.TypeMessage, cfg, id)
+	if err != nil {
+		return 0, false, fmt.Errorf("fault check overload {%s} table: {%v}", msg.TypeMessage, err)
 	}
 
 	if over {
 		err := changeLogTableNameCreate(db, msg.TypeMessage)
 		if err != nil {
-			return fmt.Errorf("fault update name of {%s} table: {%v}", msg.TypeMessage, err)
+			return 0, false, fmt.Errorf("fault update name of {%s} table: {%w}", msg.TypeMessage, err)
 		}
 	}
 
-	return nil
+	return id, over, nil
 }
 
 // Check create table by name
-func checkCreateLogTable(db *sql.DB, name string) error {
+func checkCreateLogTable(db queryer, name string) error {
 	if db == nil {
 		return fmt.Errorf("fault check create table {%s} -> not pointer db", name)
 	}
@@ -252,7 +513,11 @@ func checkCreateLogTable(db *sql.DB, name string) error {
 	nameProject string NOT NULL,
 	locationEvent string NOT NULL,
 	bodyMessage string NOT NULL,
-	timestamp TEXT DEFAULT CURRENT_TIMESTAMP);
+	timestamp TEXT DEFAULT CURRENT_TIMESTAMP,
+	eventTime TEXT,
+	skewed INTEGER NOT NULL DEFAULT 0,
+	attributes TEXT,
+	tokenId TEXT);
 	`, name)
 
 	_, err := db.Exec(q)
@@ -263,123 +528,32 @@ func checkCreateLogTable(db *sql.DB, name string) error {
 	return nil
 }
 
-// Initialisation the main table
-func initMainTable(db *sql.DB, nameI, nameW, nameE string) (int64, error) {
-	if db == nil {
-		return 0, errors.New("missed db pointer")
-	}
-	if nameI == "" {
-		return 0, errors.New("empty content of nameI")
-	}
-	if nameW == "" {
-		return 0, errors.New("empty content of nameW")
-	}
-	if nameE == "" {
-		return 0, errors.New("empty content of nameE")
-	}
-
-	result, err := db.Exec("INSERT INTO main (nameTableI, nameTableW, nameTableE) VALUES (?, ?, ?)", nameI, nameW, nameE)
-	if err != nil {
-		return 0, fmt.Errorf("fault initialisation the main table: %v", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("fault get id by LastInsertId: {%v}", err)
-	}
-
-	if id != 1 {
-		return 0, fmt.Errorf("the id must be 1, but current: {%d} ", id)
-	}
-
-	return id, nil
-}
-
-// Reading log table names from the main table
-func readLogTablesName(db *sql.DB) (nameI, nameW, nameE string, err error) {
-	if db == nil {
-		return "", "", "", errors.New("missed db pointer")
-	}
-
-	row := db.QueryRow("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1")
-
-	err = row.Scan(&nameI, &nameW, &nameE)
-	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		return "", "", "", sql.ErrNoRows
-	}
-	if err != nil {
-		return "", "", "", fmt.Errorf("fault reading log table names from the main table: {%v}", err)
-	}
-
-	return nameI, nameW, nameE, nil
-}
-
-// Check-create main tables
-func checkCreateMainTable(db *sql.DB) error {
+// Change the current log table: close it and create the next one
+func changeLogTableNameCreate(db queryer, typeTable string) error {
 	if db == nil {
 		return errors.New("missed db pointer")
 	}
 
-	_, err := db.Exec(`
-	CREATE TABLE IF NOT EXISTS main (
-	id INTEGER PRIMARY KEY,
-	nameTableI string UNIQUE,
-	nameTableW string UNIQUE,
-	nameTableE string UNIQUE,
-	timestamp TEXT DEFAULT CURRENT_TIMESTAMP);
-	`)
-	if err != nil {
-		return fmt.Errorf("fault create the main table: %v", err)
-	}
-
-	return nil
-}
-
-// Change the name of log table
-func changeLogTableNameCreate(db *sql.DB, typeTable string) error {
-	if db == nil {
-		return errors.New("missed db pointer")
+	switch typeTable {
+	case "I", "W", "E":
+	default:
+		return fmt.Errorf("error in type of table. want I or W or E, recieve: {%s}", typeTable)
 	}
 
-	nameI, nameW, nameE, err := readLogTablesName(db)
+	var (
+		oldName string
+		seq     int
+	)
+	err := db.QueryRow("SELECT nameTable, seq FROM partitions WHERE typeTable = ? AND state = ?", typeTable, StateActive).
+		Scan(&oldName, &seq)
 	if err != nil {
-		return fmt.Errorf("fault read names of tables")
+		return fmt.Errorf("fault read the current {%s} table: {%v}", typeTable, err)
 	}
-	var newName string
+	newName := fmt.Sprintf("log%s_%d", typeTable, seq+1)
 
-	switch typeTable {
-	case "I":
-		newName, err = incrementIdInName(nameI)
-		if err != nil {
-			return fmt.Errorf("fault change name of I table: {%v}", err)
-		}
-		err = updateNameLogTable(db, newName, typeTable)
-		if err != nil {
-			return fmt.Errorf("fault update the name of I table: {%v}", err)
-		}
-
-	case "W":
-		newName, err = incrementIdInName(nameW)
-		if err != nil {
-			return fmt.Errorf("fault change name of W table: {%v}", err)
-		}
-		err = updateNameLogTable(db, newName, typeTable)
-		if err != nil {
-			return fmt.Errorf("fault update the name of W table: {%v}", err)
-		}
-
-	case "E":
-		newName, err = incrementIdInName(nameE)
-		if err != nil {
-			return fmt.Errorf("fault change name of E table: {%v}", err)
-		}
-		err = updateNameLogTable(db, newName, typeTable)
-		if err != nil {
-			return fmt.Errorf("fault update the name of E table: {%v}", err)
-		}
-
-	default:
-		return fmt.Errorf("error in type of table. want I or W or E, recieve: {%s}", typeTable)
+	err = closePartition(db, oldName)
+	if err != nil {
+		return fmt.Errorf("fault close {%s} table: {%v}", oldName, err)
 	}
 
 	// Create new table
@@ -387,71 +561,15 @@ func changeLogTableNameCreate(db *sql.DB, typeTable string) error {
 	if err != nil {
 		return fmt.Errorf("fault create new table {%s}: {%v}", newName, err)
 	}
-
-	return nil
-}
-
-// Increment an index in the name log table
-func incrementIdInName(name string) (string, error) {
-
-	sl := strings.Split(name, "_")
-	if len(sl) != 2 {
-		return "", fmt.Errorf("not correct the name of table: {%s}", name)
-	}
-
-	index, err := strconv.Atoi(sl[1])
+	err = checkCreateFtsTable(db, newName)
 	if err != nil {
-		return "", fmt.Errorf("name table not have the index table: {%s}", name)
+		return fmt.Errorf("fault create full-text index of {%s}: {%v}", newName, err)
 	}
 
-	index += 1
-
-	return fmt.Sprintf("%s_%d", sl[0], index), nil
-}
-
-// Update the name log table in the main table
-func updateNameLogTable(db *sql.DB, newName, typeTable string) error {
-	if db == nil {
-		return errors.New("missed db pointer")
-	}
-	if newName == "" {
-		return errors.New("missed content newName")
-	}
-	if typeTable == "" {
-		return errors.New("missed content typeTable")
-	}
-
-	var resQ sql.Result
-	switch typeTable {
-	case "I":
-		res, err := db.Exec("UPDATE main SET nameTableI=? WHERE id=1", newName)
-		if err != nil {
-			return fmt.Errorf("fault update the name of I table: {%v}", err)
-		}
-		resQ = res
-	case "W":
-		res, err := db.Exec("UPDATE main SET nameTableW=? WHERE id=1", newName)
-		if err != nil {
-			return fmt.Errorf("fault update the name of W table: {%v}", err)
-		}
-		resQ = res
-	case "E":
-		res, err := db.Exec("UPDATE main SET nameTableE=? WHERE id=1", newName)
-		if err != nil {
-			return fmt.Errorf("fault update the name of E table: {%v}", err)
-		}
-		resQ = res
-	default:
-		return fmt.Errorf("error in type of table. want I or W or E, recieve: {%s}", typeTable)
-	}
-
-	nChStr, err := resQ.RowsAffected()
+	err = openPartition(db, typeTable, newName, seq+1)
 	if err != nil {
-		return fmt.Errorf("error get RowsAffected after update: {%v}", err)
+		return fmt.Errorf("fault open {%s} table: {%v}", newName, err)
 	}
 
-	if nChStr != 1 {
-		return errors.New("fault execution update")
-	}
 	return nil
 }
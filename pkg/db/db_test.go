@@ -2,6 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -20,6 +23,8 @@ import (
 // Test - Saving the received message in the database. Return error
 func Test_SavingMessage_SUCCESS(t *testing.T) {
 
+	cfg := testConfig(10, 10, 10)
+
 	msg := []MessageT{
 		{
 			TypeMessage:   "I",
@@ -49,117 +54,201 @@ func Test_SavingMessage_SUCCESS(t *testing.T) {
 		{
 			nameTest: "Save I msg. Not Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage, nil, false, nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[0].BodyMessage, msg[0].LocationEvent).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
 			},
 			index: 0,
 		},
 		{
 			nameTest: "Save I msg. Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(20), msg[0].BodyMessage, msg[0].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logI_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(20)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("I", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logI_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logI_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logI_2", "I", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectCommit()
 			},
 			index: 0,
 		},
 		{
 			nameTest: "Save W msg. Not Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[1].BodyMessage, msg[1].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
 			},
 			index: 1,
 		},
 		{
 			nameTest: "Save W msg. Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(20), msg[1].BodyMessage, msg[1].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logW_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(20)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("W", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logW_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logW_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logW_2", "W", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectCommit()
 			},
 			index: 1,
 		},
 		{
 			nameTest: "Save E msg. Not Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[2].BodyMessage, msg[2].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
 			},
 			index: 2,
 		},
 		{
 			nameTest: "Save E msg. Over ",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(20), msg[2].BodyMessage, msg[2].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(20, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logE_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(20)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("E", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logE_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logE_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logE_2", "E", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
+				mock.ExpectCommit()
 			},
 			index: 2,
 		},
@@ -174,12 +263,284 @@ func Test_SavingMessage_SUCCESS(t *testing.T) {
 
 			tt.initMock(mock)
 
-			instAct, err := RepoDB(db)
+			instAct, err := RepoDB(db, cfg)
 			require.NoError(t, err)
 
-			instAct.SavingMessage(msg[tt.index])
+			_, err = instAct.SavingMessage(msg[tt.index])
 			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+
+		})
+	}
+}
+
+// Test - Saving the batch of messages in one transaction
+func Test_SavingMessages_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 2, 2)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	msgs := []MessageT{
+		{TypeMessage: "I", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "1"},
+		{TypeMessage: "I", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "2"},
+		{TypeMessage: "I", NameProject: "", LocationEvent: "cmd/main.go:65", BodyMessage: "no project"},
+		{TypeMessage: "X", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "bad type"},
+		{TypeMessage: "I", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "3"},
+		{TypeMessage: "I", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "4"},
+		{TypeMessage: "E", NameProject: "project", LocationEvent: "cmd/main.go:66", BodyMessage: "5"},
+	}
+
+	refs, results, err := instAct.SavingMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, results, len(msgs))
+	assert.Equal(t, RefT{NameTable: "logI_1", Id: 3}, refs[4])
+	assert.Equal(t, RefT{NameTable: "logI_2", Id: 1}, refs[5])
+	assert.Equal(t, RefT{}, refs[2], "not saved message")
+	for i, res := range results {
+		if i == 2 || i == 3 {
+			assert.Errorf(t, res, "message {%d}", i)
+			continue
+		}
+		assert.NoErrorf(t, res, "message {%d}", i)
+	}
+
+	// logI_1 is overloaded by the 3rd message, the 4th is in logI_2
+	nameI, _, _, err := readLogTablesName(db)
+	require.NoError(t, err)
+	assert.Equal(t, "logI_2", nameI)
+
+	stored, err := instAct.ReadingMessages(FilterT{})
+	require.NoError(t, err)
+	assert.Len(t, stored, 5)
+}
+
+// Test - Saving the batch of messages. Begin and commit of the transaction
+func Test_SavingMessages_Mock_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT msg").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+		WithArgs(StateActive).
+		WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+			AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
+	mock.ExpectExec("INSERT INTO logW_1").
+		WithArgs("project", "cmd/main.go:65", "Not equal", nil, false, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO fts_logW_1").
+		WithArgs(int64(1), "Not equal", "cmd/main.go:65").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("UPDATE partitions SET firstId").
+		WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE msg").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+
+	_, results, err := instAct.SavingMessages([]MessageT{
+		{TypeMessage: "W", NameProject: "project", LocationEvent: "cmd/main.go:65", BodyMessage: "Not equal"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []error{nil}, results)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test - Concurrent saving with rotation of log tables. Every message is saved once, indexes of log tables are contiguous
+func Test_SavingMessage_Concurrent_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	const (
+		workers   = 16
+		perWorker = 60
+		sizeBatch = 7
+	)
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "stress.db"))
+	require.NoError(t, err)
+	defer db.Close()
 
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// even workers save by one message, odd workers save by batches
+			var batch []MessageT
+			for i := 0; i < perWorker; i++ {
+				msg := MessageT{
+					TypeMessage:   "I",
+					NameProject:   "stress",
+					LocationEvent: "db_test.go",
+					BodyMessage:   fmt.Sprintf("%d-%d", w, i),
+				}
+				if w%2 == 0 {
+					errs <- savingErr(instAct, msg)
+					continue
+				}
+				batch = append(batch, msg)
+				if len(batch) == sizeBatch || i == perWorker-1 {
+					_, res, err := instAct.SavingMessages(batch)
+					errs <- err
+					for _, err := range res {
+						errs <- err
+					}
+					batch = nil
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	names, err := readPartitionsName(db, "I")
+	require.NoError(t, err)
+	nameI, _, _, err := readLogTablesName(db)
+	require.NoError(t, err)
+	assert.Equal(t, names[len(names)-1], nameI, "the current log table is the last one")
+
+	bodies := make(map[string]int)
+	for i, name := range names {
+		assert.Equal(t, fmt.Sprintf("logI_%d", i+1), name, "indexes of log tables are contiguous")
+
+		rows, err := db.Query(fmt.Sprintf("SELECT id, bodyMessage FROM %s ORDER BY id", name))
+		require.NoError(t, err)
+		var n int64
+		for rows.Next() {
+			var (
+				id   int64
+				body string
+			)
+			require.NoError(t, rows.Scan(&id, &body))
+			n++
+			assert.Equal(t, n, id, "ids in {%s} are contiguous", name)
+			bodies[body]++
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+
+		// a table is closed after the message with id 11
+		if name != nameI {
+			assert.Equalf(t, int64(11), n, "rows in {%s}", name)
+		} else {
+			assert.LessOrEqualf(t, n, int64(11), "rows in {%s}", name)
+		}
+	}
+
+	assert.Len(t, bodies, workers*perWorker)
+	for body, n := range bodies {
+		assert.Equalf(t, 1, n, "message {%s} is saved {%d} times", body, n)
+	}
+}
+
+// Test - Writers and concurrent readers of the SQLite file: writes wait for locks, not SQLITE_BUSY
+func Test_SavingMessage_Readers_SUCCESS(t *testing.T) {
+
+	instAct, closeDB, err := Open(DriverConfigT{Type: "sqlite", Name: filepath.Join(t.TempDir(), "iwe.db"), ConfigT: testConfig(20, 20, 20)})
+	require.NoError(t, err)
+	defer closeDB()
+	require.NoError(t, instAct.Tables())
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, _ = instAct.Partitions()
+				_, _ = instAct.ReadingMessages(FilterT{TypeMessage: "I", Limit: 50})
+			}
+		}()
+	}
+
+	for i := range 500 {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: fmt.Sprintf("%d", i)})
+		require.NoError(t, err)
+	}
+	close(done)
+	wg.Wait()
+
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "I", Limit: maxLimitRead})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 500)
+}
+
+// Test - The failed commit does not leave the transaction open: next writes are saved
+func Test_inWriteTx_Commit_SUCCESS(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "iwe.db")
+	obj, closeDB, err := Open(DriverConfigT{Type: "sqlite", Name: path + "?_pragma=foreign_keys(1)", ConfigT: testConfig(10, 10, 10)})
+	require.NoError(t, err)
+	defer closeDB()
+	require.NoError(t, obj.Tables())
+	o := obj.(*ObjectDB)
+
+	require.NoError(t, o.inWriteTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE parent (id INTEGER PRIMARY KEY); " +
+			"CREATE TABLE child (parentId INTEGER REFERENCES parent(id) DEFERRABLE INITIALLY DEFERRED)")
+		return err
+	}))
+
+	// the deferred constraint fails on COMMIT, the transaction stays active in SQLite
+	err = o.inWriteTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO child (parentId) VALUES (1)")
+		return err
+	})
+	require.ErrorContains(t, err, "fault commit transaction")
+
+	for range 3 {
+		_, err = o.SavingMessage(MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "after fault"})
+		require.NoError(t, err)
+	}
+}
+
+// Test - Parameters of SQLite connections are added if not set by the name
+func Test_sqliteDSN_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		name     string
+		want     string
+	}{
+		{nameTest: "file", name: "iwe.db", want: "iwe.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"},
+		{nameTest: "memory", name: ":memory:", want: ":memory:?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"},
+		{
+			nameTest: "set by the name",
+			name:     "file:iwe.db?_pragma=journal_mode(DELETE)&_txlock=deferred",
+			want:     "file:iwe.db?_pragma=journal_mode(DELETE)&_txlock=deferred&_pragma=busy_timeout(5000)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			assert.Equal(t, tt.want, sqliteDSN(tt.name))
 		})
 	}
 }
@@ -187,6 +548,53 @@ func Test_SavingMessage_SUCCESS(t *testing.T) {
 // Test - Working with database tables
 func Test_Tables_SUCCESS(t *testing.T) {
 
+	// migrations of the new database
+	expectMigrations := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COALESCE").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS partitions").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FROM pragma_table_info").
+			WithArgs("partitions", "state").
+			WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+		mock.ExpectQuery("FROM sqlite_master WHERE type = 'table' AND name = ?").
+			WithArgs("main").
+			WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+		mock.ExpectExec("CREATE UNIQUE INDEX IF NOT EXISTS partitions_active").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT nameTable FROM partitions WHERE rowCount IS NULL").
+			WillReturnRows(sqlmock.NewRows([]string{"nameTable"}))
+		mock.ExpectExec("INSERT INTO schema_version").
+			WithArgs(1, "catalog of log tables").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS messageIds").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE INDEX IF NOT EXISTS messageIds_timeSaved").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_version").
+			WithArgs(2, "ids of messages").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+
+		for _, m := range migrations[2:] {
+			if m.apply != nil {
+				mock.ExpectExec("CREATE TABLE IF NOT EXISTS tokens").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			mock.ExpectQuery(`SELECT nameTable FROM partitions WHERE state IN`).
+				WithArgs(StateActive, StateClosed).
+				WillReturnRows(sqlmock.NewRows([]string{"nameTable"}))
+			mock.ExpectExec("INSERT INTO schema_version").
+				WithArgs(m.version, m.name).
+				WillReturnResult(sqlmock.NewResult(int64(m.version), 1))
+		}
+		mock.ExpectCommit()
+	}
+
 	tests := []struct {
 		nameTest string
 		initMock func(mock sqlmock.Sqlmock)
@@ -195,39 +603,54 @@ func Test_Tables_SUCCESS(t *testing.T) {
 			nameTest: "Tables is missed",
 			initMock: func(mock sqlmock.Sqlmock) {
 
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
-					WillReturnResult(sqlmock.NewResult(0, 0))
+				expectMigrations(mock)
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}))
 
-				mock.ExpectExec("INSERT INTO main").
-					WithArgs("logI_1", "logW_1", "logE_1").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				for _, typeTable := range []string{"I", "W", "E"} {
+					mock.ExpectQuery("SELECT COUNT").
+						WithArgs(StateActive, typeTable).
+						WillReturnRows(sqlmock.NewRows([]string{"active", "seq"}).AddRow(0, 0))
 
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectExec("INSERT INTO partitions").
+						WithArgs("log"+typeTable+"_1", typeTable, 1, StateActive).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+				}
 
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
 
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+				for _, typeTable := range []string{"I", "W", "E"} {
+					mock.ExpectExec("CREATE TABLE IF NOT EXISTS log" + typeTable + "_1").WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_log" + typeTable + "_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				}
 			},
 		},
 		{
 			nameTest: "Tables is exists",
 			initMock: func(mock sqlmock.Sqlmock) {
 
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
+				// the schema of the last version, migrations are not applied
+				mock.ExpectBegin()
+				mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").
 					WillReturnResult(sqlmock.NewResult(0, 0))
-
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
-
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
-
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
-
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT COALESCE").
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(len(migrations)))
+				mock.ExpectCommit()
+
+				mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+					WithArgs(StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+						AddRow("I", "logI_3").AddRow("W", "logW_1").AddRow("E", "logE_2"))
+
+				for _, name := range []string{"logI_3", "logW_1", "logE_2"} {
+					mock.ExpectExec("CREATE TABLE IF NOT EXISTS " + name).WillReturnResult(sqlmock.NewResult(0, 0))
+					mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_" + name).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
 			},
 		},
 	}
@@ -241,11 +664,12 @@ func Test_Tables_SUCCESS(t *testing.T) {
 
 			tt.initMock(mock)
 
-			instAct, err := RepoDB(db)
+			instAct, err := RepoDB(db, testConfig(10, 10, 10))
 			require.NoError(t, err)
 
 			err = instAct.Tables()
 			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
 
 		})
 	}
@@ -275,63 +699,42 @@ func Test_checkOverloadLogTable_SUCCESS(t *testing.T) {
 		nameTest   string
 		typeTable  string
 		curStrNumb int64
-		maxI       string
-		maxW       string
-		maxE       string
 		wantFlag   bool
 	}{
 		{
 			nameTest:   "Not over I",
 			typeTable:  "I",
 			curStrNumb: 1,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   false,
 		},
 		{
 			nameTest:   "Not over W",
 			typeTable:  "W",
 			curStrNumb: 1,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   false,
 		},
 		{
 			nameTest:   "Not over E",
 			typeTable:  "E",
 			curStrNumb: 1,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   false,
 		},
 		{
 			nameTest:   "Over I",
 			typeTable:  "I",
 			curStrNumb: 11,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   true,
 		},
 		{
 			nameTest:   "Over W",
 			typeTable:  "W",
 			curStrNumb: 11,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   true,
 		},
 		{
 			nameTest:   "Over E",
 			typeTable:  "E",
 			curStrNumb: 11,
-			maxI:       "10",
-			maxW:       "10",
-			maxE:       "10",
 			wantFlag:   true,
 		},
 	}
@@ -339,7 +742,7 @@ func Test_checkOverloadLogTable_SUCCESS(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.nameTest, func(t *testing.T) {
 
-			flag, err := checkOverloadLogTable(tt.typeTable, tt.maxI, tt.maxW, tt.maxE, tt.curStrNumb)
+			flag, err := checkOverloadLogTable(tt.typeTable, testConfig(10, 10, 10), tt.curStrNumb)
 			require.NoError(t, err)
 			assert.Equalf(t, tt.wantFlag, flag, "want:{%t}, recieved:{%t}", tt.wantFlag, flag)
 		})
@@ -361,14 +764,15 @@ func Test_doSaving_SUCCESS(t *testing.T) {
 	}
 	var tableName = "logI_1"
 
-	mock.ExpectExec("INSERT INTO").WithArgs(msg.NameProject, msg.LocationEvent, msg.BodyMessage).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO").WithArgs(msg.NameProject, msg.LocationEvent, msg.BodyMessage, nil, false, nil, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO fts_logI_1").WithArgs(int64(1), msg.BodyMessage, msg.LocationEvent).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	ind, err := doSaving(db, tableName, msg)
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), ind)
 }
 
-// Test - Save message + check overload log table + update name log table + create new table
+// Test - Save message + update catalog + check overload log table + create new log table
 func Test_savingMessageCheckResult_SUCCESS(t *testing.T) {
 
 	msg := []MessageT{
@@ -397,120 +801,165 @@ func Test_savingMessageCheckResult_SUCCESS(t *testing.T) {
 		mockInit  func(mock sqlmock.Sqlmock)
 		index     int
 		nameTable string
-		maxI      string
-		maxW      string
-		maxE      string
 	}{
 		{
 			nameTest: "Store msg I. Not over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage, nil, false, nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[0].BodyMessage, msg[0].LocationEvent).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 			index:     0,
 			nameTable: "logI_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 		{
 			nameTest: "Store msg I. Over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage).
+					WithArgs(msg[0].NameProject, msg[0].LocationEvent, msg[0].BodyMessage, nil, false, nil, nil).
+					WillReturnResult(sqlmock.NewResult(6, 1))
+
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(6), msg[0].BodyMessage, msg[0].LocationEvent).
 					WillReturnResult(sqlmock.NewResult(6, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(6)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logI_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("I", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logI_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logI_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logI_2", "I", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			index:     0,
 			nameTable: "logI_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 		{
 			nameTest: "Store msg W. Not over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage).
+					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage, nil, false, nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[1].BodyMessage, msg[1].LocationEvent).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 			index:     1,
 			nameTable: "logW_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 		{
 			nameTest: "Store msg W. Over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage).
+					WithArgs(msg[1].NameProject, msg[1].LocationEvent, msg[1].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(6, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(6), msg[1].BodyMessage, msg[1].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(6, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logW_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(6)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("W", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logW_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logW_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logW_2", "W", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			index:     1,
 			nameTable: "logW_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 		{
 			nameTest: "Store msg E. Not over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage).
+					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(1), msg[2].BodyMessage, msg[2].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(1)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 			index:     2,
 			nameTable: "logE_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 		{
 			nameTest: "Store msg E. Over",
 			mockInit: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO").
-					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage).
+					WithArgs(msg[2].NameProject, msg[2].LocationEvent, msg[2].BodyMessage, nil, false, nil, nil).
 					WillReturnResult(sqlmock.NewResult(6, 1))
 
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectExec("INSERT INTO fts_").
+					WithArgs(int64(6), msg[2].BodyMessage, msg[2].LocationEvent).
+					WillReturnResult(sqlmock.NewResult(6, 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logE_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET firstId").
+					WithArgs(sql.Named("id", int64(6)), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("E", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logE_1", 1))
+
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logE_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logE_2", "E", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			index:     2,
 			nameTable: "logE_1",
-			maxI:      "5",
-			maxW:      "5",
-			maxE:      "5",
 		},
 	}
 
@@ -523,7 +972,7 @@ func Test_savingMessageCheckResult_SUCCESS(t *testing.T) {
 
 			tt.mockInit(mock)
 
-			err = savingMessageCheckResult(db, tt.nameTable, tt.maxI, tt.maxW, tt.maxE, msg[tt.index])
+			_, _, err = savingMessageCheckResult(db, tt.nameTable, testConfig(5, 5, 5), msg[tt.index])
 			require.NoError(t, err)
 		})
 	}
@@ -543,60 +992,7 @@ func Test_checkCreateLogTable_SUCCESS(t *testing.T) {
 	require.NoError(t, err)
 }
 
-// Test - Initialisation the main table
-func Test_initMainTable_SUCCESS(t *testing.T) {
-
-	nameI := "logI_1"
-	nameW := "logW_1"
-	nameE := "logE_1"
-
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-
-	mock.ExpectExec("INSERT INTO main").
-		WithArgs(nameI, nameW, nameE).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	num, err := initMainTable(db, nameI, nameW, nameE)
-	require.NoError(t, err)
-	assert.Equalf(t, int64(1), num, "wait 1, recieved:{%d}", num)
-
-}
-
-// Test - Reading log table names from the main table
-func Test_readLogTablesName_SUCCESS(t *testing.T) {
-
-	db, mock, err := sqlmock.New()
-	require.NoErrorf(t, err, "recieved error: {%v}", err)
-	defer db.Close()
-
-	mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-		WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-			AddRow("logI_1", "logW_1", "logE_1"))
-
-	nameI, nameW, nameE, err := readLogTablesName(db)
-	require.NoErrorf(t, err, "recieved err: {%v}", err)
-	assert.Equalf(t, "logI_1", nameI, "wait {logI_1}, recieved {%s}", nameI)
-	assert.Equalf(t, "logW_1", nameW, "wait {logW_1}, recieved {%s}", nameW)
-	assert.Equalf(t, "logE_1", nameE, "wait {logE_1}, recieved {%s}", nameE)
-}
-
-// Test - Check-create main tables
-func Test_checkCreateMainTable_SUCCESS(t *testing.T) {
-
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
-		WillReturnResult(sqlmock.NewResult(0, 0))
-
-	err = checkCreateMainTable(db)
-	require.NoError(t, err)
-}
-
-// Test - Change the name of log table
+// Test - Change the current log table: close it and create the next one
 func Test_changeLogTableNameCreate_SUCCESS(t *testing.T) {
 
 	tests := []struct {
@@ -607,48 +1003,66 @@ func Test_changeLogTableNameCreate_SUCCESS(t *testing.T) {
 		{
 			nameTest: "Change name I table",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("I", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logI_1", 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logI_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logI_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logI_2", "I", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			typeTable: "I",
 		},
 		{
 			nameTest: "Change name W table",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("W", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logW_1", 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logW_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logW_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logW_2", "W", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			typeTable: "W",
 		},
 		{
 			nameTest: "Change name E table",
 			initMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").
-					WillReturnRows(sqlmock.NewRows([]string{"nameTableI", "nameTableW", "nameTableE"}).
-						AddRow("logI_1", "logW_1", "logE_1"))
+				mock.ExpectQuery("SELECT nameTable, seq FROM partitions").
+					WithArgs("E", StateActive).
+					WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq"}).AddRow("logE_1", 1))
 
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logE_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE partitions SET state").
+					WithArgs(StateClosed, "logE_1", StateActive).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectExec("CREATE TABLE IF NOT EXISTS").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectExec("INSERT INTO partitions").
+					WithArgs("logE_2", "E", 2, StateActive).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			typeTable: "E",
 		},
@@ -669,72 +1083,6 @@ func Test_changeLogTableNameCreate_SUCCESS(t *testing.T) {
 	}
 }
 
-// Test - // Increment an index in the name log table
-func Test_incrementIdInName_SUCCESS(t *testing.T) {
-
-	curName := "logI_1"
-	waitName := "logI_2"
-
-	newName, err := incrementIdInName(curName)
-	require.NoError(t, err)
-	assert.Equalf(t, waitName, newName, "wait:{%s} recieved:{%s}", waitName, newName)
-}
-
-// Test - Update the name log table in the main table
-func Test_updateNameLogTable_SUCCESS(t *testing.T) {
-
-	tests := []struct {
-		nameTest  string
-		nameTable string
-		nameType  string
-		mocks     func(mock sqlmock.Sqlmock)
-	}{
-		{
-			nameTest:  "SUCCESS change name LogI_1",
-			nameTable: "logI_2",
-			nameType:  "I",
-			mocks: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logI_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
-			},
-		},
-		{
-			nameTest:  "SUCCESS change name LogW_1",
-			nameTable: "logW_2",
-			nameType:  "W",
-			mocks: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logW_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
-			},
-		},
-		{
-			nameTest:  "SUCCESS change name LogE_1",
-			nameTable: "logE_2",
-			nameType:  "E",
-			mocks: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE main SET nameTable").
-					WithArgs("logE_2").
-					WillReturnResult(sqlmock.NewResult(1, 1))
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.nameTest, func(t *testing.T) {
-
-			db, mock, err := sqlmock.New()
-			require.NoError(t, err)
-			defer db.Close()
-
-			tt.mocks(mock)
-			err = updateNameLogTable(db, tt.nameTable, tt.nameType)
-			require.NoErrorf(t, err, "wait no error, but recieved: {%v}", err)
-		})
-	}
-}
-
 // =======================
 // ==       FAULT       ==
 // =======================
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const maxSizeMessageId = 128
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Create the table of ids of saved messages. The same query for SQLite and PostgreSQL
+func checkCreateMessageIdsTable(db queryer) error {
+	if db == nil {
+		return errors.New("fault check create table messageIds -> not pointer db")
+	}
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS messageIds (
+	nameProject TEXT NOT NULL,
+	messageId TEXT NOT NULL,
+	timeSaved BIGINT NOT NULL,
+	PRIMARY KEY (nameProject, messageId));
+	`)
+	if err != nil {
+		return fmt.Errorf("table {messageIds} is not created: %v", err)
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS messageIds_timeSaved ON messageIds (timeSaved)")
+	if err != nil {
+		return fmt.Errorf("index of {messageIds} is not created: %v", err)
+	}
+
+	return nil
+}
+
+// Saving the message by save if its id is not registered within the window. The id is registered
+// in the same transaction, so it is rolled back with the fault message. Return ErrDuplicate, error
+func savingUnique(db queryer, window time.Duration, now time.Time, msg MessageT, save func(db queryer) error) error {
+
+	err := checkMessageId(msg.MessageId)
+	if err != nil {
+		return err
+	}
+	if msg.MessageId == "" || window == 0 {
+		return save(db)
+	}
+
+	// the expired id is taken again, the row is locked up to the end of transaction
+	res, err := db.Exec(`INSERT INTO messageIds (nameProject, messageId, timeSaved) VALUES ($1, $2, $3)
+	ON CONFLICT (nameProject, messageId) DO UPDATE SET timeSaved = excluded.timeSaved
+	WHERE messageIds.timeSaved < $4`,
+		msg.NameProject, msg.MessageId, now.UnixMilli(), now.Add(-window).UnixMilli())
+	if err != nil {
+		return fmt.Errorf("fault register id {%s} of message: {%w}", msg.MessageId, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error get RowsAffected after register id: {%w}", err)
+	}
+	if n == 0 {
+		return ErrDuplicate
+	}
+
+	return save(db)
+}
+
+// Removing ids saved before the window
+func purgeMessageIds(db queryer, window time.Duration, now time.Time) error {
+	if window == 0 {
+		return nil
+	}
+
+	_, err := db.Exec("DELETE FROM messageIds WHERE timeSaved < $1", now.Add(-window).UnixMilli())
+	if err != nil {
+		return fmt.Errorf("fault remove expired ids of messages: {%w}", err)
+	}
+
+	return nil
+}
+
+// Check the length of id of the message
+func checkMessageId(id string) error {
+	if len(id) > maxSizeMessageId {
+		return &FieldError{Field: "messageId", Description: fmt.Sprintf("long msg.MessageId: {%d} bytes, allowed {%d}", len(id), maxSizeMessageId)}
+	}
+	return nil
+}
+
+// Check some message of the batch has the id
+func hasMessageIds(msgs []MessageT) bool {
+	for _, msg := range msgs {
+		if msg.MessageId != "" {
+			return true
+		}
+	}
+	return false
+}
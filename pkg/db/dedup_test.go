@@ -0,0 +1,135 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Error of saving the message
+func savingErr(instAct ActionsDB, msg MessageT) error {
+	_, err := instAct.SavingMessage(msg)
+	return err
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Repeated ids of messages are not saved again. SQLite and memory
+func Test_SavingMessage_Dedup_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+	cfg.DedupWindow = time.Hour
+
+	tests := []struct {
+		nameTest string
+		repo     func(t *testing.T) ActionsDB
+	}{
+		{
+			nameTest: "SQLite",
+			repo: func(t *testing.T) ActionsDB {
+				instAct, err := RepoDB(openTestDB(t), cfg)
+				require.NoError(t, err)
+				return instAct
+			},
+		},
+		{
+			nameTest: "Memory",
+			repo: func(t *testing.T) ActionsDB {
+				return RepoMem(cfg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct := tt.repo(t)
+			require.NoError(t, instAct.Tables())
+
+			msg := MessageT{TypeMessage: "E", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: "one", MessageId: "id-1"}
+			require.NoError(t, savingErr(instAct, msg))
+			require.ErrorIs(t, savingErr(instAct, msg), ErrDuplicate)
+
+			// the same id of other project, messages without id
+			other := msg
+			other.NameProject = "beta"
+			require.NoError(t, savingErr(instAct, other))
+			msg.MessageId = ""
+			require.NoError(t, savingErr(instAct, msg))
+			require.NoError(t, savingErr(instAct, msg))
+
+			// repeats inside the batch and of the saved message; the fault message does not take its id
+			_, results, err := instAct.SavingMessages([]MessageT{
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:2", BodyMessage: "two", MessageId: "id-2"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:2", BodyMessage: "two", MessageId: "id-2"},
+				{TypeMessage: "E", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: "one", MessageId: "id-1"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "", BodyMessage: "three", MessageId: "id-3"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:3", BodyMessage: "three", MessageId: "id-3"},
+			})
+			require.NoError(t, err)
+			assert.NoError(t, results[0])
+			assert.ErrorIs(t, results[1], ErrDuplicate)
+			assert.ErrorIs(t, results[2], ErrDuplicate)
+			assert.Error(t, results[3])
+			assert.NotErrorIs(t, results[3], ErrDuplicate)
+			assert.NoError(t, results[4])
+
+			msgs, err := instAct.ReadingMessages(FilterT{})
+			require.NoError(t, err)
+			assert.Len(t, msgs, 6)
+		})
+	}
+}
+
+// Test - The id is taken again after the window, expired ids are removed
+func Test_savingUnique_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+	require.NoError(t, checkCreateMessageIdsTable(db))
+
+	saved := 0
+	save := func(db queryer) error {
+		saved++
+		return nil
+	}
+	msg := MessageT{NameProject: "alpha", MessageId: "id-1"}
+	now := time.Now()
+
+	require.NoError(t, savingUnique(db, time.Hour, now, msg, save))
+	require.ErrorIs(t, savingUnique(db, time.Hour, now.Add(59*time.Minute), msg, save), ErrDuplicate)
+	require.NoError(t, savingUnique(db, time.Hour, now.Add(61*time.Minute), msg, save))
+	// window 0 - deduplication is off
+	require.NoError(t, savingUnique(db, 0, now, msg, save))
+	assert.Equal(t, 3, saved)
+
+	require.NoError(t, purgeMessageIds(db, time.Hour, now.Add(3*time.Hour)))
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM messageIds").Scan(&n))
+	assert.Equal(t, 0, n)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Not allowed id of message and window
+func Test_savingUnique_FAULT(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+	cfg.DedupWindow = time.Hour
+
+	instAct := RepoMem(cfg)
+	require.NoError(t, instAct.Tables())
+
+	msg := MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: "one", MessageId: strings.Repeat("x", maxSizeMessageId+1)}
+	require.Error(t, savingErr(instAct, msg))
+
+	db := openTestDB(t)
+	require.NoError(t, checkCreateMessageIdsTable(db))
+	require.Error(t, savingUnique(db, time.Hour, time.Now(), msg, func(db queryer) error { return nil }))
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Config of the storage driver
+type DriverConfigT struct {
+	Type    string // name of the registered driver: sqlite, postgres, memory
+	Name    string // source of the driver: file of SQLite, DSN of PostgreSQL. Not used by memory
+	ConfigT        // settings of the storage
+}
+
+// Factory of the storage. Return interface, function of close, error
+type DriverFactory func(cfg DriverConfigT) (ActionsDB, func() error, error)
+
+var (
+	muDrivers sync.RWMutex
+	drivers   = make(map[string]DriverFactory)
+)
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Registration of the storage driver by name. Panics if the name is registered twice or the factory is nil
+func Register(name string, factory DriverFactory) {
+
+	muDrivers.Lock()
+	defer muDrivers.Unlock()
+
+	if factory == nil {
+		panic("db: register nil factory of driver " + name)
+	}
+	if _, ok := drivers[name]; ok {
+		panic("db: register driver twice " + name)
+	}
+	drivers[name] = factory
+}
+
+// Names of the registered storage drivers, sorted
+func Drivers() []string {
+
+	muDrivers.RLock()
+	defer muDrivers.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Create the storage by config. Return interface, function of close, error
+func Open(cfg DriverConfigT) (ActionsDB, func() error, error) {
+
+	muDrivers.RLock()
+	factory, ok := drivers[cfg.Type]
+	muDrivers.RUnlock()
+
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown storage driver {%s}, registered: %v", cfg.Type, Drivers())
+	}
+	err := cfg.Check()
+	if err != nil {
+		return nil, nil, fmt.Errorf("fault settings of the storage: %v", err)
+	}
+
+	return factory(cfg)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Factory of the storage over database/sql: connect by the driver name, then create the db object by repo
+func sqlFactory(repo func(db *sql.DB, cfg ConfigT) (ActionsDB, error)) DriverFactory {
+	return func(cfg DriverConfigT) (ActionsDB, func() error, error) {
+
+		ptrDb, closeDB, err := ConDb(cfg.Type, cfg.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		obj, err := repo(ptrDb, cfg.ConfigT)
+		if err != nil {
+			closeDB()
+			return nil, nil, err
+		}
+
+		return obj, closeDB, nil
+	}
+}
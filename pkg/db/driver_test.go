@@ -0,0 +1,134 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Creating the storage by the registered driver
+func Test_Open_SUCCESS(t *testing.T) {
+
+	assert.Subset(t, Drivers(), []string{"memory", "postgres", "sqlite"})
+
+	tests := []struct {
+		nameTest string
+		cfg      DriverConfigT
+	}{
+		{nameTest: "Memory", cfg: DriverConfigT{Type: "memory", ConfigT: testConfig(10, 10, 10)}},
+		{nameTest: "SQLite", cfg: DriverConfigT{Type: "sqlite", Name: ":memory:", ConfigT: testConfig(10, 10, 10)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct, closeDB, err := Open(tt.cfg)
+			require.NoError(t, err)
+			defer closeDB()
+
+			require.NoError(t, instAct.Tables())
+			parts, err := instAct.Partitions()
+			require.NoError(t, err)
+			assert.Len(t, parts, 3)
+		})
+	}
+}
+
+// Test - Close of SQLite in the WAL mode: messages are moved into the database file
+func Test_Open_CloseWAL_SUCCESS(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "iwe.db")
+	cfg := DriverConfigT{Type: "sqlite", Name: path + "?_pragma=journal_mode(WAL)", ConfigT: testConfig(10, 10, 10)}
+
+	instAct, closeDB, err := Open(cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+	_, err = instAct.SavingMessage(MessageT{TypeMessage: "E", NameProject: "p", LocationEvent: "l", BodyMessage: "before close"})
+	require.NoError(t, err)
+	require.NoError(t, closeDB())
+
+	info, err := os.Stat(path + "-wal")
+	if err == nil {
+		assert.Zero(t, info.Size(), "journal is checkpointed")
+	} else {
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	instAct, closeDB, err = Open(DriverConfigT{Type: "sqlite", Name: path, ConfigT: testConfig(10, 10, 10)})
+	require.NoError(t, err)
+	defer closeDB()
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "E"})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "before close", msgs[0].BodyMessage)
+}
+
+// Test - Registration of the own driver
+func Test_Register_SUCCESS(t *testing.T) {
+
+	Register("test_register", func(cfg DriverConfigT) (ActionsDB, func() error, error) {
+		return RepoMem(testConfig(10, 10, 10)), func() error { return nil }, nil
+	})
+	defer func() {
+		muDrivers.Lock()
+		delete(drivers, "test_register")
+		muDrivers.Unlock()
+	}()
+
+	instAct, _, err := Open(DriverConfigT{Type: "test_register", ConfigT: testConfig(10, 10, 10)})
+	require.NoError(t, err)
+	assert.IsType(t, &ObjectMem{}, instAct)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Creating the storage. Unknown driver, not allowed settings
+func Test_Open_FAULT(t *testing.T) {
+
+	_, _, err := Open(DriverConfigT{Type: "mysql", ConfigT: testConfig(10, 10, 10)})
+	require.Error(t, err)
+
+	_, _, err = Open(DriverConfigT{})
+	require.Error(t, err)
+
+	tests := []struct {
+		nameTest string
+		cfg      ConfigT
+	}{
+		{nameTest: "No max of I", cfg: testConfig(0, 10, 10)},
+		{nameTest: "Negative max of E", cfg: testConfig(10, 10, -1)},
+		{nameTest: "Negative window", cfg: ConfigT{MaxIdNumbI: 10, MaxIdNumbW: 10, MaxIdNumbE: 10, DedupWindow: -time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, _, err := Open(DriverConfigT{Type: "memory", ConfigT: tt.cfg})
+			require.Error(t, err)
+		})
+	}
+}
+
+// Test - Registration of the driver. The name is registered, the factory is nil
+func Test_Register_FAULT(t *testing.T) {
+
+	assert.Panics(t, func() {
+		Register("memory", func(cfg DriverConfigT) (ActionsDB, func() error, error) { return nil, nil, nil })
+	})
+	assert.Panics(t, func() {
+		Register("test_nil", nil)
+	})
+}
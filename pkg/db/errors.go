@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors of the storage, checked by errors.Is. Other errors are internal faults
+var (
+	ErrInvalidArgument = errors.New("invalid argument")       // the request is not allowed, see FieldError
+	ErrDuplicate       = errors.New("duplicate message")      // the id of message of the project is saved within the window
+	ErrUnavailable     = errors.New("storage is unavailable") // temporary fault: busy database, lost connection
+	ErrNotFound        = errors.New("not found")              // the requested object is not in the storage
+)
+
+// Not allowed field of the message or filter. Matches ErrInvalidArgument
+type FieldError struct {
+	Field       string // name of the field in the API: bodyMessage, nameProject...
+	Description string
+}
+
+// Temporary fault of the storage. Matches ErrUnavailable and the original error
+type unavailableError struct {
+	err error
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Text of the error
+func (e *FieldError) Error() string {
+	return e.Description
+}
+
+// Match with ErrInvalidArgument
+func (e *FieldError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Text of the error
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+// Match with ErrUnavailable
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// The original error
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// Marking temporary faults of the storage by ErrUnavailable. Other errors are not changed
+func storageFault(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) || !temporaryFault(err) {
+		return err
+	}
+	return &unavailableError{err: err}
+}
+
+// Check the fault of the storage is temporary: the request can be repeated later
+func temporaryFault(err error) bool {
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var errNet net.Error
+	if errors.As(err, &errNet) {
+		return true
+	}
+
+	// connection, insufficient resources, operator intervention, transaction rollback (deadlock, serialization)
+	var errPG *pq.Error
+	if errors.As(err, &errPG) {
+		switch errPG.Code.Class() {
+		case "08", "53", "57", "40":
+			return true
+		}
+		return false
+	}
+
+	var errSQLite *sqlite.Error
+	if errors.As(err, &errSQLite) {
+		switch errSQLite.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+	}
+
+	return false
+}
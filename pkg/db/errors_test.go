@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Not allowed fields of the message are FieldError matched with ErrInvalidArgument
+func Test_FieldError_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	instAct, err := RepoDB(openTestDB(t), cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	tests := []struct {
+		nameTest  string
+		msg       MessageT
+		wantField string
+	}{
+		{nameTest: "Type", msg: MessageT{TypeMessage: "X", NameProject: "p", LocationEvent: "l", BodyMessage: "b"}, wantField: "typeMessage"},
+		{nameTest: "Body", msg: MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l"}, wantField: "bodyMessage"},
+		{nameTest: "Location", msg: MessageT{TypeMessage: "W", NameProject: "p", BodyMessage: "b"}, wantField: "locationEvent"},
+		{nameTest: "Project", msg: MessageT{TypeMessage: "E", LocationEvent: "l", BodyMessage: "b"}, wantField: "nameProject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, err := instAct.SavingMessage(tt.msg)
+			require.ErrorIs(t, err, ErrInvalidArgument)
+
+			var errField *FieldError
+			require.ErrorAs(t, err, &errField)
+			assert.Equal(t, tt.wantField, errField.Field)
+		})
+	}
+
+	_, err = instAct.ReadingMessages(FilterT{TypeMessage: "T"})
+	require.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+// Test - Temporary faults of the storage are marked by ErrUnavailable
+func Test_storageFault_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		err      error
+		want     bool
+	}{
+		{nameTest: "Bad connection", err: fmt.Errorf("fault begin transaction: {%w}", driver.ErrBadConn), want: true},
+		{nameTest: "PostgreSQL connection", err: &pq.Error{Code: "08006"}, want: true},
+		{nameTest: "PostgreSQL deadlock", err: &pq.Error{Code: "40P01"}, want: true},
+		{nameTest: "PostgreSQL syntax", err: &pq.Error{Code: "42601"}, want: false},
+		{nameTest: "Not allowed field", err: &FieldError{Field: "bodyMessage", Description: "empty msg.BodyMessage"}, want: false},
+		{nameTest: "Other", err: errors.New("fault"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			err := storageFault(tt.err)
+			assert.Equal(t, tt.want, errors.Is(err, ErrUnavailable))
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.err.Error(), err.Error())
+		})
+	}
+
+	assert.NoError(t, storageFault(nil))
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+const layoutEventTime = "2006-01-02 15:04:05.000" // event time in SQLite, UTC. Sorted as text with CURRENT_TIMESTAMP
+
+// Bounds of the event time relative to the time of receiving. Zero bounds are not checked
+type EventTimePolicyT struct {
+	MaxFuture time.Duration // event time later than receiving + MaxFuture is skewed
+	MaxPast   time.Duration // event time earlier than receiving - MaxPast is skewed
+	Reject    bool          // skewed messages are rejected, else saved with the Skewed flag
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Check the event time of the message received at received. Skewed is set or FieldError is returned by Reject
+func (p EventTimePolicyT) Apply(msg *MessageT, received time.Time) error {
+
+	if msg.EventTime.IsZero() {
+		return nil
+	}
+
+	var skew string
+	switch {
+	case p.MaxFuture > 0 && msg.EventTime.After(received.Add(p.MaxFuture)):
+		skew = fmt.Sprintf("event time {%s} is later than the server time by more than {%v}", msg.EventTime.UTC().Format(time.RFC3339), p.MaxFuture)
+	case p.MaxPast > 0 && msg.EventTime.Before(received.Add(-p.MaxPast)):
+		skew = fmt.Sprintf("event time {%s} is earlier than the server time by more than {%v}", msg.EventTime.UTC().Format(time.RFC3339), p.MaxPast)
+	default:
+		return nil
+	}
+
+	if p.Reject {
+		return &FieldError{Field: "eventTime", Description: skew}
+	}
+	msg.Skewed = true
+
+	return nil
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Event time for SQLite. Zero - NULL
+func eventTimeText(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(layoutEventTime)
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Event time out of bounds is flagged
+func Test_EventTimePolicyT_Apply_SUCCESS(t *testing.T) {
+
+	received := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := EventTimePolicyT{MaxFuture: 5 * time.Minute, MaxPast: 24 * time.Hour}
+
+	tests := []struct {
+		nameTest   string
+		policy     EventTimePolicyT
+		eventTime  time.Time
+		wantSkewed bool
+	}{
+		{nameTest: "Not set", policy: policy, eventTime: time.Time{}, wantSkewed: false},
+		{nameTest: "In bounds", policy: policy, eventTime: received.Add(-time.Hour), wantSkewed: false},
+		{nameTest: "Future", policy: policy, eventTime: received.Add(6 * time.Minute), wantSkewed: true},
+		{nameTest: "Past", policy: policy, eventTime: received.Add(-25 * time.Hour), wantSkewed: true},
+		{nameTest: "Zero bounds", policy: EventTimePolicyT{}, eventTime: received.Add(-100 * time.Hour), wantSkewed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			msg := MessageT{EventTime: tt.eventTime}
+			require.NoError(t, tt.policy.Apply(&msg, received))
+			assert.Equal(t, tt.wantSkewed, msg.Skewed)
+		})
+	}
+}
+
+// Test - Messages are ordered and filtered by the event time. SQLite and memory
+func Test_ReadingMessages_EventTime_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 10, 10)
+
+	tests := []struct {
+		nameTest string
+		repo     func(t *testing.T) ActionsDB
+	}{
+		{
+			nameTest: "SQLite",
+			repo: func(t *testing.T) ActionsDB {
+				instAct, err := RepoDB(openTestDB(t), cfg)
+				require.NoError(t, err)
+				return instAct
+			},
+		},
+		{
+			nameTest: "Memory",
+			repo: func(t *testing.T) ActionsDB {
+				return RepoMem(cfg)
+			},
+		},
+	}
+
+	now := time.Now().UTC()
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct := tt.repo(t)
+			require.NoError(t, instAct.Tables())
+
+			// spooled messages are received later than newer ones
+			_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "second", EventTime: now.Add(-time.Hour)})
+			require.NoError(t, err)
+			_, err = instAct.SavingMessage(MessageT{TypeMessage: "E", NameProject: "p", LocationEvent: "l", BodyMessage: "first", EventTime: now.Add(-2 * time.Hour), Skewed: true})
+			require.NoError(t, err)
+			_, err = instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "third"})
+			require.NoError(t, err)
+
+			msgs, err := instAct.ReadingMessages(FilterT{ByEventTime: true})
+			require.NoError(t, err)
+			require.Len(t, msgs, 3)
+			assert.Equal(t, []string{"first", "second", "third"}, []string{msgs[0].BodyMessage, msgs[1].BodyMessage, msgs[2].BodyMessage})
+			assert.True(t, msgs[0].Skewed)
+			assert.False(t, msgs[1].Skewed)
+			assert.Equal(t, now.Add(-2*time.Hour).Truncate(time.Millisecond), msgs[0].EventTime.Truncate(time.Millisecond))
+
+			// the message without the event time is in the range by the time of receiving
+			msgs, err = instAct.ReadingMessages(FilterT{ByEventTime: true, TimeFrom: now.Add(-90 * time.Minute)})
+			require.NoError(t, err)
+			require.Len(t, msgs, 2)
+			assert.Equal(t, "second", msgs[0].BodyMessage)
+			assert.Equal(t, msgs[1].Timestamp, msgs[1].EventTime)
+
+			msgs, err = instAct.ReadingMessages(FilterT{ByEventTime: true, TimeTo: now.Add(-90 * time.Minute)})
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+			assert.Equal(t, "first", msgs[0].BodyMessage)
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Event time out of bounds is rejected
+func Test_EventTimePolicyT_Apply_FAULT(t *testing.T) {
+
+	received := time.Now()
+	policy := EventTimePolicyT{MaxFuture: time.Minute, MaxPast: time.Hour, Reject: true}
+
+	for _, eventTime := range []time.Time{received.Add(2 * time.Minute), received.Add(-2 * time.Hour)} {
+		msg := MessageT{EventTime: eventTime}
+		err := policy.Apply(&msg, received)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+
+		var errField *FieldError
+		require.ErrorAs(t, err, &errField)
+		assert.Equal(t, "eventTime", errField.Field)
+		assert.False(t, msg.Skewed)
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	defaultMarkStart = "<b>"
+	defaultMarkEnd   = "</b>"
+	sizeSnippet      = 16 // words in the snippet
+)
+
+// Full-text search of messages. Fields of FilterT narrow the search as in ReadingMessages
+type SearchT struct {
+	FilterT
+	Query     string // words, "phrases", prefix*, AND, OR, NOT of bodyMessage and locationEvent
+	MarkStart string // before found words in the snippet. Empty - <b>
+	MarkEnd   string // after found words in the snippet. Empty - </b>
+}
+
+// Message found by the search
+type FoundMessageT struct {
+	StoredMessageT
+	Rank    float64 // relevance to the query, greater is better. Compared only inside one result
+	Snippet string  // part of bodyMessage with marked found words
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Search messages in the full-text indexes of log tables. Return messages sorted by relevance, then newest first
+func (o ObjectDB) SearchMessages(search SearchT) ([]FoundMessageT, error) {
+
+	types, err := checkSearch(search)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.checkFtsQuery(search.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := readPartitions(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read catalog: {%v}", err)
+	}
+
+	limit, offset := limitsByFilter(search.FilterT)
+	read := func(parts []PartitionT, limit, offset int) ([]FoundMessageT, error) {
+		return o.searchPartitions(parts, search, limit, offset)
+	}
+	compare := func(a, b FoundMessageT) int {
+		if a.Rank != b.Rank {
+			return cmp.Compare(b.Rank, a.Rank)
+		}
+		return compareMessages(a.StoredMessageT, b.StoredMessageT, b.Timestamp.Compare(a.Timestamp))
+	}
+
+	return queryByBatches(partitionsByFilter(catalog, types, search.FilterT), limit, offset, read, compare)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Search messages in full-text indexes of log tables in one query. Return messages sorted by relevance, then newest first
+func (o ObjectDB) searchPartitions(parts []PartitionT, search SearchT, limit, offset int) ([]FoundMessageT, error) {
+
+	selects := make([]string, 0, len(parts))
+	for _, p := range parts {
+		fts := ftsName(p.NameTable)
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS typeMessage, '%s' AS nameTable, %d AS seq, id, nameProject, locationEvent, bodyMessage, timestamp, "+
+				"COALESCE(eventTime, timestamp) AS eventTime, skewed, attributes, tokenId, score, snippet FROM "+
+				"(SELECT rowid AS ftsId, -bm25(%[4]s) AS score, snippet(%[4]s, 0, :markStart, :markEnd, '...', %[5]d) AS snippet "+
+				"FROM %[4]s WHERE %[4]s MATCH :query) JOIN %[6]s ON id = ftsId%[7]s",
+			p.TypeTable, p.NameTable, p.Seq, fts, sizeSnippet, p.NameTable, whereByFilter(search.FilterT)))
+	}
+
+	q := strings.Join(selects, " UNION ALL ") + " ORDER BY score DESC, timestamp DESC, typeMessage, seq, id LIMIT :limit OFFSET :offset"
+
+	markStart, markEnd := marksBySearch(search)
+	args := append(argsByFilter(search.FilterT),
+		sql.Named("limit", limit),
+		sql.Named("offset", offset),
+		sql.Named("query", search.Query),
+		sql.Named("markStart", markStart),
+		sql.Named("markEnd", markEnd))
+
+	rows, err := o.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fault search messages: {%w}", storageFault(err))
+	}
+	defer rows.Close()
+
+	msgs := []FoundMessageT{}
+	for rows.Next() {
+		var msg FoundMessageT
+		msg.StoredMessageT, err = scanMessage(rows, &msg.Rank, &msg.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault search messages: {%w}", storageFault(err))
+	}
+
+	return msgs, nil
+}
+
+// Check the query of search on the empty full-text index of the connection: the generic SQLITE_ERROR is
+// not allowed syntax of the query - FieldError. So faults of the search by the checked query are faults of the storage
+func (o ObjectDB) checkFtsQuery(query string) error {
+
+	ctx := context.Background()
+	conn, err := o.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("fault check query of search: {%w}", storageFault(err))
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "CREATE VIRTUAL TABLE IF NOT EXISTS temp.fts_query USING fts5(bodyMessage, locationEvent)")
+	if err != nil {
+		return fmt.Errorf("fault check query of search: {%w}", storageFault(err))
+	}
+
+	var n int
+	err = conn.QueryRowContext(ctx, "SELECT count(*) FROM temp.fts_query WHERE fts_query MATCH ?", query).Scan(&n)
+	var errSQLite *sqlite.Error
+	if errors.As(err, &errSQLite) && errSQLite.Code()&0xff == sqlite3.SQLITE_ERROR {
+		return &FieldError{Field: "query", Description: fmt.Sprintf("not allowed query of search: {%v}", err)}
+	}
+	if err != nil {
+		return fmt.Errorf("fault check query of search: {%w}", storageFault(err))
+	}
+
+	return nil
+}
+
+// Name of the full-text index of the log table. It is not matched as a log table by readPartitionsName
+func ftsName(nameTable string) string {
+	return "fts_" + nameTable
+}
+
+// Check create the full-text index of the log table. The index is external: texts are read from the log table
+func checkCreateFtsTable(db queryer, nameTable string) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	q := fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(bodyMessage, locationEvent, content='%s', content_rowid='id')",
+		ftsName(nameTable), nameTable)
+
+	_, err := db.Exec(q)
+	if err != nil {
+		return fmt.Errorf("full-text index of {%s} is not created: %v", nameTable, err)
+	}
+
+	return nil
+}
+
+// Adding the saved message to the full-text index of its log table
+func indexMessage(db queryer, nameTable string, id int64, msg MessageT) error {
+
+	_, err := db.Exec(fmt.Sprintf("INSERT INTO %s (rowid, bodyMessage, locationEvent) VALUES (?, ?, ?)", ftsName(nameTable)),
+		id, msg.BodyMessage, msg.LocationEvent)
+	if err != nil {
+		return fmt.Errorf("fault index the message in {%s}: %w", nameTable, err)
+	}
+
+	return nil
+}
+
+// Check the search. Return types of log tables, error
+func checkSearch(search SearchT) ([]string, error) {
+
+	if strings.TrimSpace(search.Query) == "" {
+		return nil, &FieldError{Field: "query", Description: "empty query of search"}
+	}
+	types, err := typesByFilter(search.TypeMessage)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAttributes(search.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// Marks of found words by the search
+func marksBySearch(search SearchT) (string, string) {
+
+	markStart, markEnd := search.MarkStart, search.MarkEnd
+	if markStart == "" {
+		markStart = defaultMarkStart
+	}
+	if markEnd == "" {
+		markEnd = defaultMarkEnd
+	}
+
+	return markStart, markEnd
+}
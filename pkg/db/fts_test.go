@@ -0,0 +1,186 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Search messages in rotated log tables. SQLite and memory
+func Test_SearchMessages_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(1, 10, 10)
+
+	tests := []struct {
+		nameTest string
+		repo     func(t *testing.T) ActionsDB
+	}{
+		{
+			nameTest: "SQLite",
+			repo: func(t *testing.T) ActionsDB {
+				instAct, err := RepoDB(openTestDB(t), cfg)
+				require.NoError(t, err)
+				return instAct
+			},
+		},
+		{
+			nameTest: "Memory",
+			repo: func(t *testing.T) ActionsDB {
+				return RepoMem(cfg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct := tt.repo(t)
+			require.NoError(t, instAct.Tables())
+
+			_, results, err := instAct.SavingMessages([]MessageT{
+				{TypeMessage: "E", NameProject: "alpha", LocationEvent: "db.go:10", BodyMessage: "dial tcp 10.0.0.1:5432: connection refused"},
+				{TypeMessage: "E", NameProject: "alpha", LocationEvent: "db.go:12", BodyMessage: "query timeout"},
+				{TypeMessage: "E", NameProject: "beta", LocationEvent: "cache.go:7", BodyMessage: "connection refused, connection refused again"},
+				{TypeMessage: "E", NameProject: "beta", LocationEvent: "disk.go:3", BodyMessage: "no space left on device"},
+				{TypeMessage: "E", NameProject: "beta", LocationEvent: "api.go:40", BodyMessage: "context canceled"},
+				{TypeMessage: "E", NameProject: "beta", LocationEvent: "api.go:41", BodyMessage: "out of memory"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: "connection established"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:2", BodyMessage: "connection established again"},
+				{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:3", BodyMessage: "connection established once more"},
+			})
+			require.NoError(t, err)
+			for _, err := range results {
+				require.NoError(t, err)
+			}
+
+			// the more relevant message is first
+			found, err := instAct.SearchMessages(SearchT{Query: "connection refused"})
+			require.NoError(t, err)
+			require.Len(t, found, 2)
+			assert.Equal(t, "beta", found[0].NameProject)
+			assert.Equal(t, "alpha", found[1].NameProject)
+			assert.Greater(t, found[0].Rank, found[1].Rank)
+			assert.Contains(t, found[1].Snippet, "<b>connection</b> <b>refused</b>")
+
+			// rotated log tables are searched
+			found, err = instAct.SearchMessages(SearchT{Query: "established", FilterT: FilterT{TypeMessage: "I"}})
+			require.NoError(t, err)
+			require.Len(t, found, 3)
+			tables := map[string]bool{}
+			for _, msg := range found {
+				tables[msg.NameTable] = true
+			}
+			assert.Equal(t, map[string]bool{"logI_1": true, "logI_2": true}, tables)
+
+			// filter, marks and pagination
+			found, err = instAct.SearchMessages(SearchT{Query: "connection", FilterT: FilterT{NameProject: "alpha"}, MarkStart: "[", MarkEnd: "]"})
+			require.NoError(t, err)
+			require.Len(t, found, 4)
+			assert.Contains(t, found[0].Snippet, "[connection]")
+
+			found, err = instAct.SearchMessages(SearchT{Query: "connection", FilterT: FilterT{Limit: 2, Offset: 4}})
+			require.NoError(t, err)
+			assert.Len(t, found, 1)
+
+			// locationEvent is searched too, the phrase has the dot
+			found, err = instAct.SearchMessages(SearchT{Query: `"cache.go"`, FilterT: FilterT{TypeMessage: "E"}})
+			require.NoError(t, err)
+			require.Len(t, found, 1)
+			assert.Equal(t, "cache.go:7", found[0].LocationEvent)
+
+			found, err = instAct.SearchMessages(SearchT{Query: "panic"})
+			require.NoError(t, err)
+			assert.Len(t, found, 0)
+		})
+	}
+}
+
+// Test - The full-text index is dropped with its log table
+func Test_dropPartition_Fts_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(1, 10, 10)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+	require.NoError(t, savingErr(instAct, MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "one"}))
+	require.NoError(t, savingErr(instAct, MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "two"}))
+
+	_, err = instAct.ApplyRetention(RetentionPolicyT{ByType: map[string]RetentionT{"I": {KeepLast: 1}}}, false)
+	require.NoError(t, err)
+
+	has, err := hasTable(db, ftsName("logI_1"))
+	require.NoError(t, err)
+	assert.False(t, has)
+	has, err = hasTable(db, ftsName("logI_2"))
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Search in more log tables than SELECTs of one compound query: log tables are searched by batches
+func Test_SearchMessages_Batches_SUCCESS(t *testing.T) {
+
+	instAct, err := RepoDB(openTestDB(t), testConfig(1, 1, 1))
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	msgs := make([]MessageT, 1100)
+	for i := range msgs {
+		msgs[i] = MessageT{TypeMessage: "W", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: fmt.Sprintf("timeout %04d", i)}
+	}
+	msgs[700].BodyMessage = "timeout timeout 0700"
+	_, errs, err := instAct.SavingMessages(msgs)
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	found, err := instAct.SearchMessages(SearchT{Query: "timeout", FilterT: FilterT{Limit: maxLimitRead}})
+	require.NoError(t, err)
+	require.Len(t, found, maxLimitRead)
+	assert.Equal(t, "timeout timeout 0700", found[0].BodyMessage, "the most relevant first")
+
+	found, err = instAct.SearchMessages(SearchT{Query: "timeout", FilterT: FilterT{Limit: 10, Offset: 1095}})
+	require.NoError(t, err)
+	assert.Len(t, found, 5)
+}
+
+// Test - Not allowed query of search is InvalidArgument
+func Test_SearchMessages_FAULT(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+	require.NoError(t, savingErr(instAct, MessageT{TypeMessage: "E", NameProject: "p", LocationEvent: "l", BodyMessage: "connection refused"}))
+
+	for _, search := range []SearchT{
+		{Query: " "},
+		{Query: `"connection`},
+		{Query: "connection AND ("},
+		{Query: "connection", FilterT: FilterT{TypeMessage: "T"}},
+	} {
+		_, err := instAct.SearchMessages(search)
+		require.ErrorIs(t, err, ErrInvalidArgument, search.Query)
+	}
+
+	// faults of the storage are not the query: the index is dropped during the search
+	_, err = db.Exec("DROP TABLE " + ftsName("logE_1"))
+	require.NoError(t, err)
+	_, err = instAct.SearchMessages(SearchT{Query: "connection"})
+	require.ErrorContains(t, err, "no such table")
+	assert.NotErrorIs(t, err, ErrInvalidArgument)
+}
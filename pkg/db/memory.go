@@ -0,0 +1,576 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
+)
+
+// Storage of messages in memory, for tests and short runs. Log tables and the catalog
+// behave as in SQLite: each log table has own ids from 1, rotation by MAX_IDNUMB_LOG*.
+// Messages are lost on exit.
+type ObjectMem struct {
+	mu     *sync.RWMutex
+	parts  *[]PartitionT               // catalog, sorted by type and index
+	tables map[string][]StoredMessageT // key - name of log table
+	ids    map[string]time.Time        // time of saving by project and id of message
+	purged *time.Time                  // last removing of expired ids
+	tokens *[]tokenMemT                // sorted by time of creation
+	cfg    ConfigT
+}
+
+// Token with the hash of its secret
+type tokenMemT struct {
+	TokenT
+	hash string
+}
+
+func init() {
+	Register("memory", func(cfg DriverConfigT) (ActionsDB, func() error, error) {
+		return RepoMem(cfg.ConfigT), func() error { return nil }, nil
+	})
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Create the in-memory db object. Return interface.
+func RepoMem(cfg ConfigT) ActionsDB {
+	return &ObjectMem{
+		mu:     &sync.RWMutex{},
+		parts:  &[]PartitionT{},
+		tables: make(map[string][]StoredMessageT),
+		ids:    make(map[string]time.Time),
+		purged: &time.Time{},
+		tokens: &[]tokenMemT{},
+		cfg:    cfg,
+	}
+}
+
+// Working with log tables: the first log table of each type without the active one
+func (o ObjectMem) Tables() error {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, typeTable := range []string{"I", "W", "E"} {
+		if o.active(typeTable) >= 0 {
+			continue
+		}
+		seq := 0
+		for _, p := range *o.parts {
+			if p.TypeTable == typeTable && p.Seq > seq {
+				seq = p.Seq
+			}
+		}
+		o.open(typeTable, seq+1)
+	}
+
+	return nil
+}
+
+// Saving the received message. Return reference, error (ErrDuplicate if the id of message is repeated)
+func (o ObjectMem) SavingMessage(msg MessageT) (RefT, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.purgeIds(window, now)
+	return o.savingUnique(window, now, msg)
+}
+
+// Saving the batch of messages. Return reference and error of each message (nil - saved, ErrDuplicate), error of batch
+func (o ObjectMem) SavingMessages(msgs []MessageT) ([]RefT, []error, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.purgeIds(window, now)
+	refs := make([]RefT, len(msgs))
+	results := make([]error, len(msgs))
+	for i, msg := range msgs {
+		refs[i], results[i] = o.savingUnique(window, now, msg)
+	}
+
+	return refs, results, nil
+}
+
+// Reading messages from all log tables by filter. Return messages sorted by time, error
+func (o ObjectMem) ReadingMessages(filter FilterT) ([]StoredMessageT, error) {
+
+	types, err := typesByFilter(filter.TypeMessage)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAttributes(filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	type foundT struct {
+		msg StoredMessageT
+		seq int
+	}
+	var found []foundT
+	for _, p := range partitionsByFilter(*o.parts, types, filter) {
+		for _, msg := range o.tables[p.NameTable] {
+			if matchFilter(msg, filter) {
+				found = append(found, foundT{msg: msg, seq: p.Seq})
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		switch {
+		case !timeByFilter(a.msg, filter).Equal(timeByFilter(b.msg, filter)):
+			return timeByFilter(a.msg, filter).Before(timeByFilter(b.msg, filter))
+		case a.msg.TypeMessage != b.msg.TypeMessage:
+			return a.msg.TypeMessage < b.msg.TypeMessage
+		case a.seq != b.seq:
+			return a.seq < b.seq
+		default:
+			return a.msg.Id < b.msg.Id
+		}
+	})
+
+	limit, offset := limitsByFilter(filter)
+	msgs := []StoredMessageT{}
+	for i := offset; i < len(found) && len(msgs) < limit; i++ {
+		msgs = append(msgs, found[i].msg)
+	}
+
+	return msgs, nil
+}
+
+// Search messages by words of the query in bodyMessage and locationEvent. All words are found as substrings
+// without case, operators of the query are not supported. Return messages sorted by number of found words, then newest first
+func (o ObjectMem) SearchMessages(search SearchT) ([]FoundMessageT, error) {
+
+	types, err := checkSearch(search)
+	if err != nil {
+		return nil, err
+	}
+	words := searchWords(search.Query)
+	markStart, markEnd := marksBySearch(search)
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	type foundT struct {
+		msg FoundMessageT
+		seq int
+	}
+	var found []foundT
+	for _, p := range partitionsByFilter(*o.parts, types, search.FilterT) {
+		for _, msg := range o.tables[p.NameTable] {
+			if !matchFilter(msg, search.FilterT) {
+				continue
+			}
+			rank := rankWords(msg, words)
+			if rank == 0 {
+				continue
+			}
+			found = append(found, foundT{
+				msg: FoundMessageT{StoredMessageT: msg, Rank: float64(rank), Snippet: markWords(msg.BodyMessage, words, markStart, markEnd)},
+				seq: p.Seq,
+			})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		switch {
+		case a.msg.Rank != b.msg.Rank:
+			return a.msg.Rank > b.msg.Rank
+		case !a.msg.Timestamp.Equal(b.msg.Timestamp):
+			return a.msg.Timestamp.After(b.msg.Timestamp)
+		case a.msg.TypeMessage != b.msg.TypeMessage:
+			return a.msg.TypeMessage < b.msg.TypeMessage
+		case a.seq != b.seq:
+			return a.seq < b.seq
+		default:
+			return a.msg.Id < b.msg.Id
+		}
+	})
+
+	limit, offset := limitsByFilter(search.FilterT)
+	msgs := []FoundMessageT{}
+	for i := offset; i < len(found) && len(msgs) < limit; i++ {
+		msgs = append(msgs, found[i].msg)
+	}
+
+	return msgs, nil
+}
+
+// Reading the catalog of log tables. Return log tables sorted by type and index
+func (o ObjectMem) Partitions() ([]PartitionT, error) {
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return append([]PartitionT{}, *o.parts...), nil
+}
+
+// Removing the expired log tables by policy. Return removed (dryRun - to be removed) log tables, error
+func (o ObjectMem) ApplyRetention(policy RetentionPolicyT, dryRun bool) ([]PartitionT, error) {
+
+	parts, err := o.Partitions()
+	if err != nil {
+		return nil, err
+	}
+
+	return applyRetention(parts, policy, dryRun,
+		func(p PartitionT) error {
+			o.mu.RLock()
+			defer o.mu.RUnlock()
+
+			_, err := writeArchive(policy.DirArchive, p.NameTable, func(enc *json.Encoder) error {
+				for _, msg := range o.tables[p.NameTable] {
+					err := enc.Encode(archiveMessageT{
+						Id:            msg.Id,
+						TypeMessage:   msg.TypeMessage,
+						NameProject:   msg.NameProject,
+						LocationEvent: msg.LocationEvent,
+						BodyMessage:   msg.BodyMessage,
+						Timestamp:     msg.Timestamp.Format(layoutTimestamp),
+						EventTime:     msg.EventTime.Format(layoutEventTime),
+						Skewed:        msg.Skewed,
+						Attributes:    msg.Attributes,
+						TokenId:       msg.TokenId,
+					})
+					if err != nil {
+						return fmt.Errorf("fault write message: %v", err)
+					}
+				}
+				return nil
+			})
+			return err
+		},
+		func(p PartitionT, state string) error {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+
+			for i := range *o.parts {
+				cur := &(*o.parts)[i]
+				if cur.NameTable != p.NameTable {
+					continue
+				}
+				if cur.State != StateClosed {
+					return fmt.Errorf("the {%s} table is not closed and can not be dropped", p.NameTable)
+				}
+				cur.State = state
+				delete(o.tables, p.NameTable)
+				return nil
+			}
+			return fmt.Errorf("the {%s} table is not found", p.NameTable)
+		})
+}
+
+// Creating the token. Return created token, secret, error
+func (o ObjectMem) CreateToken(token TokenT) (TokenT, string, error) {
+
+	token, secret, hash, err := newToken(token)
+	if err != nil {
+		return TokenT{}, "", err
+	}
+	token.Projects = slices.Clone(token.Projects)
+	token.Operations = slices.Clone(token.Operations)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	*o.tokens = append(*o.tokens, tokenMemT{TokenT: token, hash: hash})
+
+	return cloneToken(token), secret, nil
+}
+
+// Reading all tokens. Return tokens sorted by time of creation, error
+func (o ObjectMem) ListTokens() ([]TokenT, error) {
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	tokens := make([]TokenT, 0, len(*o.tokens))
+	for _, t := range *o.tokens {
+		tokens = append(tokens, cloneToken(t.TokenT))
+	}
+
+	return tokens, nil
+}
+
+// Revoking the token by id. Return the token, error (ErrNotFound)
+func (o ObjectMem) RevokeToken(id string) (TokenT, error) {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range *o.tokens {
+		t := &(*o.tokens)[i]
+		if t.Id != id {
+			continue
+		}
+		if t.TimeRevoke.IsZero() {
+			t.TimeRevoke = time.Now().UTC().Truncate(time.Millisecond)
+		}
+		return cloneToken(t.TokenT), nil
+	}
+
+	return TokenT{}, fmt.Errorf("token {%s}: {%w}", id, ErrNotFound)
+}
+
+// Reading the token by the secret. Revoked and expired tokens are returned too. Return token, error (ErrNotFound)
+func (o ObjectMem) TokenBySecret(secret string) (TokenT, error) {
+
+	hash := hashSecret(secret)
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, t := range *o.tokens {
+		if t.hash == hash {
+			return cloneToken(t.TokenT), nil
+		}
+	}
+
+	return TokenT{}, fmt.Errorf("token: {%w}", ErrNotFound)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Copy of the token with own slices
+func cloneToken(t TokenT) TokenT {
+	t.Projects = slices.Clone(t.Projects)
+	t.Operations = slices.Clone(t.Operations)
+	return t
+}
+
+// Saving the message if its id is not saved within the window. Return reference, error (ErrDuplicate). Under the lock
+func (o ObjectMem) savingUnique(window time.Duration, now time.Time, msg MessageT) (RefT, error) {
+
+	err := checkMessageId(msg.MessageId)
+	if err != nil {
+		return RefT{}, err
+	}
+	if msg.MessageId == "" || window == 0 {
+		return o.saving(msg)
+	}
+
+	key := msg.NameProject + "\x00" + msg.MessageId
+	if t, ok := o.ids[key]; ok && !t.Before(now.Add(-window)) {
+		return RefT{}, ErrDuplicate
+	}
+
+	ref, err := o.saving(msg)
+	if err != nil {
+		return RefT{}, err
+	}
+	o.ids[key] = now
+
+	return ref, nil
+}
+
+// Removing expired ids, not often than once per window. Under the lock
+func (o ObjectMem) purgeIds(window time.Duration, now time.Time) {
+	if window == 0 || now.Sub(*o.purged) < window {
+		return
+	}
+
+	for key, t := range o.ids {
+		if t.Before(now.Add(-window)) {
+			delete(o.ids, key)
+		}
+	}
+	*o.purged = now
+}
+
+// Saving the message in the current log table of its type. The log table is changed when overloaded.
+// Return reference, error. Under the lock
+func (o ObjectMem) saving(msg MessageT) (RefT, error) {
+
+	err := checkMessage(msg)
+	if err != nil {
+		return RefT{}, err
+	}
+
+	i := o.active(msg.TypeMessage)
+	if i < 0 {
+		return RefT{}, fmt.Errorf("fault read the current {%s} table: no active table", msg.TypeMessage)
+	}
+	p := &(*o.parts)[i]
+
+	// check before saving, so the fault message is not stored
+	id := p.LastId + 1
+	over, err := checkOverloadLogTable(msg.TypeMessage, o.cfg, id)
+	if err != nil {
+		return RefT{}, fmt.Errorf("fault check overload {%s} table: {%v}", msg.TypeMessage, err)
+	}
+
+	ts := time.Now().UTC()
+	// the time of receiving is read as the missed event time
+	if msg.EventTime.IsZero() {
+		msg.EventTime = ts
+	}
+	msg.EventTime = msg.EventTime.UTC()
+	msg.Attributes = maps.Clone(msg.Attributes)
+	o.tables[p.NameTable] = append(o.tables[p.NameTable], StoredMessageT{MessageT: msg, Id: id, NameTable: p.NameTable, Timestamp: ts})
+	if p.FirstId == 0 {
+		p.FirstId = id
+		p.FirstTime = ts
+	}
+	p.LastId = id
+	p.LastTime = ts
+	p.RowCount++
+	ref := RefT{NameTable: p.NameTable, Id: id}
+
+	if over {
+		p.State = StateClosed
+		p.TimeClose = ts
+		o.open(p.TypeTable, p.Seq+1)
+		metrics.Rotated(p.TypeTable)
+	}
+
+	return ref, nil
+}
+
+// Index of the active log table of the type in the catalog. -1 - not found
+func (o ObjectMem) active(typeTable string) int {
+	for i, p := range *o.parts {
+		if p.TypeTable == typeTable && p.State == StateActive {
+			return i
+		}
+	}
+	return -1
+}
+
+// Adding the new log table to the catalog as active
+func (o ObjectMem) open(typeTable string, seq int) {
+
+	name := fmt.Sprintf("log%s_%d", typeTable, seq)
+	*o.parts = append(*o.parts, PartitionT{
+		TypeTable: typeTable,
+		NameTable: name,
+		Seq:       seq,
+		State:     StateActive,
+		TimeOpen:  time.Now().UTC(),
+	})
+	o.tables[name] = []StoredMessageT{}
+
+	sort.SliceStable(*o.parts, func(i, j int) bool {
+		a, b := (*o.parts)[i], (*o.parts)[j]
+		if a.TypeTable != b.TypeTable {
+			return a.TypeTable < b.TypeTable
+		}
+		return a.Seq < b.Seq
+	})
+}
+
+// Check the message by filter, without type
+func matchFilter(msg StoredMessageT, filter FilterT) bool {
+
+	if filter.NameProject != "" && msg.NameProject != filter.NameProject {
+		return false
+	}
+	if filter.LocationEvent != "" && msg.LocationEvent != filter.LocationEvent {
+		return false
+	}
+	if filter.BodySubstr != "" && !strings.Contains(msg.BodyMessage, filter.BodySubstr) {
+		return false
+	}
+	if !matchAttributes(msg.Attributes, filter.Attributes) {
+		return false
+	}
+	if !filter.TimeFrom.IsZero() && timeByFilter(msg, filter).Before(filter.TimeFrom) {
+		return false
+	}
+	if !filter.TimeTo.IsZero() && timeByFilter(msg, filter).After(filter.TimeTo) {
+		return false
+	}
+
+	return true
+}
+
+// Words of the query in lower case, without quotes, prefix marks and operators
+func searchWords(query string) []string {
+
+	var words []string
+	for _, w := range strings.Fields(query) {
+		switch w {
+		case "AND", "OR", "NOT":
+			continue
+		}
+		w = strings.ToLower(strings.Trim(w, `"*()`))
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+
+	return words
+}
+
+// Number of found words in the message. 0 - some word is not found
+func rankWords(msg StoredMessageT, words []string) int {
+
+	text := strings.ToLower(msg.BodyMessage + " " + msg.LocationEvent)
+	rank := 0
+	for _, w := range words {
+		n := strings.Count(text, w)
+		if n == 0 {
+			return 0
+		}
+		rank += n
+	}
+
+	return rank
+}
+
+// Text with marked words
+func markWords(text string, words []string, markStart, markEnd string) string {
+
+	lower := strings.ToLower(text)
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		size := 0
+		for _, w := range words {
+			if strings.HasPrefix(lower[i:], w) && len(w) > size {
+				size = len(w)
+			}
+		}
+		if size == 0 {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString(markStart + text[i:i+size] + markEnd)
+		i += size
+	}
+
+	return b.String()
+}
+
+// Time of the message for ordering and time range: the event time or the time of receiving
+func timeByFilter(msg StoredMessageT, filter FilterT) time.Time {
+	if filter.ByEventTime {
+		return msg.EventTime
+	}
+	return msg.Timestamp
+}
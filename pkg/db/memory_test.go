@@ -0,0 +1,134 @@
+package db
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Saving, rotation, reading and retention in memory
+func Test_ObjectMem_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 2, 2)
+
+	instAct := RepoMem(cfg)
+	require.NoError(t, instAct.Tables())
+	require.NoError(t, instAct.Tables())
+
+	// the same layout as in SQLite: logI_1 holds 3 messages
+	for _, body := range []string{"one", "two", "three", "four", "connection refused"} {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: body})
+		require.NoError(t, err)
+	}
+	refs, results, err := instAct.SavingMessages([]MessageT{
+		{TypeMessage: "E", NameProject: "beta", LocationEvent: "main.go:2", BodyMessage: "connection refused"},
+		{TypeMessage: "T", NameProject: "beta", LocationEvent: "main.go:2", BodyMessage: "test"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, RefT{NameTable: "logE_1", Id: 1}, refs[0])
+	assert.NoError(t, results[0])
+	assert.Error(t, results[1])
+
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "I"})
+	require.NoError(t, err)
+	tables := []string{}
+	for _, msg := range msgs {
+		tables = append(tables, msg.NameTable)
+	}
+	assert.Equal(t, []string{"logI_1", "logI_1", "logI_1", "logI_2", "logI_2"}, tables)
+
+	msgs, err = instAct.ReadingMessages(FilterT{BodySubstr: "refused", NameProject: "beta"})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "logE_1", msgs[0].NameTable)
+
+	msgs, err = instAct.ReadingMessages(FilterT{TypeMessage: "I", Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "two", msgs[0].BodyMessage)
+
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	assert.Equal(t, StateClosed, parts[1].State)
+	assert.Equal(t, int64(3), parts[1].RowCount)
+
+	// retention with archive
+	dir := t.TempDir()
+	removed, err := instAct.ApplyRetention(RetentionPolicyT{ByType: map[string]RetentionT{"I": {KeepLast: 1}}, DirArchive: dir}, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, StateArchived, removed[0].State)
+
+	files, err := filepath.Glob(filepath.Join(dir, "logI_1_*.jsonl.gz"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	msgs, err = instAct.ReadingMessages(FilterT{TypeMessage: "I"})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 2)
+}
+
+// Test - Saving messages from several goroutines
+func Test_ObjectMem_Concurrent_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	instAct := RepoMem(cfg)
+	require.NoError(t, instAct.Tables())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := instAct.SavingMessage(MessageT{TypeMessage: "W", NameProject: "p", LocationEvent: "l", BodyMessage: "b"})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	var total int64
+	for _, p := range parts {
+		if p.TypeTable == "W" {
+			total += p.RowCount
+		}
+	}
+	assert.Equal(t, int64(400), total)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Saving the message. Tables are not created, MAX is not set
+func Test_ObjectMem_FAULT(t *testing.T) {
+
+	instAct := RepoMem(ConfigT{})
+	msg := MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: "b"}
+
+	_, err := instAct.SavingMessage(msg)
+	require.Error(t, err)
+
+	require.NoError(t, instAct.Tables())
+	_, err = instAct.SavingMessage(msg)
+	require.Error(t, err)
+
+	_, err = instAct.ReadingMessages(FilterT{TypeMessage: "T"})
+	require.Error(t, err)
+}
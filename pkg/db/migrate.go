@@ -0,0 +1,227 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Migration of the schema from the schema_version table
+type MigrationT struct {
+	Version int
+	Name    string
+	Tables  []string // stored log tables changed by the migration
+}
+
+// Storage with versioned migrations of the schema
+type MigratorDB interface {
+	Migrate(dryRun bool) ([]MigrationT, error)
+}
+
+// Step of the schema. Steps are applied once in order of versions. Steps are idempotent:
+// databases of versions without the schema_version table already have a part of changes
+type migrationT struct {
+	version  int
+	name     string
+	apply    func(db queryer) error                   // change of the schema. nil - none
+	applyLog func(db queryer, nameTable string) error // change of each stored log table. nil - none
+}
+
+// Migrations of the SQLite schema. New log tables are created by checkCreateLogTable and checkCreateFtsTable
+// with the schema of the last version, so a change of log tables is added to them too
+var migrations = []migrationT{
+	{version: 1, name: "catalog of log tables", apply: migrateCatalog},
+	{version: 2, name: "ids of messages", apply: checkCreateMessageIdsTable},
+	{version: 3, name: "event time of messages", applyLog: addColumnsLog("eventTime TEXT", "skewed INTEGER NOT NULL DEFAULT 0")},
+	{version: 4, name: "attributes of messages", applyLog: addColumnsLog("attributes TEXT")},
+	{version: 5, name: "full-text indexes", applyLog: fillFtsTable},
+	{version: 6, name: "tokens of clients", apply: checkCreateTokensTable, applyLog: addColumnsLog("tokenId TEXT")},
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Applying the pending migrations of the schema in one transaction.
+// Return applied (dryRun - pending, applied and rolled back) migrations, error
+func (o ObjectDB) Migrate(dryRun bool) ([]MigrationT, error) {
+
+	o.muWrite.Lock()
+	defer o.muWrite.Unlock()
+
+	tx, err := o.DB.Begin()
+	if err != nil {
+		return nil, storageFault(fmt.Errorf("fault begin transaction: {%w}", err))
+	}
+	defer tx.Rollback()
+
+	applied, err := migrate(tx, migrations)
+	if err != nil {
+		return nil, storageFault(err)
+	}
+	if dryRun {
+		return applied, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, storageFault(fmt.Errorf("fault commit transaction: {%w}", err))
+	}
+
+	return applied, nil
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Applying migrations of the list newer than the version of the database. Return applied migrations, error
+func migrate(db queryer, list []migrationT) ([]MigrationT, error) {
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	timeApplied TEXT DEFAULT CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("fault create the schema_version table: %v", err)
+	}
+
+	var current int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current)
+	if err != nil {
+		return nil, fmt.Errorf("fault read version of the schema: %v", err)
+	}
+	if last := list[len(list)-1].version; current > last {
+		return nil, fmt.Errorf("version of the schema {%d} is newer than supported {%d}", current, last)
+	}
+
+	applied := []MigrationT{}
+	for _, m := range list {
+		if m.version <= current {
+			continue
+		}
+
+		tables, err := applyMigration(db, m)
+		if err != nil {
+			return nil, fmt.Errorf("fault migration {%d} {%s}: {%v}", m.version, m.name, err)
+		}
+
+		_, err = db.Exec("INSERT INTO schema_version (version, name) VALUES (?, ?)", m.version, m.name)
+		if err != nil {
+			return nil, fmt.Errorf("fault save version {%d} of the schema: %v", m.version, err)
+		}
+		applied = append(applied, MigrationT{Version: m.version, Name: m.name, Tables: tables})
+	}
+
+	return applied, nil
+}
+
+// Applying the migration to the schema, then to each stored log table. Return changed log tables, error
+func applyMigration(db queryer, m migrationT) ([]string, error) {
+
+	if m.apply != nil {
+		err := m.apply(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.applyLog == nil {
+		return nil, nil
+	}
+
+	names, err := storedLogTables(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		err := m.applyLog(db, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return names, nil
+}
+
+// Names of stored log tables of the catalog: active and closed tables which exist.
+// The active log table of a new catalog is created later
+func storedLogTables(db queryer) ([]string, error) {
+
+	rows, err := db.Query("SELECT nameTable FROM partitions WHERE state IN (?, ?) ORDER BY typeTable, seq", StateActive, StateClosed)
+	if err != nil {
+		return nil, fmt.Errorf("fault read stored log tables: %v", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("fault scan name of log table: %v", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: %v", err)
+	}
+
+	stored := []string{}
+	for _, name := range names {
+		exists, err := hasTable(db, name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			stored = append(stored, name)
+		}
+	}
+
+	return stored, nil
+}
+
+// Adding missed columns to the log table. defs - definitions of columns: "name TYPE ..."
+func addColumnsLog(defs ...string) func(db queryer, nameTable string) error {
+	return func(db queryer, nameTable string) error {
+
+		for _, def := range defs {
+			column, _, _ := strings.Cut(def, " ")
+			has, err := hasColumn(db, nameTable, column)
+			if err != nil {
+				return err
+			}
+			if has {
+				continue
+			}
+			_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", nameTable, def))
+			if err != nil {
+				return fmt.Errorf("fault add column {%s} to the {%s} table: %v", def, nameTable, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// Check-create the full-text index of the log table. The missed index is filled from the log table
+func fillFtsTable(db queryer, nameTable string) error {
+
+	has, err := hasTable(db, ftsName(nameTable))
+	if err != nil {
+		return err
+	}
+	if has {
+		return nil
+	}
+
+	err = checkCreateFtsTable(db, nameTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("INSERT INTO %[1]s (%[1]s) VALUES ('rebuild')", ftsName(nameTable)))
+	if err != nil {
+		return fmt.Errorf("fault fill full-text index of {%s}: %v", nameTable, err)
+	}
+
+	return nil
+}
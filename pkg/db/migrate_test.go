@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create the database of versions without the schema_version table: the catalog, ids of messages,
+// log tables with full-text indexes and saved messages
+func createUnversionedDB(t *testing.T, db *sql.DB, cfg ConfigT) ActionsDB {
+	t.Helper()
+
+	require.NoError(t, checkCreatePartitionsTable(db))
+	require.NoError(t, checkCreateMessageIdsTable(db))
+	require.NoError(t, initPartitions(db))
+	for _, name := range []string{"logI_1", "logW_1", "logE_1"} {
+		require.NoError(t, checkCreateLogTable(db, name))
+		require.NoError(t, checkCreateFtsTable(db, name))
+	}
+
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	for _, body := range []string{"disk is full", "disk is cleaned", "cache is warm"} {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l", BodyMessage: body})
+		require.NoError(t, err)
+	}
+
+	return instAct
+}
+
+// Version of the schema of database. 0 - no schema_version table
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	has, err := hasTable(db, "schema_version")
+	require.NoError(t, err)
+	if !has {
+		return 0
+	}
+	var version int
+	require.NoError(t, db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version))
+
+	return version
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Upgrade of the database built without versions of the schema. Dry run does not change it
+func Test_Migrate_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 10, 10)
+
+	db := openTestDB(t)
+	instAct := createUnversionedDB(t, db, cfg)
+	migrator, ok := instAct.(MigratorDB)
+	require.True(t, ok)
+
+	pending, err := migrator.Migrate(true)
+	require.NoError(t, err)
+	require.Len(t, pending, len(migrations))
+	for i, m := range pending {
+		assert.Equal(t, migrations[i].version, m.Version)
+		assert.Equal(t, migrations[i].name, m.Name)
+	}
+	assert.Nil(t, pending[0].Tables)
+	assert.Equal(t, []string{"logE_1", "logI_1", "logI_2", "logW_1"}, pending[4].Tables)
+	assert.Equal(t, 0, schemaVersion(t, db), "dry run is rolled back")
+
+	require.NoError(t, instAct.Tables())
+	assert.Equal(t, migrations[len(migrations)-1].version, schemaVersion(t, db))
+
+	// messages of the previous version are read and found once
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "I"})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 3)
+	found, err := instAct.SearchMessages(SearchT{Query: "disk", FilterT: FilterT{TypeMessage: "I"}})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	// the last version: nothing to apply
+	pending, err = migrator.Migrate(true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	require.NoError(t, instAct.Tables())
+	assert.Equal(t, migrations[len(migrations)-1].version, schemaVersion(t, db))
+}
+
+// Test - Dry run of migration of the database of v0.0.6: the main table is kept
+func Test_Migrate_DryRun_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+
+	createOldMainTable(t, db, "logI_2", "logW_1", "logE_1")
+	createOldLogTable(t, db, "logI_1", "2025-01-01 10:00:00")
+	createOldLogTable(t, db, "logI_2", "2025-01-02 10:00:00")
+	createOldLogTable(t, db, "logW_1")
+	createOldLogTable(t, db, "logE_1")
+
+	instAct, err := RepoDB(db, testConfig(10, 10, 10))
+	require.NoError(t, err)
+
+	pending, err := instAct.(MigratorDB).Migrate(true)
+	require.NoError(t, err)
+	require.Len(t, pending, len(migrations))
+	assert.Equal(t, []string{"logE_1", "logI_1", "logI_2", "logW_1"}, pending[2].Tables)
+
+	for _, name := range []string{"main", "logI_1"} {
+		has, err := hasTable(db, name)
+		require.NoError(t, err)
+		assert.True(t, has, name)
+	}
+	for _, name := range []string{"partitions", "messageIds", "fts_logI_1", "schema_version"} {
+		has, err := hasTable(db, name)
+		require.NoError(t, err)
+		assert.False(t, has, name)
+	}
+}
+
+// Test - Applying migrations newer than the version of the database
+func Test_migrate_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+
+	var calls []string
+	list := []migrationT{
+		{version: 1, name: "one", apply: func(db queryer) error {
+			calls = append(calls, "one")
+			_, err := db.Exec("CREATE TABLE one (id INTEGER)")
+			return err
+		}},
+		{version: 2, name: "two", apply: func(db queryer) error {
+			calls = append(calls, "two")
+			return nil
+		}},
+	}
+
+	applied, err := migrate(db, list[:1])
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, 1, schemaVersion(t, db))
+
+	applied, err = migrate(db, list)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, MigrationT{Version: 2, Name: "two"}, applied[0])
+	assert.Equal(t, []string{"one", "two"}, calls)
+	assert.Equal(t, 2, schemaVersion(t, db))
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Migrations of the schema. Return error
+func Test_migrate_FAULT(t *testing.T) {
+
+	list := []migrationT{
+		{version: 1, name: "one", apply: func(db queryer) error { return nil }},
+		{version: 2, name: "two", apply: func(db queryer) error { return errors.New("broken") }},
+	}
+
+	tests := []struct {
+		nameTest string
+		version  int // version of the database before migration
+		wantErr  string
+		wantVer  int // version of the database after migration
+	}{
+		{
+			nameTest: "Fault migration is not saved",
+			version:  0,
+			wantErr:  "fault migration {2} {two}: {broken}",
+			wantVer:  1,
+		},
+		{
+			nameTest: "Schema is newer than supported",
+			version:  3,
+			wantErr:  "version of the schema {3} is newer than supported {2}",
+			wantVer:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			db := openTestDB(t)
+			if tt.version != 0 {
+				_, err := migrate(db, []migrationT{{version: tt.version, name: "new"}})
+				require.NoError(t, err)
+			}
+
+			_, err := migrate(db, list)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, tt.wantVer, schemaVersion(t, db))
+		})
+	}
+}
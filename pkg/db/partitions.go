@@ -0,0 +1,420 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// State of log table in the catalog
+const (
+	StateActive   = "active"   // the current log table of its type
+	StateClosed   = "closed"   // overloaded, not written
+	StateArchived = "archived" // exported to the archive and dropped
+	StateDropped  = "dropped"  // dropped without export
+)
+
+// Log table (partition) from the catalog
+type PartitionT struct {
+	TypeTable string // I, W, E
+	NameTable string
+	Seq       int // index in the name of log table
+	State     string
+	FirstId   int64 // 0 - no messages
+	LastId    int64
+	FirstTime time.Time // time of the first message. Zero - no messages
+	LastTime  time.Time
+	RowCount  int64
+	TimeOpen  time.Time
+	TimeClose time.Time // zero - the current log table
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Reading the catalog of log tables. Return log tables sorted by type and index
+func (o ObjectDB) Partitions() ([]PartitionT, error) {
+	return readPartitions(o.DB)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Check-create the catalog of log tables
+func checkCreatePartitionsTable(db queryer) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS partitions (
+	nameTable string PRIMARY KEY,
+	typeTable string NOT NULL,
+	seq INTEGER NOT NULL,
+	firstId INTEGER,
+	lastId INTEGER,
+	firstTime TEXT,
+	lastTime TEXT,
+	rowCount INTEGER,
+	state string NOT NULL DEFAULT 'active',
+	timeOpen TEXT DEFAULT CURRENT_TIMESTAMP,
+	timeClose TEXT);
+	`)
+	if err != nil {
+		return fmt.Errorf("fault create the partitions table: %v", err)
+	}
+
+	return nil
+}
+
+// Check-create the catalog with migration of previous layouts: the registry without state and the main table.
+// Only one log table of each type can be active.
+func migrateCatalog(db queryer) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	err := checkCreatePartitionsTable(db)
+	if err != nil {
+		return err
+	}
+
+	// Registry without state and statistics
+	has, err := hasColumn(db, "partitions", "state")
+	if err != nil {
+		return err
+	}
+	if !has {
+		for _, col := range []string{"firstId INTEGER", "lastId INTEGER", "firstTime TEXT", "lastTime TEXT", "rowCount INTEGER", "state string NOT NULL DEFAULT 'active'"} {
+			_, err := db.Exec("ALTER TABLE partitions ADD COLUMN " + col)
+			if err != nil {
+				return fmt.Errorf("fault add column {%s} to the partitions table: %v", col, err)
+			}
+		}
+		_, err = db.Exec("UPDATE partitions SET state = CASE WHEN timeClose IS NULL THEN ? ELSE ? END", StateActive, StateClosed)
+		if err != nil {
+			return fmt.Errorf("fault set state of log tables: %v", err)
+		}
+	}
+
+	// Main table
+	has, err = hasTable(db, "main")
+	if err != nil {
+		return err
+	}
+	if has {
+		var nameI, nameW, nameE string
+		err := db.QueryRow("SELECT nameTableI, nameTableW, nameTableE FROM main WHERE id = 1").Scan(&nameI, &nameW, &nameE)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("fault reading log table names from the main table: {%v}", err)
+		}
+
+		if err == nil {
+			for _, cur := range []struct{ typeTable, name string }{{"I", nameI}, {"W", nameW}, {"E", nameE}} {
+				err := catalogLogTables(db, cur.typeTable, cur.name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = db.Exec("DROP TABLE main")
+		if err != nil {
+			return fmt.Errorf("fault drop the main table: %v", err)
+		}
+	}
+
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS partitions_active ON partitions (typeTable) WHERE state = 'active'")
+	if err != nil {
+		return fmt.Errorf("fault create index of active log tables: %v", err)
+	}
+
+	return refreshPartitionsStats(db)
+}
+
+// Adding log tables of the type from the schema to the catalog. The current log table is active
+func catalogLogTables(db queryer, typeTable, current string) error {
+
+	names, err := readPartitionsName(db, typeTable)
+	if err != nil {
+		return fmt.Errorf("fault read log tables of {%s}: {%v}", typeTable, err)
+	}
+
+	for _, name := range names {
+		_, err := db.Exec(`INSERT OR IGNORE INTO partitions (nameTable, typeTable, seq, state, timeClose) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			name, typeTable, indexFromName(name), StateClosed)
+		if err != nil {
+			return fmt.Errorf("fault add {%s} to the catalog: %v", name, err)
+		}
+	}
+
+	_, err = db.Exec(`UPDATE partitions SET
+	state = CASE WHEN nameTable = :current THEN :active ELSE :closed END,
+	timeClose = CASE WHEN nameTable = :current THEN NULL ELSE COALESCE(timeClose, CURRENT_TIMESTAMP) END
+	WHERE typeTable = :type AND state IN (:active, :closed)`,
+		sql.Named("current", current),
+		sql.Named("active", StateActive),
+		sql.Named("closed", StateClosed),
+		sql.Named("type", typeTable))
+	if err != nil {
+		return fmt.Errorf("fault set the current {%s} table: %v", typeTable, err)
+	}
+
+	return nil
+}
+
+// Calculation of statistics for log tables without them. Time range of closed log table is taken from messages
+func refreshPartitionsStats(db queryer) error {
+
+	rows, err := db.Query("SELECT nameTable FROM partitions WHERE rowCount IS NULL AND state IN (?, ?)", StateActive, StateClosed)
+	if err != nil {
+		return fmt.Errorf("fault read log tables without statistics: %v", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("fault scan log table name: {%v}", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	for _, name := range names {
+
+		has, err := hasTable(db, name)
+		if err != nil {
+			return err
+		}
+		if !has {
+			_, err := db.Exec("UPDATE partitions SET state = ?, rowCount = 0 WHERE nameTable = ?", StateDropped, name)
+			if err != nil {
+				return fmt.Errorf("fault set state of {%s}: %v", name, err)
+			}
+			continue
+		}
+
+		q := fmt.Sprintf(`UPDATE partitions SET
+		(firstId, lastId, firstTime, lastTime, rowCount) = (SELECT MIN(id), MAX(id), MIN(timestamp), MAX(timestamp), COUNT(*) FROM %s)
+		WHERE nameTable = ?`, name)
+		_, err = db.Exec(q, name)
+		if err != nil {
+			return fmt.Errorf("fault calculate statistics of {%s}: %v", name, err)
+		}
+
+		_, err = db.Exec(`UPDATE partitions SET
+		timeOpen = COALESCE(firstTime, timeOpen),
+		timeClose = CASE WHEN state = ? THEN COALESCE(lastTime, timeClose) ELSE timeClose END
+		WHERE nameTable = ?`, StateClosed, name)
+		if err != nil {
+			return fmt.Errorf("fault set time range of {%s}: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Initialisation of the catalog: the first log table of each type without the active one
+func initPartitions(db queryer) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	for _, typeTable := range []string{"I", "W", "E"} {
+		var (
+			active int
+			seq    int
+		)
+		err := db.QueryRow("SELECT COUNT(*) FILTER (WHERE state = ?), COALESCE(MAX(seq), 0) FROM partitions WHERE typeTable = ?",
+			StateActive, typeTable).Scan(&active, &seq)
+		if err != nil {
+			return fmt.Errorf("fault read the catalog of {%s}: %v", typeTable, err)
+		}
+		if active != 0 {
+			continue
+		}
+
+		err = openPartition(db, typeTable, fmt.Sprintf("log%s_%d", typeTable, seq+1), seq+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Adding the new log table to the catalog as active
+func openPartition(db queryer, typeTable, name string, seq int) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	_, err := db.Exec("INSERT INTO partitions (nameTable, typeTable, seq, rowCount, state) VALUES (?, ?, ?, 0, ?)",
+		name, typeTable, seq, StateActive)
+	if err != nil {
+		return fmt.Errorf("fault open {%s} table: %v", name, err)
+	}
+
+	return nil
+}
+
+// Close the active log table in the catalog
+func closePartition(db queryer, name string) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	res, err := db.Exec("UPDATE partitions SET state = ?, timeClose = CURRENT_TIMESTAMP WHERE nameTable = ? AND state = ?",
+		StateClosed, name, StateActive)
+	if err != nil {
+		return fmt.Errorf("fault close {%s} table: %v", name, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error get RowsAffected after update: {%v}", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("the {%s} table is not active", name)
+	}
+
+	return nil
+}
+
+// Update statistics of the log table after saving the message with id
+func updatePartitionStats(db queryer, name string, id int64) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	q := fmt.Sprintf(`UPDATE partitions SET
+	firstId = COALESCE(firstId, :id),
+	lastId = :id,
+	firstTime = COALESCE(firstTime, (SELECT timestamp FROM %[1]s WHERE id = :id)),
+	lastTime = (SELECT timestamp FROM %[1]s WHERE id = :id),
+	rowCount = COALESCE(rowCount, 0) + 1
+	WHERE nameTable = :name`, name)
+
+	_, err := db.Exec(q, sql.Named("id", id), sql.Named("name", name))
+	if err != nil {
+		return fmt.Errorf("fault update statistics of {%s}: %v", name, err)
+	}
+
+	return nil
+}
+
+// Reading names of the active log tables from the catalog
+func readLogTablesName(db queryer) (nameI, nameW, nameE string, err error) {
+	if db == nil {
+		return "", "", "", errors.New("missed db pointer")
+	}
+
+	rows, err := db.Query("SELECT typeTable, nameTable FROM partitions WHERE state = ?", StateActive)
+	if err != nil {
+		return "", "", "", fmt.Errorf("fault reading log table names from the catalog: {%v}", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var typeTable, name string
+		if err := rows.Scan(&typeTable, &name); err != nil {
+			return "", "", "", fmt.Errorf("fault scan log table name: {%v}", err)
+		}
+		switch typeTable {
+		case "I":
+			nameI = name
+		case "W":
+			nameW = name
+		case "E":
+			nameE = name
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", "", fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	if nameI == "" || nameW == "" || nameE == "" {
+		return "", "", "", sql.ErrNoRows
+	}
+
+	return nameI, nameW, nameE, nil
+}
+
+// Reading the catalog of log tables
+func readPartitions(db queryer) ([]PartitionT, error) {
+	if db == nil {
+		return nil, errors.New("missed db pointer")
+	}
+
+	rows, err := db.Query(`SELECT nameTable, typeTable, seq, state,
+	COALESCE(firstId, 0), COALESCE(lastId, 0), COALESCE(firstTime, ''), COALESCE(lastTime, ''), COALESCE(rowCount, 0),
+	COALESCE(timeOpen, ''), COALESCE(timeClose, '')
+	FROM partitions ORDER BY typeTable, seq`)
+	if err != nil {
+		return nil, fmt.Errorf("fault read the partitions table: {%v}", err)
+	}
+	defer rows.Close()
+
+	parts := []PartitionT{}
+	for rows.Next() {
+		var (
+			p                                        PartitionT
+			firstTime, lastTime, timeOpen, timeClose string
+		)
+		err := rows.Scan(&p.NameTable, &p.TypeTable, &p.Seq, &p.State,
+			&p.FirstId, &p.LastId, &firstTime, &lastTime, &p.RowCount, &timeOpen, &timeClose)
+		if err != nil {
+			return nil, fmt.Errorf("fault scan partition: {%v}", err)
+		}
+
+		for _, t := range []struct {
+			dst *time.Time
+			src string
+		}{{&p.FirstTime, firstTime}, {&p.LastTime, lastTime}, {&p.TimeOpen, timeOpen}, {&p.TimeClose, timeClose}} {
+			if t.src == "" {
+				continue
+			}
+			*t.dst, err = time.Parse(layoutTimestamp, t.src)
+			if err != nil {
+				return nil, fmt.Errorf("fault parse time {%s} of {%s}: {%v}", t.src, p.NameTable, err)
+			}
+		}
+		parts = append(parts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return parts, nil
+}
+
+// Check the table exists
+func hasTable(db queryer, name string) (bool, error) {
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("fault check table {%s}: %v", name, err)
+	}
+
+	return n != 0, nil
+}
+
+// Check the column of table exists
+func hasColumn(db queryer, table, column string) (bool, error) {
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("fault check column {%s.%s}: %v", table, column, err)
+	}
+
+	return n != 0, nil
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create log table of previous versions with messages at the times
+func createOldLogTable(t *testing.T, db *sql.DB, name string, times ...string) {
+	t.Helper()
+
+	require.NoError(t, checkCreateLogTable(db, name))
+	for _, ts := range times {
+		_, err := db.Exec(fmt.Sprintf("INSERT INTO %s (nameProject, locationEvent, bodyMessage, timestamp) VALUES ('p', 'l', 'b', ?)", name), ts)
+		require.NoError(t, err)
+	}
+}
+
+// Create the main table of previous versions
+func createOldMainTable(t *testing.T, db *sql.DB, nameI, nameW, nameE string) {
+	t.Helper()
+
+	_, err := db.Exec(`
+	CREATE TABLE main (
+	id INTEGER PRIMARY KEY,
+	nameTableI string UNIQUE,
+	nameTableW string UNIQUE,
+	nameTableE string UNIQUE,
+	timestamp TEXT DEFAULT CURRENT_TIMESTAMP);
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO main (nameTableI, nameTableW, nameTableE) VALUES (?, ?, ?)", nameI, nameW, nameE)
+	require.NoError(t, err)
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Reading the catalog of log tables. Rotation closes the log table and opens the next one
+func Test_Partitions_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(1, 10, 10)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	for i := 0; i < 3; i++ {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "project", LocationEvent: "main.go:1", BodyMessage: "msg"})
+		require.NoError(t, err)
+	}
+
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+
+	assert.Equal(t, "logE_1", parts[0].NameTable)
+	assert.Equal(t, StateActive, parts[0].State)
+	assert.Equal(t, int64(0), parts[0].RowCount)
+	assert.True(t, parts[0].FirstTime.IsZero())
+
+	// logI_1 is closed after the 2nd message
+	assert.Equal(t, "logI_1", parts[1].NameTable)
+	assert.Equal(t, StateClosed, parts[1].State)
+	assert.Equal(t, int64(1), parts[1].FirstId)
+	assert.Equal(t, int64(2), parts[1].LastId)
+	assert.Equal(t, int64(2), parts[1].RowCount)
+	assert.False(t, parts[1].FirstTime.IsZero())
+	assert.False(t, parts[1].LastTime.IsZero())
+	assert.False(t, parts[1].TimeClose.IsZero())
+
+	assert.Equal(t, "logI_2", parts[2].NameTable)
+	assert.Equal(t, 2, parts[2].Seq)
+	assert.Equal(t, StateActive, parts[2].State)
+	assert.Equal(t, int64(1), parts[2].RowCount)
+	assert.True(t, parts[2].TimeClose.IsZero())
+
+	assert.Equal(t, "logW_1", parts[3].NameTable)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Migration of the main table to the catalog
+func Test_migrateCatalog_Main_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+
+	// database of v0.0.6: logI_1, logI_2 are closed, logI_3 is current
+	createOldMainTable(t, db, "logI_3", "logW_1", "logE_1")
+	createOldLogTable(t, db, "logI_1", "2025-01-01 10:00:00", "2025-01-02 10:00:00")
+	createOldLogTable(t, db, "logI_2", "2025-01-03 10:00:00")
+	createOldLogTable(t, db, "logI_3", "2025-01-04 10:00:00")
+	createOldLogTable(t, db, "logW_1")
+	createOldLogTable(t, db, "logE_1")
+
+	instAct, err := RepoDB(db, testConfig(10, 10, 10))
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	has, err := hasTable(db, "main")
+	require.NoError(t, err)
+	assert.False(t, has, "the main table is dropped")
+
+	nameI, nameW, nameE, err := readLogTablesName(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logI_3", "logW_1", "logE_1"}, []string{nameI, nameW, nameE})
+
+	parts, err := readPartitions(db)
+	require.NoError(t, err)
+	require.Len(t, parts, 5)
+
+	byName := make(map[string]PartitionT)
+	for _, p := range parts {
+		byName[p.NameTable] = p
+	}
+	assert.Equal(t, StateClosed, byName["logI_1"].State)
+	assert.Equal(t, int64(2), byName["logI_1"].RowCount)
+	assert.Equal(t, int64(2), byName["logI_1"].LastId)
+	assert.Equal(t, "2025-01-01 10:00:00", byName["logI_1"].FirstTime.Format(layoutTimestamp))
+	assert.Equal(t, "2025-01-02 10:00:00", byName["logI_1"].TimeClose.Format(layoutTimestamp))
+	assert.Equal(t, StateClosed, byName["logI_2"].State)
+	assert.Equal(t, StateActive, byName["logI_3"].State)
+	assert.Equal(t, int64(1), byName["logI_3"].RowCount)
+	assert.True(t, byName["logI_3"].TimeClose.IsZero())
+	assert.Equal(t, StateActive, byName["logW_1"].State)
+	assert.Equal(t, int64(0), byName["logW_1"].RowCount)
+
+	// columns are added, messages of v0.0.6 are read by the time of receiving
+	for _, col := range []string{"eventTime", "skewed", "attributes", "tokenId"} {
+		has, err = hasColumn(db, "logI_1", col)
+		require.NoError(t, err)
+		assert.True(t, has, col)
+	}
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "I", ByEventTime: true})
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+	assert.Equal(t, msgs[0].Timestamp, msgs[0].EventTime)
+
+	// full-text indexes are filled from log tables
+	found, err := instAct.SearchMessages(SearchT{Query: "b", FilterT: FilterT{TypeMessage: "I"}})
+	require.NoError(t, err)
+	assert.Len(t, found, 4)
+
+	// repeated start does not change the catalog
+	require.NoError(t, instAct.Tables())
+	again, err := readPartitions(db)
+	require.NoError(t, err)
+	assert.Equal(t, parts, again)
+}
+
+// Test - Migration of the registry without state and the main table to the catalog
+func Test_migrateCatalog_Registry_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+
+	createOldMainTable(t, db, "logI_2", "logW_1", "logE_1")
+	createOldLogTable(t, db, "logI_1", "2025-01-01 10:00:00")
+	createOldLogTable(t, db, "logI_2", "2025-01-02 10:00:00")
+	createOldLogTable(t, db, "logW_1")
+	createOldLogTable(t, db, "logE_1")
+
+	_, err := db.Exec(`
+	CREATE TABLE partitions (
+	nameTable string PRIMARY KEY,
+	typeTable string NOT NULL,
+	seq INTEGER NOT NULL,
+	timeOpen TEXT DEFAULT CURRENT_TIMESTAMP,
+	timeClose TEXT);
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO partitions (nameTable, typeTable, seq, timeOpen, timeClose) VALUES
+	('logI_1', 'I', 1, '2025-01-01 10:00:00', '2025-01-01 10:00:00'),
+	('logI_2', 'I', 2, '2025-01-01 10:00:00', NULL),
+	('logW_1', 'W', 1, '2025-01-01 10:00:00', NULL),
+	('logE_1', 'E', 1, '2025-01-01 10:00:00', NULL)`)
+	require.NoError(t, err)
+
+	instAct, err := RepoDB(db, testConfig(10, 10, 10))
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	parts, err := readPartitions(db)
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+
+	assert.Equal(t, "logI_1", parts[1].NameTable)
+	assert.Equal(t, StateClosed, parts[1].State)
+	assert.Equal(t, int64(1), parts[1].RowCount)
+	assert.Equal(t, "logI_2", parts[2].NameTable)
+	assert.Equal(t, StateActive, parts[2].State)
+	assert.Equal(t, int64(1), parts[2].LastId)
+}
+
+// Test - Reading names of the active log tables from the catalog
+func Test_readLogTablesName_SUCCESS(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoErrorf(t, err, "recieved error: {%v}", err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+		WithArgs(StateActive).
+		WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+			AddRow("I", "logI_1").AddRow("W", "logW_1").AddRow("E", "logE_1"))
+
+	nameI, nameW, nameE, err := readLogTablesName(db)
+	require.NoErrorf(t, err, "recieved err: {%v}", err)
+	assert.Equalf(t, "logI_1", nameI, "wait {logI_1}, recieved {%s}", nameI)
+	assert.Equalf(t, "logW_1", nameW, "wait {logW_1}, recieved {%s}", nameW)
+	assert.Equalf(t, "logE_1", nameE, "wait {logE_1}, recieved {%s}", nameE)
+}
+
+// Test - Check-create the catalog of log tables
+func Test_checkCreatePartitionsTable_SUCCESS(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS partitions").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = checkCreatePartitionsTable(db)
+	require.NoError(t, err)
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Reading names of the active log tables. Not all types are active
+func Test_readLogTablesName_FAULT(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT typeTable, nameTable FROM partitions WHERE state").
+		WithArgs(StateActive).
+		WillReturnRows(sqlmock.NewRows([]string{"typeTable", "nameTable"}).
+			AddRow("I", "logI_1").AddRow("E", "logE_1"))
+
+	_, _, _, err = readLogTablesName(db)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// Test - Close the active log table. The log table is already closed
+func Test_closePartition_FAULT(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE partitions SET state").
+		WithArgs(StateClosed, "logI_1", StateActive).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = closePartition(db, "logI_1")
+	require.Error(t, err)
+}
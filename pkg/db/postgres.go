@@ -0,0 +1,749 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	metrics "github.com/Part001-R/netlogiwe/pkg/metrics"
+)
+
+// Storage of messages in PostgreSQL. Log tables of a type are list partitions of the parent
+// table (logI, logW, logE) by the seq column, so rotation is creating the next partition.
+// Writers of several servers are serialised by advisory locks of the transaction.
+type ObjectPG struct {
+	DB  *sql.DB
+	cfg ConfigT
+}
+
+// Keys of advisory locks
+const (
+	lockCatalogPG = "netlogiwe.catalog"
+	lockTypePG    = "netlogiwe.log" // + type of log table
+)
+
+// Document of the full-text search. The same expression is in the index and in queries
+const ftsDocumentPG = "to_tsvector('simple', bodyMessage || ' ' || locationEvent)"
+
+func init() {
+	Register("postgres", sqlFactory(RepoPG))
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Create the PostgreSQL db object with the settings. Return interface.
+func RepoPG(db *sql.DB, cfg ConfigT) (ActionsDB, error) {
+	if db == nil {
+		return nil, errors.New("empty pinter db")
+	}
+	return &ObjectPG{DB: db, cfg: cfg}, nil
+}
+
+// Working with database tables
+func (o ObjectPG) Tables() error {
+
+	return inTxPG(o.DB, func(tx *sql.Tx) error {
+
+		_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", lockCatalogPG)
+		if err != nil {
+			return fmt.Errorf("fault lock the catalog: {%v}", err)
+		}
+
+		err = checkCreatePartitionsTablePG(tx)
+		if err != nil {
+			return err
+		}
+
+		err = checkCreateMessageIdsTable(tx)
+		if err != nil {
+			return err
+		}
+
+		err = checkCreateTokensTable(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, typeTable := range []string{"I", "W", "E"} {
+
+			err := checkCreateParentTablePG(tx, typeTable)
+			if err != nil {
+				return err
+			}
+
+			var (
+				name string
+				seq  int
+			)
+			err = tx.QueryRow("SELECT nameTable, seq FROM partitions WHERE typeTable = $1 AND state = $2", typeTable, StateActive).
+				Scan(&name, &seq)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = tx.QueryRow("SELECT COALESCE(MAX(seq), 0) + 1 FROM partitions WHERE typeTable = $1", typeTable).Scan(&seq)
+				if err != nil {
+					return fmt.Errorf("fault read the catalog of {%s}: %v", typeTable, err)
+				}
+				err = openPartitionPG(tx, typeTable, seq)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("fault read the current {%s} table: {%v}", typeTable, err)
+			}
+
+			err = checkCreateLogTablePG(tx, typeTable, seq)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Saving the received message in the database. Return reference, error (ErrDuplicate if the id of message is repeated)
+func (o ObjectPG) SavingMessage(msg MessageT) (RefT, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	var (
+		ref     RefT
+		rotated bool
+	)
+	err := inTxPG(o.DB, func(tx *sql.Tx) error {
+		if msg.MessageId != "" {
+			err := purgeMessageIds(tx, window, now)
+			if err != nil {
+				return err
+			}
+		}
+		return savingUnique(tx, window, now, msg, func(db queryer) error {
+			var err error
+			ref, rotated, err = savingByTypePG(db, o.cfg, msg)
+			return err
+		})
+	})
+	if err != nil {
+		return RefT{}, err
+	}
+	countRotations([]MessageT{msg}, []bool{rotated}, nil)
+
+	return ref, nil
+}
+
+// Saving the batch of messages in one transaction. Return reference and error of each message (nil - saved, ErrDuplicate),
+// error of transaction
+func (o ObjectPG) SavingMessages(msgs []MessageT) ([]RefT, []error, error) {
+
+	window := o.cfg.DedupWindow
+	now := time.Now()
+
+	refs := make([]RefT, len(msgs))
+	rotated := make([]bool, len(msgs))
+	results := make([]error, len(msgs))
+	err := inTxPG(o.DB, func(tx *sql.Tx) error {
+		if hasMessageIds(msgs) {
+			err := purgeMessageIds(tx, window, now)
+			if err != nil {
+				return err
+			}
+		}
+		for i, msg := range msgs {
+			results[i] = storageFault(savingInSavepoint(tx, func(db queryer) error {
+				return savingUnique(db, window, now, msg, func(db queryer) error {
+					var err error
+					refs[i], rotated[i], err = savingByTypePG(db, o.cfg, msg)
+					return err
+				})
+			}))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	countRotations(msgs, rotated, results)
+
+	return refs, results, nil
+}
+
+// Reading messages from all log tables by filter. Return messages sorted by time, error
+func (o ObjectPG) ReadingMessages(filter FilterT) ([]StoredMessageT, error) {
+
+	types, err := typesByFilter(filter.TypeMessage)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAttributes(filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := readPartitionsPG(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read catalog: {%v}", err)
+	}
+
+	seqs := make(map[string][]int64)
+	for _, p := range partitionsByFilter(catalog, types, filter) {
+		seqs[p.TypeTable] = append(seqs[p.TypeTable], int64(p.Seq))
+	}
+
+	where, args := whereByFilterPG(filter)
+	var parts []string
+	for _, typeTable := range types {
+		if len(seqs[typeTable]) == 0 {
+			continue
+		}
+		args = append(args, pq.Array(seqs[typeTable]))
+		parts = append(parts, fmt.Sprintf(
+			"SELECT '%[1]s' AS typeMessage, 'log%[1]s_' || seq AS nameTable, seq, id, nameProject, locationEvent, bodyMessage, timestamp, "+
+				"COALESCE(eventTime, timestamp) AS eventTime, skewed, attributes, tokenId FROM %[2]s WHERE seq = ANY($%[3]d)%[4]s",
+			typeTable, pq.QuoteIdentifier("log"+typeTable), len(args), where))
+	}
+	if len(parts) == 0 {
+		return []StoredMessageT{}, nil
+	}
+
+	limit, offset := limitsByFilter(filter)
+	args = append(args, limit, offset)
+	q := strings.Join(parts, " UNION ALL ") +
+		fmt.Sprintf(" ORDER BY "+columnTimeByFilter(filter, "eventTime")+", typeMessage, seq, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := o.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fault read messages: {%v}", err)
+	}
+	defer rows.Close()
+
+	msgs := []StoredMessageT{}
+	for rows.Next() {
+		msg, err := scanMessagePG(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return msgs, nil
+}
+
+// Search messages by the full-text index of log tables. Return messages sorted by relevance, then newest first
+func (o ObjectPG) SearchMessages(search SearchT) ([]FoundMessageT, error) {
+
+	types, err := checkSearch(search)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := readPartitionsPG(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read catalog: {%v}", err)
+	}
+
+	seqs := make(map[string][]int64)
+	for _, p := range partitionsByFilter(catalog, types, search.FilterT) {
+		seqs[p.TypeTable] = append(seqs[p.TypeTable], int64(p.Seq))
+	}
+
+	markStart, markEnd := marksBySearch(search)
+	where, args := whereByFilterPG(search.FilterT)
+	args = append(args, search.Query, headlineOptionsPG(markStart, markEnd))
+	argQuery, argOptions := len(args)-1, len(args)
+
+	var parts []string
+	for _, typeTable := range types {
+		if len(seqs[typeTable]) == 0 {
+			continue
+		}
+		args = append(args, pq.Array(seqs[typeTable]))
+		parts = append(parts, fmt.Sprintf(
+			"SELECT '%[1]s' AS typeMessage, 'log%[1]s_' || seq AS nameTable, seq, id, nameProject, locationEvent, bodyMessage, timestamp, "+
+				"COALESCE(eventTime, timestamp) AS eventTime, skewed, attributes, tokenId, ts_rank(%[2]s, q) AS score, "+
+				"ts_headline('simple', bodyMessage, q, $%[3]d) AS snippet "+
+				"FROM %[4]s, websearch_to_tsquery('simple', $%[5]d) q WHERE seq = ANY($%[6]d) AND %[2]s @@ q%[7]s",
+			typeTable, ftsDocumentPG, argOptions, pq.QuoteIdentifier("log"+typeTable), argQuery, len(args), where))
+	}
+	if len(parts) == 0 {
+		return []FoundMessageT{}, nil
+	}
+
+	limit, offset := limitsByFilter(search.FilterT)
+	args = append(args, limit, offset)
+	q := strings.Join(parts, " UNION ALL ") +
+		fmt.Sprintf(" ORDER BY score DESC, timestamp DESC, typeMessage, seq, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := o.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fault search messages: {%w}", storageFault(err))
+	}
+	defer rows.Close()
+
+	msgs := []FoundMessageT{}
+	for rows.Next() {
+		var msg FoundMessageT
+		msg.StoredMessageT, err = scanMessagePG(rows, &msg.Rank, &msg.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return msgs, nil
+}
+
+// Reading the catalog of log tables. Return log tables sorted by type and index
+func (o ObjectPG) Partitions() ([]PartitionT, error) {
+	return readPartitionsPG(o.DB)
+}
+
+// Removing the expired log tables by policy. Return removed (dryRun - to be removed) log tables, error
+func (o ObjectPG) ApplyRetention(policy RetentionPolicyT, dryRun bool) ([]PartitionT, error) {
+
+	parts, err := readPartitionsPG(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read catalog: {%v}", err)
+	}
+
+	return applyRetention(parts, policy, dryRun,
+		func(p PartitionT) error {
+			_, err := exportPartition(o.DB, p, policy.DirArchive)
+			return err
+		},
+		func(p PartitionT, state string) error {
+			return inTxPG(o.DB, func(tx *sql.Tx) error {
+				return dropPartitionPG(tx, p.NameTable, state)
+			})
+		})
+}
+
+// Creating the token in the database. Return created token, secret, error
+func (o ObjectPG) CreateToken(token TokenT) (TokenT, string, error) {
+
+	token, secret, hash, err := newToken(token)
+	if err != nil {
+		return TokenT{}, "", err
+	}
+
+	err = inTxPG(o.DB, func(tx *sql.Tx) error {
+		return insertToken(tx, token, hash)
+	})
+	if err != nil {
+		return TokenT{}, "", err
+	}
+
+	return token, secret, nil
+}
+
+// Reading all tokens. Return tokens sorted by time of creation, error
+func (o ObjectPG) ListTokens() ([]TokenT, error) {
+	return readTokens(o.DB, "", nil)
+}
+
+// Revoking the token by id. Return the token, error (ErrNotFound)
+func (o ObjectPG) RevokeToken(id string) (TokenT, error) {
+
+	var token TokenT
+	err := inTxPG(o.DB, func(tx *sql.Tx) error {
+		var err error
+		token, err = revokeToken(tx, id, time.Now())
+		return err
+	})
+	if err != nil {
+		return TokenT{}, err
+	}
+
+	return token, nil
+}
+
+// Reading the token by the secret. Revoked and expired tokens are returned too. Return token, error (ErrNotFound)
+func (o ObjectPG) TokenBySecret(secret string) (TokenT, error) {
+	return tokenByHash(o.DB, hashSecret(secret))
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Execution of fn in one transaction
+func inTxPG(db *sql.DB, fn func(tx *sql.Tx) error) error {
+
+	start := time.Now()
+	defer func() { metrics.ObserveDBWrite("postgres", time.Since(start)) }()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return storageFault(fmt.Errorf("fault begin transaction: {%w}", err))
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return storageFault(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return storageFault(fmt.Errorf("fault commit transaction: {%w}", err))
+	}
+
+	return nil
+}
+
+// Check-create the catalog of log tables
+func checkCreatePartitionsTablePG(db queryer) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS partitions (
+	nameTable TEXT PRIMARY KEY,
+	typeTable TEXT NOT NULL,
+	seq INTEGER NOT NULL,
+	firstId BIGINT,
+	lastId BIGINT,
+	firstTime TIMESTAMPTZ,
+	lastTime TIMESTAMPTZ,
+	rowCount BIGINT NOT NULL DEFAULT 0,
+	state TEXT NOT NULL DEFAULT 'active',
+	timeOpen TIMESTAMPTZ NOT NULL DEFAULT now(),
+	timeClose TIMESTAMPTZ);
+	`)
+	if err != nil {
+		return fmt.Errorf("fault create the partitions table: %v", err)
+	}
+
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS partitions_active ON partitions (typeTable) WHERE state = 'active'")
+	if err != nil {
+		return fmt.Errorf("fault create index of active log tables: %v", err)
+	}
+
+	return nil
+}
+
+// Check-create the parent table of log tables of the type
+func checkCreateParentTablePG(db queryer, typeTable string) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	q := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL,
+	seq INTEGER NOT NULL,
+	nameProject TEXT NOT NULL,
+	locationEvent TEXT NOT NULL,
+	bodyMessage TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL DEFAULT now(),
+	eventTime TIMESTAMPTZ,
+	skewed BOOLEAN NOT NULL DEFAULT false,
+	attributes JSONB,
+	tokenId TEXT,
+	PRIMARY KEY (seq, id)) PARTITION BY LIST (seq);
+	`, pq.QuoteIdentifier("log"+typeTable))
+
+	_, err := db.Exec(q)
+	if err != nil {
+		return fmt.Errorf("table {log%s} is not created: %v", typeTable, err)
+	}
+
+	// parent tables of previous versions. Columns are added to all log tables of the type
+	q = fmt.Sprintf(`ALTER TABLE %s
+	ADD COLUMN IF NOT EXISTS eventTime TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS skewed BOOLEAN NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS attributes JSONB,
+	ADD COLUMN IF NOT EXISTS tokenId TEXT`, pq.QuoteIdentifier("log"+typeTable))
+	_, err = db.Exec(q)
+	if err != nil {
+		return fmt.Errorf("fault add columns to {log%s}: %v", typeTable, err)
+	}
+
+	// the full-text index of the parent table is created in all its log tables
+	q = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin (%s)",
+		pq.QuoteIdentifier("log"+typeTable+"_fts"), pq.QuoteIdentifier("log"+typeTable), ftsDocumentPG)
+	_, err = db.Exec(q)
+	if err != nil {
+		return fmt.Errorf("full-text index of {log%s} is not created: %v", typeTable, err)
+	}
+
+	return nil
+}
+
+// Check-create the log table as the partition of its parent table
+func checkCreateLogTablePG(db queryer, typeTable string, seq int) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	name := fmt.Sprintf("log%s_%d", typeTable, seq)
+	q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES IN (%d)",
+		pq.QuoteIdentifier(name), pq.QuoteIdentifier("log"+typeTable), seq)
+
+	_, err := db.Exec(q)
+	if err != nil {
+		return fmt.Errorf("table {%s} is not created: %v", name, err)
+	}
+
+	return nil
+}
+
+// Create the log table and add it to the catalog as active
+func openPartitionPG(db queryer, typeTable string, seq int) error {
+
+	err := checkCreateLogTablePG(db, typeTable, seq)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("log%s_%d", typeTable, seq)
+	_, err = db.Exec("INSERT INTO partitions (nameTable, typeTable, seq, state) VALUES ($1, $2, $3, $4)",
+		name, typeTable, seq, StateActive)
+	if err != nil {
+		return fmt.Errorf("fault open {%s} table: %v", name, err)
+	}
+
+	return nil
+}
+
+// Saving the message in the current log table of its type. The log table is changed when overloaded. Return reference,
+// true if the log table is changed, error
+func savingByTypePG(db queryer, cfg ConfigT, msg MessageT) (RefT, bool, error) {
+	if db == nil {
+		return RefT{}, false, errors.New("empty pointer db")
+	}
+
+	err := checkMessage(msg)
+	if err != nil {
+		return RefT{}, false, err
+	}
+
+	// the current log table is not changed by other writers up to the end of transaction
+	_, err = db.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", lockTypePG+msg.TypeMessage)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault lock {%s} tables: {%w}", msg.TypeMessage, err)
+	}
+
+	var (
+		name     string
+		seq      int
+		rowCount int64
+	)
+	err = db.QueryRow("SELECT nameTable, seq, rowCount FROM partitions WHERE typeTable = $1 AND state = $2", msg.TypeMessage, StateActive).
+		Scan(&name, &seq, &rowCount)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault read the current {%s} table: {%w}", msg.TypeMessage, err)
+	}
+
+	var (
+		id int64
+		ts time.Time
+	)
+	var event any
+	if !msg.EventTime.IsZero() {
+		event = msg.EventTime.UTC()
+	}
+	attrs, err := attributesJSON(msg.Attributes)
+	if err != nil {
+		return RefT{}, false, err
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (seq, nameProject, locationEvent, bodyMessage, eventTime, skewed, attributes, tokenId)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, timestamp`, pq.QuoteIdentifier("log"+msg.TypeMessage))
+	err = db.QueryRow(q, seq, msg.NameProject, msg.LocationEvent, msg.BodyMessage, event, msg.Skewed, attrs, tokenIdOrNull(msg.TokenId)).Scan(&id, &ts)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("store an information -> flt store %s message: %w", msg.TypeMessage, err)
+	}
+
+	_, err = db.Exec(`UPDATE partitions SET
+	firstId = COALESCE(firstId, $1), lastId = $1,
+	firstTime = COALESCE(firstTime, $2), lastTime = $2,
+	rowCount = rowCount + 1
+	WHERE nameTable = $3`, id, ts, name)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault update statistics of {%s}: %w", name, err)
+	}
+
+	over, err := checkOverloadLogTable(msg.TypeMessage, cfg, rowCount+1)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault check overload {%s} table: {%v}", msg.TypeMessage, err)
+	}
+	ref := RefT{NameTable: name, Id: id}
+	if !over {
+		return ref, false, nil
+	}
+
+	// Change the current log table
+	res, err := db.Exec("UPDATE partitions SET state = $1, timeClose = now() WHERE nameTable = $2 AND state = $3", StateClosed, name, StateActive)
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("fault close {%s} table: %w", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return RefT{}, false, fmt.Errorf("error get RowsAffected after update: {%v}", err)
+	}
+	if n != 1 {
+		return RefT{}, false, fmt.Errorf("the {%s} table is not active", name)
+	}
+
+	err = openPartitionPG(db, msg.TypeMessage, seq+1)
+	if err != nil {
+		return RefT{}, false, err
+	}
+
+	return ref, true, nil
+}
+
+// Scan the message read from a log table of PostgreSQL. Extra columns after the message are scanned to extra
+func scanMessagePG(rows *sql.Rows, extra ...any) (StoredMessageT, error) {
+
+	var (
+		msg   StoredMessageT
+		seq   int64
+		attrs sql.NullString
+		token sql.NullString
+	)
+	dest := append([]any{&msg.TypeMessage, &msg.NameTable, &seq, &msg.Id, &msg.NameProject, &msg.LocationEvent, &msg.BodyMessage, &msg.Timestamp,
+		&msg.EventTime, &msg.Skewed, &attrs, &token}, extra...)
+	err := rows.Scan(dest...)
+	if err != nil {
+		return StoredMessageT{}, fmt.Errorf("fault scan message: {%v}", err)
+	}
+	msg.Timestamp = msg.Timestamp.UTC()
+	msg.EventTime = msg.EventTime.UTC()
+	msg.Attributes, err = parseAttributes(attrs)
+	if err != nil {
+		return StoredMessageT{}, err
+	}
+	msg.TokenId = token.String
+
+	return msg, nil
+}
+
+// Options of ts_headline: marks of found words and size of the snippet. Double quotes are removed from marks
+func headlineOptionsPG(markStart, markEnd string) string {
+	return fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d`,
+		strings.ReplaceAll(markStart, `"`, ""), strings.ReplaceAll(markEnd, `"`, ""), sizeSnippet, sizeSnippet/2)
+}
+
+// WHERE conditions (after the seq condition) and arguments of the query by filter
+func whereByFilterPG(filter FilterT) (string, []any) {
+
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.NameProject != "" {
+		add("nameProject = $%d", filter.NameProject)
+	}
+	if filter.LocationEvent != "" {
+		add("locationEvent = $%d", filter.LocationEvent)
+	}
+	if filter.BodySubstr != "" {
+		add("strpos(bodyMessage, $%d) > 0", filter.BodySubstr)
+	}
+	column := columnTimeByFilter(filter, "COALESCE(eventTime, timestamp)")
+	if !filter.TimeFrom.IsZero() {
+		add(column+" >= $%d", filter.TimeFrom)
+	}
+	if !filter.TimeTo.IsZero() {
+		add(column+" <= $%d", filter.TimeTo)
+	}
+	if len(filter.Attributes) != 0 {
+		attrs, _ := attributesJSON(filter.Attributes) // map of strings is always encoded
+		add("attributes @> $%d::jsonb", attrs)
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " AND " + strings.Join(conds, " AND "), args
+}
+
+// Reading the catalog of log tables
+func readPartitionsPG(db queryer) ([]PartitionT, error) {
+	if db == nil {
+		return nil, errors.New("missed db pointer")
+	}
+
+	rows, err := db.Query(`SELECT nameTable, typeTable, seq, state,
+	COALESCE(firstId, 0), COALESCE(lastId, 0), firstTime, lastTime, rowCount, timeOpen, timeClose
+	FROM partitions ORDER BY typeTable, seq`)
+	if err != nil {
+		return nil, fmt.Errorf("fault read the partitions table: {%v}", err)
+	}
+	defer rows.Close()
+
+	parts := []PartitionT{}
+	for rows.Next() {
+		var (
+			p                              PartitionT
+			firstTime, lastTime, timeClose sql.NullTime
+		)
+		err := rows.Scan(&p.NameTable, &p.TypeTable, &p.Seq, &p.State,
+			&p.FirstId, &p.LastId, &firstTime, &lastTime, &p.RowCount, &p.TimeOpen, &timeClose)
+		if err != nil {
+			return nil, fmt.Errorf("fault scan partition: {%v}", err)
+		}
+		p.TimeOpen = p.TimeOpen.UTC()
+		if firstTime.Valid {
+			p.FirstTime = firstTime.Time.UTC()
+		}
+		if lastTime.Valid {
+			p.LastTime = lastTime.Time.UTC()
+		}
+		if timeClose.Valid {
+			p.TimeClose = timeClose.Time.UTC()
+		}
+		parts = append(parts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return parts, nil
+}
+
+// Drop the closed log table. It stays in the catalog with the state: archived or dropped
+func dropPartitionPG(db queryer, name, state string) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	res, err := db.Exec("UPDATE partitions SET state = $1 WHERE nameTable = $2 AND state = $3", state, name, StateClosed)
+	if err != nil {
+		return fmt.Errorf("fault set state of {%s}: %v", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error get RowsAffected after update: {%v}", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("the {%s} table is not closed and can not be dropped", name)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", pq.QuoteIdentifier(name)))
+	if err != nil {
+		return fmt.Errorf("fault drop table {%s}: %v", name, err)
+	}
+
+	return nil
+}
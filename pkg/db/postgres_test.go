@@ -0,0 +1,248 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Open the PostgreSQL database from TEST_POSTGRES_DSN in the own schema. The test is skipped without DSN
+func openTestPG(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // search_path is set for the connection
+
+	schema := fmt.Sprintf("netlogiwe_test_%d", time.Now().UnixNano())
+	_, err = db.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	_, err = db.Exec("SET search_path TO " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		db.Close()
+	})
+
+	return db
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Saving, rotation, reading and retention in PostgreSQL
+func Test_ObjectPG_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(1, 10, 10)
+
+	db := openTestPG(t)
+	instAct, err := RepoPG(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+	require.NoError(t, instAct.Tables())
+
+	for _, body := range []string{"one", "two", "three"} {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: body})
+		require.NoError(t, err)
+	}
+	_, results, err := instAct.SavingMessages([]MessageT{
+		{TypeMessage: "E", NameProject: "beta", LocationEvent: "main.go:2", BodyMessage: "connection refused"},
+		{TypeMessage: "E", NameProject: "beta", LocationEvent: "main.go:2"},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, results[0])
+	assert.Error(t, results[1])
+
+	// repeated id of message is not saved
+	msg := MessageT{TypeMessage: "W", NameProject: "alpha", LocationEvent: "main.go:3", BodyMessage: "once", MessageId: "id-1"}
+	require.NoError(t, savingErr(instAct, msg))
+	require.ErrorIs(t, savingErr(instAct, msg), ErrDuplicate)
+
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	assert.Equal(t, "logI_1", parts[1].NameTable)
+	assert.Equal(t, StateClosed, parts[1].State)
+	assert.Equal(t, int64(2), parts[1].RowCount)
+	assert.Equal(t, "logI_2", parts[2].NameTable)
+	assert.Equal(t, StateActive, parts[2].State)
+
+	msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "I"})
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "logI_1", msgs[0].NameTable)
+	assert.Equal(t, "logI_2", msgs[2].NameTable)
+	assert.Equal(t, "three", msgs[2].BodyMessage)
+
+	msgs, err = instAct.ReadingMessages(FilterT{BodySubstr: "refused", TimeFrom: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "logE_1", msgs[0].NameTable)
+
+	found, err := instAct.SearchMessages(SearchT{Query: "connection refused"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "<b>connection</b> <b>refused</b>", found[0].Snippet)
+
+	removed, err := instAct.ApplyRetention(RetentionPolicyT{ByType: map[string]RetentionT{"I": {KeepLast: 1}}}, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, StateDropped, removed[0].State)
+
+	msgs, err = instAct.ReadingMessages(FilterT{TypeMessage: "I"})
+	require.NoError(t, err)
+	assert.Len(t, msgs, 1)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Saving the message with change of the overloaded log table
+func Test_savingByTypePG_SUCCESS(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ts := time.Now()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs("netlogiwe.logW").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT nameTable, seq, rowCount FROM partitions").
+		WithArgs("W", StateActive).
+		WillReturnRows(sqlmock.NewRows([]string{"nameTable", "seq", "rowCount"}).AddRow("logW_3", 3, 10))
+	mock.ExpectQuery(`INSERT INTO "logW" \(seq, nameProject, locationEvent, bodyMessage, eventTime, skewed, attributes, tokenId\)`).
+		WithArgs(3, "project", "main.go:1", "msg", nil, false, `{"host":"web-1"}`, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp"}).AddRow(31, ts))
+	mock.ExpectExec("UPDATE partitions SET firstId").
+		WithArgs(int64(31), ts, "logW_3").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE partitions SET state").
+		WithArgs(StateClosed, "logW_3", StateActive).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "logW_4" PARTITION OF "logW" FOR VALUES IN \(4\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO partitions").
+		WithArgs("logW_4", "W", 4, StateActive).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ref, rotated, err := savingByTypePG(db, testConfig(10, 10, 10), MessageT{TypeMessage: "W", NameProject: "project", LocationEvent: "main.go:1", BodyMessage: "msg",
+		Attributes: map[string]string{"host": "web-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, RefT{NameTable: "logW_3", Id: 31}, ref)
+	assert.True(t, rotated)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test - WHERE conditions of the query by filter
+func Test_whereByFilterPG_SUCCESS(t *testing.T) {
+
+	from := time.Now()
+
+	tests := []struct {
+		nameTest string
+		filter   FilterT
+		want     string
+		wantArgs []any
+	}{
+		{
+			nameTest: "Empty filter",
+			filter:   FilterT{},
+			want:     "",
+			wantArgs: nil,
+		},
+		{
+			nameTest: "Project, body and time",
+			filter:   FilterT{NameProject: "alpha", BodySubstr: "refused", TimeFrom: from},
+			want:     " AND nameProject = $1 AND strpos(bodyMessage, $2) > 0 AND timestamp >= $3",
+			wantArgs: []any{"alpha", "refused", from},
+		},
+		{
+			nameTest: "Event time",
+			filter:   FilterT{TimeTo: from, ByEventTime: true},
+			want:     " AND COALESCE(eventTime, timestamp) <= $1",
+			wantArgs: []any{from},
+		},
+		{
+			nameTest: "Attributes",
+			filter:   FilterT{LocationEvent: "main.go:1", Attributes: map[string]string{"requestId": "r-1", "host": "web-1"}},
+			want:     " AND locationEvent = $1 AND attributes @> $2::jsonb",
+			wantArgs: []any{"main.go:1", `{"host":"web-1","requestId":"r-1"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			where, args := whereByFilterPG(tt.filter)
+			assert.Equal(t, tt.want, where)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Saving the message. Not allowed message
+func Test_savingByTypePG_FAULT(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		nameTest string
+		msg      MessageT
+	}{
+		{nameTest: "Type", msg: MessageT{TypeMessage: "T", NameProject: "p", LocationEvent: "l", BodyMessage: "b"}},
+		{nameTest: "Body", msg: MessageT{TypeMessage: "I", NameProject: "p", LocationEvent: "l"}},
+		{nameTest: "Location", msg: MessageT{TypeMessage: "I", NameProject: "p", BodyMessage: "b"}},
+		{nameTest: "Project", msg: MessageT{TypeMessage: "I", LocationEvent: "l", BodyMessage: "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, _, err := savingByTypePG(db, testConfig(10, 10, 10), tt.msg)
+			require.Error(t, err)
+		})
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test - Drop the log table. The log table is not closed
+func Test_dropPartitionPG_FAULT(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE partitions SET state").
+		WithArgs(StateDropped, "logI_2", StateClosed).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = dropPartitionPG(db, "logI_2", StateDropped)
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,375 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimitRead  = 100
+	maxLimitRead      = 1000
+	maxCompoundSelect = 500                   // SELECTs joined by UNION ALL in one query: SQLITE_MAX_COMPOUND_SELECT
+	layoutTimestamp   = "2006-01-02 15:04:05" // format of CURRENT_TIMESTAMP in SQLite
+)
+
+// Filter for reading messages. Empty fields are not used.
+type FilterT struct {
+	TypeMessage   string // I, W, E. Empty - all types
+	NameProject   string
+	LocationEvent string
+	BodySubstr    string
+	TimeFrom      time.Time         // inclusive
+	TimeTo        time.Time         // inclusive
+	ByEventTime   bool              // order and time range by the event time, else by the time of receiving
+	Attributes    map[string]string // all attributes are matched exactly
+	Limit         int               // 0 - defaultLimitRead
+	Offset        int
+}
+
+// Message read from a log table
+type StoredMessageT struct {
+	MessageT
+	Id        int64
+	NameTable string
+	Timestamp time.Time
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Reading messages from all log tables by filter. Return messages sorted by time, error
+func (o ObjectDB) ReadingMessages(filter FilterT) ([]StoredMessageT, error) {
+
+	types, err := typesByFilter(filter.TypeMessage)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAttributes(filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := readPartitions(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read catalog: {%v}", err)
+	}
+
+	limit, offset := limitsByFilter(filter)
+	read := func(parts []PartitionT, limit, offset int) ([]StoredMessageT, error) {
+		return o.readingPartitions(parts, filter, limit, offset)
+	}
+	compare := func(a, b StoredMessageT) int {
+		if filter.ByEventTime {
+			return compareMessages(a, b, a.EventTime.Compare(b.EventTime))
+		}
+		return compareMessages(a, b, a.Timestamp.Compare(b.Timestamp))
+	}
+
+	return queryByBatches(partitionsByFilter(catalog, types, filter), limit, offset, read, compare)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Reading messages of log tables by filter in one query. Return messages sorted by time, error
+func (o ObjectDB) readingPartitions(parts []PartitionT, filter FilterT, limit, offset int) ([]StoredMessageT, error) {
+
+	selects := make([]string, 0, len(parts))
+	for _, p := range parts {
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS typeMessage, '%s' AS nameTable, %d AS seq, id, nameProject, locationEvent, bodyMessage, timestamp, "+
+				"COALESCE(eventTime, timestamp) AS eventTime, skewed, attributes, tokenId FROM %s%s",
+			p.TypeTable, p.NameTable, p.Seq, p.NameTable, whereByFilter(filter)))
+	}
+
+	q := strings.Join(selects, " UNION ALL ") + " ORDER BY " + columnTimeByFilter(filter, "eventTime") +
+		", typeMessage, seq, id LIMIT :limit OFFSET :offset"
+	args := append(argsByFilter(filter), sql.Named("limit", limit), sql.Named("offset", offset))
+
+	rows, err := o.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("fault read messages: {%v}", err)
+	}
+	defer rows.Close()
+
+	msgs := []StoredMessageT{}
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return msgs, nil
+}
+
+// Query of log tables by batches of maxCompoundSelect. Each batch reads its first offset+limit rows,
+// rows of batches are merged by compare, then offset and limit are applied. Return rows, error
+func queryByBatches[T any](parts []PartitionT, limit, offset int,
+	query func(parts []PartitionT, limit, offset int) ([]T, error), compare func(a, b T) int) ([]T, error) {
+
+	if len(parts) == 0 {
+		return []T{}, nil
+	}
+	if len(parts) <= maxCompoundSelect {
+		return query(parts, limit, offset)
+	}
+
+	var all []T
+	for batch := range slices.Chunk(parts, maxCompoundSelect) {
+		rows, err := query(batch, offset+limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, rows...)
+	}
+	slices.SortStableFunc(all, compare)
+
+	if offset >= len(all) {
+		return []T{}, nil
+	}
+	all = all[offset:]
+
+	return all[:min(limit, len(all))], nil
+}
+
+// Order of messages with the same order of the time: type, index of the log table, id
+func compareMessages(a, b StoredMessageT, byTime int) int {
+
+	switch {
+	case byTime != 0:
+		return byTime
+	case a.TypeMessage != b.TypeMessage:
+		return strings.Compare(a.TypeMessage, b.TypeMessage)
+	case a.NameTable != b.NameTable:
+		return cmp.Compare(indexFromName(a.NameTable), indexFromName(b.NameTable))
+	default:
+		return cmp.Compare(a.Id, b.Id)
+	}
+}
+
+// Scan the message read from a log table of SQLite. Extra columns after the message are scanned to extra
+func scanMessage(rows *sql.Rows, extra ...any) (StoredMessageT, error) {
+
+	var (
+		msg       StoredMessageT
+		seq       int64
+		ts, event string
+		attrs     sql.NullString
+		token     sql.NullString
+	)
+	dest := append([]any{&msg.TypeMessage, &msg.NameTable, &seq, &msg.Id, &msg.NameProject, &msg.LocationEvent, &msg.BodyMessage, &ts,
+		&event, &msg.Skewed, &attrs, &token}, extra...)
+	err := rows.Scan(dest...)
+	if err != nil {
+		return StoredMessageT{}, fmt.Errorf("fault scan message: {%v}", err)
+	}
+	msg.Timestamp, err = time.Parse(layoutTimestamp, ts)
+	if err != nil {
+		return StoredMessageT{}, fmt.Errorf("fault parse timestamp {%s}: {%v}", ts, err)
+	}
+	// fractional seconds of the event time are accepted by the layout
+	msg.EventTime, err = time.Parse(layoutTimestamp, event)
+	if err != nil {
+		return StoredMessageT{}, fmt.Errorf("fault parse event time {%s}: {%v}", event, err)
+	}
+	msg.Attributes, err = parseAttributes(attrs)
+	if err != nil {
+		return StoredMessageT{}, err
+	}
+	msg.TokenId = token.String
+
+	return msg, nil
+}
+
+// Types of log tables for reading
+func typesByFilter(typeMessage string) ([]string, error) {
+	switch typeMessage {
+	case "":
+		return []string{"I", "W", "E"}, nil
+	case "I", "W", "E":
+		return []string{typeMessage}, nil
+	default:
+		return nil, &FieldError{Field: "typeMessage", Description: fmt.Sprintf("not allowed type of message when reading: {%s}", typeMessage)}
+	}
+}
+
+// Log tables that can hold messages by filter: stored, not empty, time range is crossed
+func partitionsByFilter(catalog []PartitionT, types []string, filter FilterT) []PartitionT {
+
+	parts := []PartitionT{}
+	for _, p := range catalog {
+		if !slices.Contains(types, p.TypeTable) {
+			continue
+		}
+		if p.State != StateActive && p.State != StateClosed {
+			continue
+		}
+		if p.RowCount == 0 {
+			continue
+		}
+		// the catalog has only times of receiving
+		if filter.ByEventTime {
+			parts = append(parts, p)
+			continue
+		}
+		if !filter.TimeFrom.IsZero() && p.LastTime.Before(filter.TimeFrom.UTC().Truncate(time.Second)) {
+			continue
+		}
+		if !filter.TimeTo.IsZero() && p.FirstTime.After(filter.TimeTo.UTC()) {
+			continue
+		}
+		parts = append(parts, p)
+	}
+
+	return parts
+}
+
+// WHERE part of the query by filter. Parameters are named and shared by all log tables
+func whereByFilter(filter FilterT) string {
+
+	var conds []string
+	if filter.NameProject != "" {
+		conds = append(conds, "nameProject = :project")
+	}
+	if filter.LocationEvent != "" {
+		conds = append(conds, "locationEvent = :location")
+	}
+	if filter.BodySubstr != "" {
+		conds = append(conds, "instr(bodyMessage, :body) > 0")
+	}
+	if !filter.TimeFrom.IsZero() {
+		conds = append(conds, columnTimeByFilter(filter, "COALESCE(eventTime, timestamp)")+" >= :from")
+	}
+	if !filter.TimeTo.IsZero() {
+		conds = append(conds, columnTimeByFilter(filter, "COALESCE(eventTime, timestamp)")+" <= :to")
+	}
+	for i := range attributeKeys(filter.Attributes) {
+		conds = append(conds, fmt.Sprintf("json_extract(attributes, :attrKey%[1]d) = :attrValue%[1]d", i))
+	}
+
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// Column of time for ordering and time range: timestamp or the column of event time
+func columnTimeByFilter(filter FilterT, eventTime string) string {
+	if filter.ByEventTime {
+		return eventTime
+	}
+	return "timestamp"
+}
+
+// Limit and offset of reading by filter
+func limitsByFilter(filter FilterT) (limit, offset int) {
+
+	limit = filter.Limit
+	if limit <= 0 {
+		limit = defaultLimitRead
+	}
+	if limit > maxLimitRead {
+		limit = maxLimitRead
+	}
+	offset = filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// Arguments of the query by filter, without the limit and offset
+func argsByFilter(filter FilterT) []any {
+
+	var args []any
+	if filter.NameProject != "" {
+		args = append(args, sql.Named("project", filter.NameProject))
+	}
+	if filter.LocationEvent != "" {
+		args = append(args, sql.Named("location", filter.LocationEvent))
+	}
+	if filter.BodySubstr != "" {
+		args = append(args, sql.Named("body", filter.BodySubstr))
+	}
+	layout := layoutTimestamp
+	if filter.ByEventTime {
+		layout = layoutEventTime
+	}
+	if !filter.TimeFrom.IsZero() {
+		args = append(args, sql.Named("from", filter.TimeFrom.UTC().Format(layout)))
+	}
+	if !filter.TimeTo.IsZero() {
+		args = append(args, sql.Named("to", filter.TimeTo.UTC().Format(layout)))
+	}
+	for i, key := range attributeKeys(filter.Attributes) {
+		args = append(args,
+			sql.Named(fmt.Sprintf("attrKey%d", i), `$."`+key+`"`),
+			sql.Named(fmt.Sprintf("attrValue%d", i), filter.Attributes[key]))
+	}
+
+	return args
+}
+
+// Reading names of all log tables of the type from the schema. Return names sorted by index
+func readPartitionsName(db queryer, typeTable string) ([]string, error) {
+	if db == nil {
+		return nil, errors.New("missed db pointer")
+	}
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name GLOB ?", "log"+typeTable+"_*")
+	if err != nil {
+		return nil, fmt.Errorf("fault read names of log tables: {%v}", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("fault scan name of log table: {%v}", err)
+		}
+		if name == fmt.Sprintf("log%s_%d", typeTable, indexFromName(name)) {
+			names = append(names, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return indexFromName(names[i]) < indexFromName(names[j])
+	})
+
+	return names, nil
+}
+
+// Index of the log table from its name. Return 0 if the name not have the index
+func indexFromName(name string) int {
+
+	sl := strings.Split(name, "_")
+	if len(sl) != 2 {
+		return 0
+	}
+
+	index, err := strconv.Atoi(sl[1])
+	if err != nil || index < 1 {
+		return 0
+	}
+
+	return index
+}
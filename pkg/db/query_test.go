@@ -0,0 +1,246 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Open the in-memory SQLite database for tests
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // every connection has own in-memory database
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Settings of the storage for tests: max of log tables, the default window of deduplication
+func testConfig(maxI, maxW, maxE int64) ConfigT {
+	return ConfigT{MaxIdNumbI: maxI, MaxIdNumbW: maxW, MaxIdNumbE: maxE, DedupWindow: DefaultDedupWindow}
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Reading messages from all log tables by filter
+func Test_ReadingMessages_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(2, 2, 2)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	// 5 I messages -> logI_1, logI_2 and logI_3 are used
+	for _, body := range []string{"one", "two", "three", "four", "connection refused"} {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: body})
+		require.NoError(t, err)
+	}
+	_, err = instAct.SavingMessage(MessageT{TypeMessage: "E", NameProject: "beta", LocationEvent: "main.go:2", BodyMessage: "connection refused"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		nameTest   string
+		filter     FilterT
+		wantBodies []string
+		wantTables []string
+	}{
+		{
+			nameTest:   "All I across partitions",
+			filter:     FilterT{TypeMessage: "I"},
+			wantBodies: []string{"one", "two", "three", "four", "connection refused"},
+			wantTables: []string{"logI_1", "logI_1", "logI_1", "logI_2", "logI_2"},
+		},
+		{
+			nameTest:   "Body substring in all types",
+			filter:     FilterT{BodySubstr: "refused"},
+			wantBodies: []string{"connection refused", "connection refused"},
+			wantTables: []string{"logE_1", "logI_2"},
+		},
+		{
+			nameTest:   "Project",
+			filter:     FilterT{NameProject: "beta"},
+			wantBodies: []string{"connection refused"},
+			wantTables: []string{"logE_1"},
+		},
+		{
+			nameTest:   "Location not found",
+			filter:     FilterT{LocationEvent: "main.go:3"},
+			wantBodies: []string{},
+			wantTables: []string{},
+		},
+		{
+			nameTest:   "Limit and offset",
+			filter:     FilterT{TypeMessage: "I", Limit: 2, Offset: 1},
+			wantBodies: []string{"two", "three"},
+			wantTables: []string{"logI_1", "logI_1"},
+		},
+		{
+			nameTest:   "Time range",
+			filter:     FilterT{TypeMessage: "W", TimeFrom: time.Now().Add(-time.Hour), TimeTo: time.Now().Add(time.Hour)},
+			wantBodies: []string{},
+			wantTables: []string{},
+		},
+		{
+			nameTest:   "Time range in the future",
+			filter:     FilterT{TimeFrom: time.Now().Add(time.Hour)},
+			wantBodies: []string{},
+			wantTables: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			msgs, err := instAct.ReadingMessages(tt.filter)
+			require.NoError(t, err)
+
+			bodies := []string{}
+			tables := []string{}
+			for _, msg := range msgs {
+				bodies = append(bodies, msg.BodyMessage)
+				tables = append(tables, msg.NameTable)
+				assert.False(t, msg.Timestamp.IsZero())
+			}
+			assert.Equal(t, tt.wantBodies, bodies)
+			assert.Equal(t, tt.wantTables, tables)
+		})
+	}
+}
+
+// Test - Reading more log tables than SELECTs of one compound query: log tables are read by batches
+func Test_ReadingMessages_Batches_SUCCESS(t *testing.T) {
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, testConfig(1, 1, 1))
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	// every 2 messages -> a new log table: 1100 messages, more than 500 log tables
+	msgs := make([]MessageT, 1100)
+	for i := range msgs {
+		msgs[i] = MessageT{TypeMessage: "I", NameProject: "alpha", LocationEvent: "main.go:1", BodyMessage: fmt.Sprintf("message %04d", i)}
+	}
+	_, errs, err := instAct.SavingMessages(msgs)
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	require.Greater(t, len(parts), maxCompoundSelect)
+
+	read, err := instAct.ReadingMessages(FilterT{TypeMessage: "I", Limit: 3, Offset: 1098})
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "message 1098", read[0].BodyMessage)
+	assert.Equal(t, "message 1099", read[1].BodyMessage)
+
+	read, err = instAct.ReadingMessages(FilterT{NameProject: "alpha", Limit: maxLimitRead, Offset: 50})
+	require.NoError(t, err)
+	require.Len(t, read, maxLimitRead)
+	for i, msg := range read {
+		assert.Equal(t, fmt.Sprintf("message %04d", i+50), msg.BodyMessage)
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Reading messages from all log tables by filter
+func Test_ReadingMessages_FAULT(t *testing.T) {
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	instAct, err := RepoDB(db, testConfig(10, 10, 10))
+	require.NoError(t, err)
+
+	_, err = instAct.ReadingMessages(FilterT{TypeMessage: "T"})
+	require.Error(t, err)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - WHERE part of the query by filter
+func Test_whereByFilter_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest string
+		filter   FilterT
+		want     string
+	}{
+		{
+			nameTest: "Empty filter",
+			filter:   FilterT{},
+			want:     "",
+		},
+		{
+			nameTest: "Project and body",
+			filter:   FilterT{NameProject: "alpha", BodySubstr: "refused"},
+			want:     " WHERE nameProject = :project AND instr(bodyMessage, :body) > 0",
+		},
+		{
+			nameTest: "Time range",
+			filter:   FilterT{TimeFrom: time.Now(), TimeTo: time.Now()},
+			want:     " WHERE timestamp >= :from AND timestamp <= :to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			assert.Equal(t, tt.want, whereByFilter(tt.filter))
+		})
+	}
+}
+
+// Test - Reading names of all log tables of the type
+func Test_readPartitionsName_SUCCESS(t *testing.T) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name FROM sqlite_master").
+		WithArgs("logI_*").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow("logI_10").
+			AddRow("logI_2").
+			AddRow("logI_x").
+			AddRow("logI_1"))
+
+	names, err := readPartitionsName(db, "I")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logI_1", "logI_2", "logI_10"}, names)
+}
+
+// Test - Index of the log table from its name
+func Test_indexFromName_SUCCESS(t *testing.T) {
+
+	assert.Equal(t, 12, indexFromName("logE_12"))
+	assert.Equal(t, 0, indexFromName("logE"))
+	assert.Equal(t, 0, indexFromName("logE_x"))
+}
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Retention of log tables of one type. Zero fields are not used.
+type RetentionT struct {
+	KeepLast int           // number of the newest log tables to keep, with the current one
+	MaxAge   time.Duration // closed log tables older are removed
+}
+
+// Retention policy of log tables
+type RetentionPolicyT struct {
+	ByType     map[string]RetentionT // key - I, W, E
+	DirArchive string                // log tables are exported here before removing. Empty - no export
+}
+
+// Message in the archive file
+type archiveMessageT struct {
+	Id            int64             `json:"id"`
+	TypeMessage   string            `json:"typeMessage"`
+	NameProject   string            `json:"nameProject"`
+	LocationEvent string            `json:"locationEvent"`
+	BodyMessage   string            `json:"bodyMessage"`
+	Timestamp     string            `json:"timestamp"`
+	EventTime     string            `json:"eventTime,omitempty"`
+	Skewed        bool              `json:"skewed,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	TokenId       string            `json:"tokenId,omitempty"`
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Removing the expired log tables by policy. Return removed (dryRun - to be removed) log tables, error
+func (o ObjectDB) ApplyRetention(policy RetentionPolicyT, dryRun bool) ([]PartitionT, error) {
+
+	parts, err := readPartitions(o.DB)
+	if err != nil {
+		return nil, fmt.Errorf("fault read registry: {%v}", err)
+	}
+
+	return applyRetention(parts, policy, dryRun,
+		func(p PartitionT) error {
+			_, err := exportPartition(o.DB, p, policy.DirArchive)
+			return err
+		},
+		func(p PartitionT, state string) error {
+			return o.inWriteTx(func(tx *sql.Tx) error {
+				return dropPartition(tx, p.NameTable, state)
+			})
+		})
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Selection of the expired log tables. The active log table is never expired.
+// Age of log table is counted from its last message
+func expiredPartitions(parts []PartitionT, policy RetentionPolicyT, now time.Time) []PartitionT {
+
+	byType := make(map[string][]PartitionT)
+	for _, p := range parts {
+		if p.State != StateActive && p.State != StateClosed {
+			continue
+		}
+		byType[p.TypeTable] = append(byType[p.TypeTable], p)
+	}
+
+	expired := []PartitionT{}
+	for _, typeTable := range []string{"I", "W", "E"} {
+
+		rule, ok := policy.ByType[typeTable]
+		if !ok {
+			continue
+		}
+		list := byType[typeTable] // sorted by index
+
+		for i, p := range list {
+			if p.State != StateClosed {
+				continue
+			}
+			last := p.LastTime
+			if last.IsZero() {
+				last = p.TimeClose
+			}
+			newer := len(list) - i // number of log tables from this to the current
+			switch {
+			case rule.KeepLast > 0 && newer > rule.KeepLast:
+				expired = append(expired, p)
+			case rule.MaxAge > 0 && now.Sub(last) > rule.MaxAge:
+				expired = append(expired, p)
+			}
+		}
+	}
+
+	return expired
+}
+
+// Removing the expired log tables of the catalog by policy. Log tables are exported (if DirArchive is set),
+// then dropped with the state: archived or dropped
+func applyRetention(parts []PartitionT, policy RetentionPolicyT, dryRun bool,
+	export func(p PartitionT) error, drop func(p PartitionT, state string) error) ([]PartitionT, error) {
+
+	expired := expiredPartitions(parts, policy, time.Now())
+	if dryRun {
+		return expired, nil
+	}
+
+	removed := []PartitionT{}
+	for _, p := range expired {
+
+		// closed log tables are not written, so export is done without the lock
+		if policy.DirArchive != "" {
+			err := export(p)
+			if err != nil {
+				return removed, fmt.Errorf("fault export {%s}: {%v}", p.NameTable, err)
+			}
+		}
+
+		state := StateDropped
+		if policy.DirArchive != "" {
+			state = StateArchived
+		}
+		err := drop(p, state)
+		if err != nil {
+			return removed, fmt.Errorf("fault drop {%s}: {%v}", p.NameTable, err)
+		}
+		p.State = state
+		removed = append(removed, p)
+	}
+
+	return removed, nil
+}
+
+// Export messages of the log table to the gzip file of JSON lines. Return path of file
+func exportPartition(db queryer, p PartitionT, dir string) (string, error) {
+	if db == nil {
+		return "", errors.New("missed db pointer")
+	}
+
+	return writeArchive(dir, p.NameTable, func(enc *json.Encoder) error {
+
+		rows, err := db.Query(fmt.Sprintf(`SELECT id, nameProject, locationEvent, bodyMessage, timestamp, eventTime, skewed, attributes, tokenId FROM "%s" ORDER BY id`, p.NameTable))
+		if err != nil {
+			return fmt.Errorf("fault read {%s}: %v", p.NameTable, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				msg   = archiveMessageT{TypeMessage: p.TypeTable}
+				event sql.NullString
+				attrs sql.NullString
+				token sql.NullString
+			)
+			err := rows.Scan(&msg.Id, &msg.NameProject, &msg.LocationEvent, &msg.BodyMessage, &msg.Timestamp, &event, &msg.Skewed, &attrs, &token)
+			if err != nil {
+				return fmt.Errorf("fault scan message: %v", err)
+			}
+			msg.EventTime = event.String
+			msg.TokenId = token.String
+			msg.Attributes, err = parseAttributes(attrs)
+			if err != nil {
+				return err
+			}
+			err = enc.Encode(msg)
+			if err != nil {
+				return fmt.Errorf("fault write message: %v", err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("fault read rows: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// Writing the archive file <name>_<time>.jsonl.gz: messages are encoded by write into the temp file,
+// which is renamed after sync. Return path of file
+func writeArchive(dir, name string, write func(enc *json.Encoder) error) (string, error) {
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("fault create dir {%s}: %v", dir, err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.jsonl.gz", name, time.Now().UTC().Format("20060102T150405")))
+	f, err := os.CreateTemp(dir, name+"_*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("fault create file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	err = write(json.NewEncoder(zw))
+	if err != nil {
+		return "", err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return "", fmt.Errorf("fault close gzip: %v", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		return "", fmt.Errorf("fault sync file: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return "", fmt.Errorf("fault close file: %v", err)
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return "", fmt.Errorf("fault rename file: %v", err)
+	}
+
+	return path, nil
+}
+
+// Drop the closed log table. It stays in the catalog with the state: archived or dropped
+func dropPartition(db queryer, name, state string) error {
+	if db == nil {
+		return errors.New("missed db pointer")
+	}
+
+	res, err := db.Exec("UPDATE partitions SET state = ? WHERE nameTable = ? AND state = ?", state, name, StateClosed)
+	if err != nil {
+		return fmt.Errorf("fault set state of {%s}: %v", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error get RowsAffected after update: {%v}", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("the {%s} table is not closed and can not be dropped", name)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", ftsName(name)))
+	if err != nil {
+		return fmt.Errorf("fault drop full-text index of {%s}: %v", name, err)
+	}
+	_, err = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", name))
+	if err != nil {
+		return fmt.Errorf("fault drop table {%s}: %v", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Removing the expired log tables by policy
+func Test_ApplyRetention_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(1, 10, 10)
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, cfg)
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	// logI_1, logI_2, logI_3 are closed with 2 messages, logI_4 is current
+	for i := 0; i < 6; i++ {
+		_, err := instAct.SavingMessage(MessageT{TypeMessage: "I", NameProject: "project", LocationEvent: "main.go:1", BodyMessage: "msg"})
+		require.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+	policy := RetentionPolicyT{
+		ByType:     map[string]RetentionT{"I": {KeepLast: 2}, "E": {MaxAge: time.Hour}},
+		DirArchive: dir,
+	}
+
+	// dry run
+	expired, err := instAct.ApplyRetention(policy, true)
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	assert.Equal(t, "logI_1", expired[0].NameTable)
+	assert.Equal(t, "logI_2", expired[1].NameTable)
+	names, err := readPartitionsName(db, "I")
+	require.NoError(t, err)
+	assert.Len(t, names, 4)
+
+	// removing
+	removed, err := instAct.ApplyRetention(policy, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	assert.Equal(t, "logI_1", removed[0].NameTable)
+	assert.Equal(t, "logI_2", removed[1].NameTable)
+
+	names, err = readPartitionsName(db, "I")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logI_3", "logI_4"}, names)
+
+	// removed log tables stay in the catalog
+	parts, err := instAct.Partitions()
+	require.NoError(t, err)
+	require.Len(t, parts, 6)
+	assert.Equal(t, "logI_1", parts[1].NameTable)
+	assert.Equal(t, StateArchived, parts[1].State)
+	assert.Equal(t, StateArchived, parts[2].State)
+	assert.Equal(t, StateClosed, parts[3].State)
+	assert.Equal(t, StateArchived, removed[0].State)
+
+	// archive
+	files, err := filepath.Glob(filepath.Join(dir, "logI_1_*.jsonl.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var msgs []archiveMessageT
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		var msg archiveMessageT
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &msg))
+		msgs = append(msgs, msg)
+	}
+	require.NoError(t, sc.Err())
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "I", msgs[0].TypeMessage)
+	assert.Equal(t, int64(2), msgs[1].Id)
+
+	// nothing to remove
+	removed, err = instAct.ApplyRetention(policy, false)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Selection of the expired log tables
+func Test_expiredPartitions_SUCCESS(t *testing.T) {
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	parts := []PartitionT{
+		{TypeTable: "E", NameTable: "logE_1", Seq: 1, State: StateClosed, TimeClose: now.Add(-200 * day)},
+		{TypeTable: "E", NameTable: "logE_2", Seq: 2, State: StateClosed, TimeClose: now.Add(-10 * day)},
+		{TypeTable: "E", NameTable: "logE_3", Seq: 3, State: StateActive},
+		{TypeTable: "I", NameTable: "logI_1", Seq: 1, State: StateClosed, TimeClose: now.Add(-3 * day)},
+		{TypeTable: "I", NameTable: "logI_2", Seq: 2, State: StateClosed, TimeClose: now.Add(-2 * day)},
+		{TypeTable: "I", NameTable: "logI_3", Seq: 3, State: StateClosed, TimeClose: now.Add(-1 * day)},
+		{TypeTable: "I", NameTable: "logI_4", Seq: 4, State: StateActive},
+		{TypeTable: "W", NameTable: "logW_1", Seq: 1, State: StateClosed, TimeClose: now.Add(-900 * day)},
+		{TypeTable: "W", NameTable: "logW_2", Seq: 2, State: StateActive},
+		{TypeTable: "W", NameTable: "logW_0", Seq: 0, State: StateDropped, TimeClose: now.Add(-999 * day)},
+	}
+
+	tests := []struct {
+		nameTest string
+		policy   RetentionPolicyT
+		want     []string
+	}{
+		{
+			nameTest: "Empty policy",
+			policy:   RetentionPolicyT{},
+			want:     []string{},
+		},
+		{
+			nameTest: "Keep last 2 I",
+			policy:   RetentionPolicyT{ByType: map[string]RetentionT{"I": {KeepLast: 2}}},
+			want:     []string{"logI_1", "logI_2"},
+		},
+		{
+			nameTest: "Keep last 1 never removes the current",
+			policy:   RetentionPolicyT{ByType: map[string]RetentionT{"W": {KeepLast: 1}, "I": {KeepLast: 1}}},
+			want:     []string{"logI_1", "logI_2", "logI_3", "logW_1"},
+		},
+		{
+			nameTest: "Keep E 180 days",
+			policy:   RetentionPolicyT{ByType: map[string]RetentionT{"E": {MaxAge: 180 * day}}},
+			want:     []string{"logE_1"},
+		},
+		{
+			nameTest: "Keep last and age together",
+			policy:   RetentionPolicyT{ByType: map[string]RetentionT{"I": {KeepLast: 3, MaxAge: 36 * time.Hour}}},
+			want:     []string{"logI_1", "logI_2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			names := []string{}
+			for _, p := range expiredPartitions(parts, tt.policy, now) {
+				names = append(names, p.NameTable)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Drop the closed log table. The current log table is not dropped
+func Test_dropPartition_FAULT(t *testing.T) {
+
+	db := openTestDB(t)
+	instAct, err := RepoDB(db, testConfig(10, 10, 10))
+	require.NoError(t, err)
+	require.NoError(t, instAct.Tables())
+
+	err = dropPartition(db, "logW_1", StateDropped)
+	require.Error(t, err)
+
+	names, err := readPartitionsName(db, "W")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logW_1"}, names)
+}
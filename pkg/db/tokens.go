@@ -0,0 +1,311 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Operations allowed by tokens
+const (
+	OperationWrite = "write" // saving messages
+	OperationRead  = "read"  // queries, search, tail
+	OperationAdmin = "admin" // the admin service: retention, catalog, tokens
+)
+
+const (
+	AllProjects      = "*" // any project
+	prefixToken      = "iwe_"
+	maxSizeTokenName = 128
+)
+
+// Token of the API. The secret is shown once on creation, only its SHA-256 hash is stored
+type TokenT struct {
+	Id         string   // public id, saved with messages of the token
+	Name       string   // owner or service of the token
+	Projects   []string // AllProjects - any project
+	Operations []string // OperationWrite, OperationRead, OperationAdmin
+	TimeCreate time.Time
+	TimeExpire time.Time // zero - never
+	TimeRevoke time.Time // zero - not revoked
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Check the token is not revoked and not expired at the time
+func (t TokenT) Active(now time.Time) bool {
+	return t.TimeRevoke.IsZero() && (t.TimeExpire.IsZero() || now.Before(t.TimeExpire))
+}
+
+// Check the token allows the operation
+func (t TokenT) Allows(operation string) bool {
+	return slices.Contains(t.Operations, operation)
+}
+
+// Creating the token in the database of SQLite. Return created token, secret, error
+func (o ObjectDB) CreateToken(token TokenT) (TokenT, string, error) {
+
+	token, secret, hash, err := newToken(token)
+	if err != nil {
+		return TokenT{}, "", err
+	}
+
+	err = o.inWriteTx(func(tx *sql.Tx) error {
+		return insertToken(tx, token, hash)
+	})
+	if err != nil {
+		return TokenT{}, "", err
+	}
+
+	return token, secret, nil
+}
+
+// Reading all tokens. Return tokens sorted by time of creation, error
+func (o ObjectDB) ListTokens() ([]TokenT, error) {
+	return readTokens(o.DB, "", nil)
+}
+
+// Revoking the token by id. Return the token, error (ErrNotFound)
+func (o ObjectDB) RevokeToken(id string) (TokenT, error) {
+
+	var token TokenT
+	err := o.inWriteTx(func(tx *sql.Tx) error {
+		var err error
+		token, err = revokeToken(tx, id, time.Now())
+		return err
+	})
+	if err != nil {
+		return TokenT{}, err
+	}
+
+	return token, nil
+}
+
+// Reading the token by the secret. Revoked and expired tokens are returned too. Return token, error (ErrNotFound)
+func (o ObjectDB) TokenBySecret(secret string) (TokenT, error) {
+	return tokenByHash(o.DB, hashSecret(secret))
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Check fields of the new token
+func checkToken(token TokenT) error {
+
+	if token.Name == "" || len(token.Name) > maxSizeTokenName {
+		return &FieldError{Field: "name", Description: fmt.Sprintf("not allowed length of the name of token: allowed 1..{%d} bytes", maxSizeTokenName)}
+	}
+	if len(token.Projects) == 0 {
+		return &FieldError{Field: "projects", Description: "empty projects of token"}
+	}
+	for _, project := range token.Projects {
+		if project == "" {
+			return &FieldError{Field: "projects", Description: "empty project of token"}
+		}
+	}
+	if len(token.Operations) == 0 {
+		return &FieldError{Field: "operations", Description: "empty operations of token"}
+	}
+	for _, op := range token.Operations {
+		switch op {
+		case OperationWrite, OperationRead, OperationAdmin:
+		default:
+			return &FieldError{Field: "operations", Description: fmt.Sprintf("not allowed operation {%s}: allowed write, read, admin", op)}
+		}
+	}
+	// the admin service works with all projects
+	if slices.Contains(token.Operations, OperationAdmin) && !slices.Contains(token.Projects, AllProjects) {
+		return &FieldError{Field: "operations", Description: fmt.Sprintf("not allowed operation {admin}: allowed with projects {%s}", AllProjects)}
+	}
+
+	return nil
+}
+
+// New token with random id and secret. Return token, secret, hash of the secret, error
+func newToken(token TokenT) (TokenT, string, string, error) {
+
+	err := checkToken(token)
+	if err != nil {
+		return TokenT{}, "", "", err
+	}
+
+	id := make([]byte, 8)
+	key := make([]byte, 32)
+	_, err = rand.Read(id)
+	if err == nil {
+		_, err = rand.Read(key)
+	}
+	if err != nil {
+		return TokenT{}, "", "", fmt.Errorf("fault generate token: {%v}", err)
+	}
+
+	token.Id = hex.EncodeToString(id)
+	token.TimeCreate = time.Now().UTC().Truncate(time.Millisecond)
+	token.TimeRevoke = time.Time{}
+	if !token.TimeExpire.IsZero() {
+		token.TimeExpire = token.TimeExpire.UTC().Truncate(time.Millisecond)
+	}
+	secret := prefixToken + base64.RawURLEncoding.EncodeToString(key)
+
+	return token, secret, hashSecret(secret), nil
+}
+
+// SHA-256 of the secret of token. Secrets are random, so the hash is not salted
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create the table of tokens. The same query for SQLite and PostgreSQL
+func checkCreateTokensTable(db queryer) error {
+	if db == nil {
+		return errors.New("fault check create table tokens -> not pointer db")
+	}
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS tokens (
+	id TEXT PRIMARY KEY,
+	hash TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	projects TEXT NOT NULL,
+	operations TEXT NOT NULL,
+	timeCreate BIGINT NOT NULL,
+	timeExpire BIGINT,
+	timeRevoke BIGINT);
+	`)
+	if err != nil {
+		return fmt.Errorf("table {tokens} is not created: %v", err)
+	}
+
+	return nil
+}
+
+// Saving the token with the hash of its secret
+func insertToken(db queryer, token TokenT, hash string) error {
+
+	projects, err := json.Marshal(token.Projects)
+	if err != nil {
+		return fmt.Errorf("fault encode projects of token: {%v}", err)
+	}
+	operations, err := json.Marshal(token.Operations)
+	if err != nil {
+		return fmt.Errorf("fault encode operations of token: {%v}", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO tokens (id, hash, name, projects, operations, timeCreate, timeExpire)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.Id, hash, token.Name, string(projects), string(operations), token.TimeCreate.UnixMilli(), unixMilliOrNull(token.TimeExpire))
+	if err != nil {
+		return fmt.Errorf("fault save token {%s}: {%w}", token.Id, err)
+	}
+
+	return nil
+}
+
+// Revoking the token. The time of revoking of the revoked token is not changed. Return token, error (ErrNotFound)
+func revokeToken(db queryer, id string, now time.Time) (TokenT, error) {
+
+	_, err := db.Exec("UPDATE tokens SET timeRevoke = $1 WHERE id = $2 AND timeRevoke IS NULL", now.UnixMilli(), id)
+	if err != nil {
+		return TokenT{}, fmt.Errorf("fault revoke token {%s}: {%w}", id, err)
+	}
+
+	tokens, err := readTokens(db, "WHERE id = $1", []any{id})
+	if err != nil {
+		return TokenT{}, err
+	}
+	if len(tokens) == 0 {
+		return TokenT{}, fmt.Errorf("token {%s}: {%w}", id, ErrNotFound)
+	}
+
+	return tokens[0], nil
+}
+
+// Reading the token by the hash of its secret. Return token, error (ErrNotFound)
+func tokenByHash(db queryer, hash string) (TokenT, error) {
+
+	tokens, err := readTokens(db, "WHERE hash = $1", []any{hash})
+	if err != nil {
+		return TokenT{}, err
+	}
+	if len(tokens) == 0 {
+		return TokenT{}, fmt.Errorf("token: {%w}", ErrNotFound)
+	}
+
+	return tokens[0], nil
+}
+
+// Reading tokens by the condition. Return tokens sorted by time of creation, error
+func readTokens(db queryer, where string, args []any) ([]TokenT, error) {
+	if db == nil {
+		return nil, errors.New("missed db pointer")
+	}
+
+	rows, err := db.Query("SELECT id, name, projects, operations, timeCreate, timeExpire, timeRevoke FROM tokens "+
+		where+" ORDER BY timeCreate, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("fault read tokens: {%w}", err)
+	}
+	defer rows.Close()
+
+	tokens := []TokenT{}
+	for rows.Next() {
+		var (
+			token                TokenT
+			projects, operations string
+			create               int64
+			expire, revoke       sql.NullInt64
+		)
+		err := rows.Scan(&token.Id, &token.Name, &projects, &operations, &create, &expire, &revoke)
+		if err != nil {
+			return nil, fmt.Errorf("fault scan token: {%v}", err)
+		}
+		err = json.Unmarshal([]byte(projects), &token.Projects)
+		if err != nil {
+			return nil, fmt.Errorf("fault parse projects of token {%s}: {%v}", token.Id, err)
+		}
+		err = json.Unmarshal([]byte(operations), &token.Operations)
+		if err != nil {
+			return nil, fmt.Errorf("fault parse operations of token {%s}: {%v}", token.Id, err)
+		}
+		token.TimeCreate = time.UnixMilli(create).UTC()
+		if expire.Valid {
+			token.TimeExpire = time.UnixMilli(expire.Int64).UTC()
+		}
+		if revoke.Valid {
+			token.TimeRevoke = time.UnixMilli(revoke.Int64).UTC()
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fault read rows: {%v}", err)
+	}
+
+	return tokens, nil
+}
+
+// Unix milliseconds of the time for the column. Zero - NULL
+func unixMilliOrNull(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UnixMilli()
+}
+
+// Id of the token for the column of log tables. Empty - NULL
+func tokenIdOrNull(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Tokens are created, found by the secret, listed and revoked; ids of tokens are saved with messages. SQLite and memory
+func Test_Tokens_SUCCESS(t *testing.T) {
+
+	cfg := testConfig(10, 10, 10)
+
+	tests := []struct {
+		nameTest string
+		repo     func(t *testing.T) ActionsDB
+	}{
+		{
+			nameTest: "SQLite",
+			repo: func(t *testing.T) ActionsDB {
+				instAct, err := RepoDB(openTestDB(t), cfg)
+				require.NoError(t, err)
+				return instAct
+			},
+		},
+		{
+			nameTest: "Memory",
+			repo: func(t *testing.T) ActionsDB {
+				return RepoMem(cfg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			instAct := tt.repo(t)
+			require.NoError(t, instAct.Tables())
+
+			expire := time.Now().Add(time.Hour)
+			token, secret, err := instAct.CreateToken(TokenT{Name: "ci", Projects: []string{"alpha"}, Operations: []string{OperationWrite}, TimeExpire: expire})
+			require.NoError(t, err)
+			assert.Len(t, token.Id, 16)
+			assert.True(t, strings.HasPrefix(secret, prefixToken))
+			assert.WithinDuration(t, expire, token.TimeExpire, time.Millisecond)
+
+			other, _, err := instAct.CreateToken(TokenT{Name: "ops", Projects: []string{AllProjects}, Operations: []string{OperationRead, OperationAdmin}})
+			require.NoError(t, err)
+
+			found, err := instAct.TokenBySecret(secret)
+			require.NoError(t, err)
+			assert.Equal(t, token, found)
+			assert.True(t, found.Active(time.Now()))
+			assert.False(t, found.Active(expire.Add(time.Second)))
+			assert.True(t, found.Allows(OperationWrite))
+			assert.False(t, found.Allows(OperationRead))
+
+			_, err = instAct.TokenBySecret(secret + "x")
+			assert.True(t, errors.Is(err, ErrNotFound))
+
+			tokens, err := instAct.ListTokens()
+			require.NoError(t, err)
+			require.Len(t, tokens, 2)
+			assert.ElementsMatch(t, []string{token.Id, other.Id}, []string{tokens[0].Id, tokens[1].Id})
+
+			revoked, err := instAct.RevokeToken(token.Id)
+			require.NoError(t, err)
+			assert.False(t, revoked.TimeRevoke.IsZero())
+			assert.False(t, revoked.Active(time.Now()))
+			again, err := instAct.RevokeToken(token.Id)
+			require.NoError(t, err)
+			assert.Equal(t, revoked.TimeRevoke, again.TimeRevoke)
+			found, err = instAct.TokenBySecret(secret)
+			require.NoError(t, err)
+			assert.False(t, found.Active(time.Now()))
+
+			// the id of token is saved with the message
+			_, err = instAct.SavingMessage(MessageT{TypeMessage: "E", NameProject: "alpha", LocationEvent: "l", BodyMessage: "b", TokenId: token.Id})
+			require.NoError(t, err)
+			_, err = instAct.SavingMessage(MessageT{TypeMessage: "E", NameProject: "alpha", LocationEvent: "l", BodyMessage: "c"})
+			require.NoError(t, err)
+			msgs, err := instAct.ReadingMessages(FilterT{TypeMessage: "E"})
+			require.NoError(t, err)
+			require.Len(t, msgs, 2)
+			assert.Equal(t, token.Id, msgs[0].TokenId)
+			assert.Equal(t, "", msgs[1].TokenId)
+		})
+	}
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Not allowed tokens and unknown ids
+func Test_Tokens_FAULT(t *testing.T) {
+
+	instAct := RepoMem(testConfig(10, 10, 10))
+
+	tests := []struct {
+		nameTest string
+		token    TokenT
+		field    string
+	}{
+		{nameTest: "Empty name", token: TokenT{Projects: []string{"p"}, Operations: []string{OperationRead}}, field: "name"},
+		{nameTest: "Long name", token: TokenT{Name: strings.Repeat("n", maxSizeTokenName+1), Projects: []string{"p"}, Operations: []string{OperationRead}}, field: "name"},
+		{nameTest: "No projects", token: TokenT{Name: "n", Operations: []string{OperationRead}}, field: "projects"},
+		{nameTest: "Empty project", token: TokenT{Name: "n", Projects: []string{""}, Operations: []string{OperationRead}}, field: "projects"},
+		{nameTest: "No operations", token: TokenT{Name: "n", Projects: []string{"p"}}, field: "operations"},
+		{nameTest: "Unknown operation", token: TokenT{Name: "n", Projects: []string{"p"}, Operations: []string{"delete"}}, field: "operations"},
+		{nameTest: "Admin of projects", token: TokenT{Name: "n", Projects: []string{"p"}, Operations: []string{OperationAdmin}}, field: "operations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+			_, _, err := instAct.CreateToken(tt.token)
+			var errField *FieldError
+			require.True(t, errors.As(err, &errField))
+			assert.Equal(t, tt.field, errField.Field)
+		})
+	}
+
+	_, err := instAct.RevokeToken("unknown")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
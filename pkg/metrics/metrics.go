@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "netlogiwe"
+
+// Reasons of not stored messages
+const (
+	ReasonInvalid     = "invalid"     // not allowed message, InvalidArgument
+	ReasonDuplicate   = "duplicate"   // id of the message is repeated within the window
+	ReasonUnavailable = "unavailable" // temporary fault of the storage
+	ReasonInternal    = "internal"    // other faults
+)
+
+// Value of labels of the not allowed message: unknown type or empty project
+const LabelInvalid = "invalid"
+
+// Value of the project label of projects over maxProjectLabels
+const LabelOther = "other"
+
+// Projects with own label values, the first ones seen. Other projects are counted as LabelOther
+const maxProjectLabels = 100
+
+// Limits of throttled messages
+const (
+	LimitRate          = "rate"           // token bucket of the project
+	LimitDailyMessages = "daily_messages" // quota of messages per day
+	LimitDailyBytes    = "daily_bytes"    // quota of bytes per day
+)
+
+// Registry of the metrics of the server: series below, Go runtime and process
+var Registry = prometheus.NewRegistry()
+
+// Projects with own label values
+var projectLabels = struct {
+	mu  sync.Mutex
+	set map[string]struct{}
+}{set: make(map[string]struct{})}
+
+var (
+	messagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Messages received by the server.",
+	}, []string{"type", "project"})
+
+	messagesStored = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_stored_total",
+		Help:      "Messages saved in the storage.",
+	}, []string{"type", "project"})
+
+	messagesRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_rejected_total",
+		Help:      "Messages not saved in the storage by reason: invalid, duplicate, unavailable, internal.",
+	}, []string{"type", "project", "reason"})
+
+	messagesThrottled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_throttled_total",
+		Help:      "Messages rejected with ResourceExhausted by limit: rate, daily_messages, daily_bytes.",
+	}, []string{"type", "project", "limit"})
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Handled gRPC requests by service, method and status code.",
+	}, []string{"service", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of gRPC requests by service and method. Streams - from the start to the end.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	dbWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_write_duration_seconds",
+		Help:      "Latency of write transactions of the storage by driver, with waiting of the write lock.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"driver"})
+
+	rotations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rotations_total",
+		Help:      "Changes of the current log table of the type by overload.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		messagesReceived, messagesStored, messagesRejected, messagesThrottled,
+		requests, requestDuration,
+		dbWriteDuration, rotations,
+	)
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// HTTP handler of the metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Counting the received message
+func MessageReceived(typeMessage, project string) {
+	typeMessage, project = messageLabels(typeMessage, project)
+	messagesReceived.WithLabelValues(typeMessage, project).Inc()
+}
+
+// Counting the stored message
+func MessageStored(typeMessage, project string) {
+	typeMessage, project = messageLabels(typeMessage, project)
+	messagesStored.WithLabelValues(typeMessage, project).Inc()
+}
+
+// Counting the not stored message by reason
+func MessageRejected(typeMessage, project, reason string) {
+	typeMessage, project = messageLabels(typeMessage, project)
+	messagesRejected.WithLabelValues(typeMessage, project, reason).Inc()
+}
+
+// Counting the message throttled by the limit
+func MessageThrottled(typeMessage, project, limit string) {
+	typeMessage, project = messageLabels(typeMessage, project)
+	messagesThrottled.WithLabelValues(typeMessage, project, limit).Inc()
+}
+
+// Observation of the write transaction of the storage driver
+func ObserveDBWrite(driver string, d time.Duration) {
+	dbWriteDuration.WithLabelValues(driver).Observe(d.Seconds())
+}
+
+// Counting the change of the current log table of the type
+func Rotated(typeTable string) {
+	rotations.WithLabelValues(typeTable).Inc()
+}
+
+// Interceptor of unary RPCs: count by status code and latency
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeRequest(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// Interceptor of stream RPCs: count by status code and duration of the stream
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	start := time.Now()
+	err := handler(srv, ss)
+	observeRequest(info.FullMethod, start, err)
+
+	return err
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Labels of the message by raw fields of the request: the not allowed message is counted as invalid,
+// projects over maxProjectLabels - as other. Return type, project
+func messageLabels(typeMessage, project string) (string, string) {
+
+	switch typeMessage {
+	case "I", "W", "E", "T":
+	default:
+		return LabelInvalid, LabelInvalid
+	}
+	if project == "" {
+		return LabelInvalid, LabelInvalid
+	}
+
+	projectLabels.mu.Lock()
+	defer projectLabels.mu.Unlock()
+
+	if _, ok := projectLabels.set[project]; ok {
+		return typeMessage, project
+	}
+	if len(projectLabels.set) >= maxProjectLabels {
+		return typeMessage, LabelOther
+	}
+	projectLabels.set[project] = struct{}{}
+
+	return typeMessage, project
+}
+
+// Observation of the finished RPC by the full name: /apigrps.iwe/SaveMessage
+func observeRequest(fullMethod string, start time.Time, err error) {
+
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	requests.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	requestDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
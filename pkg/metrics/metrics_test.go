@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Interceptors count RPCs by service, method and code
+func Test_Interceptors_SUCCESS(t *testing.T) {
+
+	before := testutil.ToFloat64(requests.WithLabelValues("apigrps.iwe", "SaveMessage", "InvalidArgument"))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/apigrps.iwe/SaveMessage"}
+	_, err := UnaryServerInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.InvalidArgument, "empty body")
+	})
+	require.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(requests.WithLabelValues("apigrps.iwe", "SaveMessage", "InvalidArgument")))
+
+	infoStream := &grpc.StreamServerInfo{FullMethod: "/apigrps.iwe/TailMessages"}
+	err = StreamServerInterceptor(nil, nil, infoStream, func(srv any, ss grpc.ServerStream) error {
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Positive(t, testutil.ToFloat64(requests.WithLabelValues("apigrps.iwe", "TailMessages", "OK")))
+}
+
+// Test - Series of the handler
+func Test_Handler_SUCCESS(t *testing.T) {
+
+	MessageReceived("E", "alpha")
+	MessageStored("E", "alpha")
+	MessageRejected("E", "alpha", ReasonDuplicate)
+	MessageThrottled("I", "alpha", LimitRate)
+	ObserveDBWrite("sqlite", 2*time.Millisecond)
+	Rotated("E")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	for _, series := range []string{
+		`netlogiwe_messages_received_total{project="alpha",type="E"}`,
+		`netlogiwe_messages_stored_total{project="alpha",type="E"}`,
+		`netlogiwe_messages_rejected_total{project="alpha",reason="duplicate",type="E"}`,
+		`netlogiwe_messages_throttled_total{limit="rate",project="alpha",type="I"}`,
+		`netlogiwe_db_write_duration_seconds_count{driver="sqlite"}`,
+		`netlogiwe_rotations_total{type="E"}`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, string(body), series)
+	}
+}
+
+// Test - Labels of not allowed messages are bounded by the invalid value
+func Test_messageLabels_SUCCESS(t *testing.T) {
+
+	tests := []struct {
+		nameTest    string
+		typeMessage string
+		project     string
+		wantType    string
+		wantProject string
+	}{
+		{nameTest: "allowed", typeMessage: "W", project: "alpha", wantType: "W", wantProject: "alpha"},
+		{nameTest: "test message", typeMessage: "T", project: "alpha", wantType: "T", wantProject: "alpha"},
+		{nameTest: "unknown type", typeMessage: "random-1", project: "random-2", wantType: LabelInvalid, wantProject: LabelInvalid},
+		{nameTest: "empty project", typeMessage: "I", project: "", wantType: LabelInvalid, wantProject: LabelInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameTest, func(t *testing.T) {
+
+			typeMessage, project := messageLabels(tt.typeMessage, tt.project)
+			assert.Equal(t, tt.wantType, typeMessage)
+			assert.Equal(t, tt.wantProject, project)
+		})
+	}
+
+	before := testutil.ToFloat64(messagesReceived.WithLabelValues(LabelInvalid, LabelInvalid))
+	MessageReceived("random-3", "random-4")
+	assert.Equal(t, before+1, testutil.ToFloat64(messagesReceived.WithLabelValues(LabelInvalid, LabelInvalid)))
+
+	// projects over the limit of label values are other, seen projects keep own values
+	for i := range maxProjectLabels {
+		MessageReceived("I", fmt.Sprintf("project-%d", i))
+	}
+	_, project := messageLabels("I", "project-over")
+	assert.Equal(t, LabelOther, project)
+	_, project = messageLabels("I", "alpha")
+	assert.Equal(t, "alpha", project)
+}
@@ -0,0 +1,640 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Overflow policy: what is done when the spool reaches MaxBytes
+type Policy int
+
+const (
+	PolicyReject     Policy = iota // the new record is rejected with ErrFull
+	PolicyDropOldest               // the oldest segments are removed to free space
+)
+
+const (
+	defaultSizeSegment = 4 << 20
+	defaultMaxBytes    = 256 << 20
+	maxSizeRecord      = 16 << 20
+	sizeHeader         = 8 // length + CRC32-C of the payload
+	extSegment         = ".seg"
+	nameCursor         = "cursor"
+)
+
+var (
+	ErrFull   = errors.New("spool is full")
+	ErrClosed = errors.New("spool is closed")
+
+	tableCRC = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Config of the spool. Zero fields take defaults
+type ConfigT struct {
+	Dir         string // directory of segment files
+	SizeSegment int64  // a new segment is started when the current one is bigger
+	MaxBytes    int64  // limit of all segments
+	Policy      Policy
+	Sync        bool // fsync after each record and cursor update
+}
+
+// Segment file: <seq>.seg with records [length uint32][crc uint32][payload]
+type segmentT struct {
+	seq     int64
+	size    int64
+	records int
+}
+
+// Position of reading: segment, offset, index of record in the segment
+type cursorT struct {
+	seq int64
+	off int64
+	idx int
+}
+
+// Durable FIFO queue of records on disk. Records are appended to the current segment and
+// read in order from the cursor; the cursor is stored after Ack, so records are delivered at least once.
+// Torn records at the end of the last segment (crash during writing) are cut on Open,
+// corrupted records are cut with the rest of their segment and counted as dropped.
+// One writer and one reader can work concurrently.
+type Spool struct {
+	cfg ConfigT
+
+	mu       sync.Mutex
+	closed   bool
+	segments []segmentT // sorted by seq, the last one is written
+	w        *os.File
+	cursor   cursorT
+	peeked   []cursorT // positions after each record of the last Peek
+	pending  int
+	dropped  uint64
+}
+
+// =======================
+// ==       PUBLIC      ==
+// =======================
+
+// Open the spool in the directory: segments are checked, the cursor is restored. Return pointer, error
+func Open(cfg ConfigT) (*Spool, error) {
+
+	if cfg.Dir == "" {
+		return nil, errors.New("empty directory of spool")
+	}
+	if cfg.SizeSegment <= 0 {
+		cfg.SizeSegment = defaultSizeSegment
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	switch cfg.Policy {
+	case PolicyReject, PolicyDropOldest:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy: {%d}", cfg.Policy)
+	}
+
+	err := os.MkdirAll(cfg.Dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("fault create dir {%s}: %v", cfg.Dir, err)
+	}
+
+	s := &Spool{cfg: cfg}
+
+	err = s.loadSegments()
+	if err != nil {
+		return nil, err
+	}
+	err = s.loadCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	last := s.segments[len(s.segments)-1]
+	s.w, err = os.OpenFile(s.pathSegment(last.seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("fault open segment {%d}: %v", last.seq, err)
+	}
+
+	return s, nil
+}
+
+// Append the record to the end of the spool
+func (s *Spool) Append(rec []byte) error {
+	if len(rec) > maxSizeRecord {
+		return fmt.Errorf("record is too big: {%d} bytes", len(rec))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	size := int64(sizeHeader + len(rec))
+	for s.sizeLocked()+size > s.cfg.MaxBytes {
+		if s.cfg.Policy == PolicyReject || len(s.segments) == 1 {
+			s.dropped++
+			return ErrFull
+		}
+		err := s.removeOldest()
+		if err != nil {
+			return err
+		}
+	}
+
+	cur := &s.segments[len(s.segments)-1]
+	if cur.size > 0 && cur.size+size > s.cfg.SizeSegment {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+		cur = &s.segments[len(s.segments)-1]
+	}
+
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(rec)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(rec, tableCRC))
+	copy(buf[sizeHeader:], rec)
+
+	_, err := s.w.Write(buf)
+	if err != nil {
+		return s.restoreSegment(cur, fmt.Errorf("fault write record: %v", err))
+	}
+	if s.cfg.Sync {
+		err := s.w.Sync()
+		if err != nil {
+			return s.restoreSegment(cur, fmt.Errorf("fault sync segment: %v", err))
+		}
+	}
+
+	cur.size += size
+	cur.records++
+	s.pending++
+
+	return nil
+}
+
+// Reading up to max records from the cursor without removing them. Return records in order of appending
+func (s *Spool) Peek(max int) ([][]byte, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	var (
+		recs [][]byte
+		f    *os.File // segment of pos
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	s.peeked = s.peeked[:0]
+	pos := s.cursor
+
+	for len(recs) < max {
+
+		i := s.indexSegment(pos.seq)
+		if i < 0 {
+			break
+		}
+		seg := s.segments[i]
+		if pos.off >= seg.size {
+			if i == len(s.segments)-1 {
+				break
+			}
+			pos = cursorT{seq: s.segments[i+1].seq}
+			continue
+		}
+
+		if f == nil || f.Name() != s.pathSegment(seg.seq) {
+			if f != nil {
+				f.Close()
+			}
+			var err error
+			f, err = os.Open(s.pathSegment(seg.seq))
+			if err != nil {
+				return nil, fmt.Errorf("fault open segment {%d}: %v", seg.seq, err)
+			}
+		}
+
+		rec, next, err := readRecord(f, pos.off, seg.size)
+		if err != nil {
+			err := s.cutSegment(i, pos)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		pos = cursorT{seq: seg.seq, off: next, idx: pos.idx + 1}
+		recs = append(recs, rec)
+		s.peeked = append(s.peeked, pos)
+	}
+
+	return recs, nil
+}
+
+// Confirm delivery of n first records of the last Peek: the cursor is moved and stored,
+// delivered segments are removed
+func (s *Spool) Ack(n int) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if n <= 0 {
+		return nil
+	}
+	if n > len(s.peeked) {
+		return fmt.Errorf("ack of {%d} records, peeked {%d}", n, len(s.peeked))
+	}
+
+	s.cursor = s.peeked[n-1]
+	s.peeked = s.peeked[:0]
+	s.pending -= n
+
+	// the cursor at the end of the delivered segment is moved to the next one
+	i := s.indexSegment(s.cursor.seq)
+	if i < len(s.segments)-1 && s.cursor.off >= s.segments[i].size {
+		s.cursor = cursorT{seq: s.segments[i+1].seq}
+	}
+
+	err := s.storeCursor()
+	if err != nil {
+		return err
+	}
+
+	return s.removeDelivered()
+}
+
+// Number of records waiting for delivery
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Size of segments on disk
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sizeLocked()
+}
+
+// Number of records lost by overflow or corruption
+func (s *Spool) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close the spool. Not delivered records stay on disk
+func (s *Spool) Close() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	return s.w.Close()
+}
+
+// =======================
+// ==      INTERNAL     ==
+// =======================
+
+// Reading segments of the directory. Segments are checked and cut on disk after the last whole record,
+// records of the cut part are counted as dropped (a torn record at the end of the last segment is not counted)
+func (s *Spool) loadSegments() error {
+
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("fault read dir {%s}: %v", s.cfg.Dir, err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, extSegment) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, extSegment), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, segmentT{seq: seq})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	if len(s.segments) == 0 {
+		s.segments = append(s.segments, segmentT{seq: 1})
+		return nil
+	}
+
+	for i := range s.segments {
+		seg := &s.segments[i]
+		info, err := os.Stat(s.pathSegment(seg.seq))
+		if err != nil {
+			return fmt.Errorf("fault stat segment {%d}: %v", seg.seq, err)
+		}
+
+		// whole records up to the first bad one
+		f, err := os.Open(s.pathSegment(seg.seq))
+		if err != nil {
+			return fmt.Errorf("fault open segment {%d}: %v", seg.seq, err)
+		}
+		var off int64
+		for off < info.Size() {
+			_, next, err := readRecord(f, off, info.Size())
+			if err != nil {
+				break
+			}
+			off = next
+			seg.records++
+		}
+		lost := 0
+		if off < info.Size() {
+			lost = countRecords(f, off, info.Size())
+			if i < len(s.segments)-1 {
+				lost = max(lost, 1) // not a torn write: at least the corrupted record
+			}
+		}
+		f.Close()
+		seg.size = off
+
+		if off < info.Size() {
+			s.dropped += uint64(lost)
+			err := os.Truncate(s.pathSegment(seg.seq), off)
+			if err != nil {
+				return fmt.Errorf("fault cut segment {%d}: %v", seg.seq, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reading the cursor. Without the cursor file reading is started from the first segment
+func (s *Spool) loadCursor() error {
+
+	s.cursor = cursorT{seq: s.segments[0].seq}
+
+	data, err := os.ReadFile(filepath.Join(s.cfg.Dir, nameCursor))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("fault read cursor: %v", err)
+	}
+	if err == nil {
+		var c cursorT
+		_, err := fmt.Sscanf(string(data), "%d %d %d", &c.seq, &c.off, &c.idx)
+		if err != nil {
+			return fmt.Errorf("fault parse cursor {%s}: %v", strings.TrimSpace(string(data)), err)
+		}
+		i := s.indexSegment(c.seq)
+		if i >= 0 && (c.off > s.segments[i].size || c.idx > s.segments[i].records) {
+			// the segment is cut before the cursor: its whole records are delivered
+			c = cursorT{seq: c.seq, off: s.segments[i].size, idx: s.segments[i].records}
+		}
+		if i >= 0 {
+			s.cursor = c
+		}
+	}
+
+	i := s.indexSegment(s.cursor.seq)
+	s.pending = s.segments[i].records - s.cursor.idx
+	for _, seg := range s.segments[i+1:] {
+		s.pending += seg.records
+	}
+
+	// segments are left by a crash between storing the cursor and removing
+	return s.removeDelivered()
+}
+
+// Storing the cursor: temp file + rename
+func (s *Spool) storeCursor() error {
+
+	path := filepath.Join(s.cfg.Dir, nameCursor)
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d %d\n", s.cursor.seq, s.cursor.off, s.cursor.idx)
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("fault create cursor: %v", err)
+	}
+	_, err = f.WriteString(data)
+	if err == nil && s.cfg.Sync {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("fault write cursor: %v", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("fault rename cursor: %v", err)
+	}
+
+	return nil
+}
+
+// Start the next segment for writing
+func (s *Spool) rotate() error {
+
+	seq := s.segments[len(s.segments)-1].seq + 1
+	f, err := os.OpenFile(s.pathSegment(seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("fault create segment {%d}: %v", seq, err)
+	}
+
+	err = s.w.Close()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("fault close segment: %v", err)
+	}
+	s.w = f
+	s.segments = append(s.segments, segmentT{seq: seq})
+
+	return nil
+}
+
+// Cut the corrupted segment at pos on disk: the rest of its records are lost. Next records of the segment
+// being written are appended after the last whole one
+func (s *Spool) cutSegment(i int, pos cursorT) error {
+
+	seg := &s.segments[i]
+	lost := seg.records - pos.idx
+	s.dropped += uint64(lost)
+	s.pending -= lost
+	seg.records = pos.idx
+	seg.size = pos.off
+
+	err := os.Truncate(s.pathSegment(seg.seq), pos.off)
+	if err != nil {
+		return fmt.Errorf("fault cut segment {%d}: %v", seg.seq, err)
+	}
+
+	return nil
+}
+
+// Cut bytes of the not written record from the segment being written, so next records follow the last whole one.
+// Not cut - the next segment is started, the torn record is cut on the next Open. Return errWrite with faults of cutting
+func (s *Spool) restoreSegment(cur *segmentT, errWrite error) error {
+
+	f, err := os.OpenFile(s.pathSegment(cur.seq), os.O_WRONLY, 0)
+	if err == nil {
+		err = f.Truncate(cur.size)
+		if err == nil {
+			err = f.Sync()
+		}
+		f.Close()
+	}
+	if err == nil {
+		return errWrite
+	}
+
+	errRotate := s.rotate()
+	if errRotate != nil {
+		return fmt.Errorf("%v: fault cut segment {%d}: %v: %v", errWrite, cur.seq, err, errRotate)
+	}
+
+	return fmt.Errorf("%v: fault cut segment {%d}: %v", errWrite, cur.seq, err)
+}
+
+// Removing the oldest segment by overflow. Its not delivered records are lost
+func (s *Spool) removeOldest() error {
+
+	seg := s.segments[0]
+	lost := 0
+	if s.cursor.seq <= seg.seq {
+		lost = seg.records - s.cursor.idx
+		s.cursor = cursorT{seq: s.segments[1].seq}
+		s.peeked = s.peeked[:0]
+	}
+	s.dropped += uint64(lost)
+	s.pending -= lost
+
+	err := s.removeSegment()
+	if err != nil {
+		return err
+	}
+
+	return s.storeCursor()
+}
+
+// Removing segments before the cursor
+func (s *Spool) removeDelivered() error {
+
+	for len(s.segments) > 1 && s.segments[0].seq < s.cursor.seq {
+		err := s.removeSegment()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removing the first segment
+func (s *Spool) removeSegment() error {
+
+	err := os.Remove(s.pathSegment(s.segments[0].seq))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("fault remove segment {%d}: %v", s.segments[0].seq, err)
+	}
+	s.segments = s.segments[1:]
+
+	return nil
+}
+
+// Size of all segments
+func (s *Spool) sizeLocked() int64 {
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// Index of the segment by seq. -1 - not found
+func (s *Spool) indexSegment(seq int64) int {
+	for i, seg := range s.segments {
+		if seg.seq == seq {
+			return i
+		}
+	}
+	return -1
+}
+
+// Path of the segment file
+func (s *Spool) pathSegment(seq int64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%016d%s", seq, extSegment))
+}
+
+// Number of records from offset to limit by their lengths, checksums are not checked. The tail shorter than
+// the record is not counted
+func countRecords(r io.ReaderAt, off, limit int64) int {
+
+	var header [sizeHeader]byte
+	n := 0
+	for off+sizeHeader <= limit {
+		_, err := r.ReadAt(header[:], off)
+		if err != nil {
+			break
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		if size > maxSizeRecord || off+sizeHeader+size > limit {
+			break
+		}
+		off += sizeHeader + size
+		n++
+	}
+
+	return n
+}
+
+// Reading the record at offset, not after limit. Return payload, offset of the next record, error
+func readRecord(r io.ReaderAt, off, limit int64) ([]byte, int64, error) {
+
+	var header [sizeHeader]byte
+	if off+sizeHeader > limit {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	_, err := r.ReadAt(header[:], off)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	if size > maxSizeRecord || off+sizeHeader+size > limit {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	rec := make([]byte, size)
+	_, err = r.ReadAt(rec, off+sizeHeader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc32.Checksum(rec, tableCRC) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+
+	return rec, off + sizeHeader + size, nil
+}
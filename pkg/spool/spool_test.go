@@ -0,0 +1,275 @@
+package spool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Append records "rec-<from>".."rec-<to-1>"
+func appendRecords(t *testing.T, s *Spool, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("rec-%d", i))))
+	}
+}
+
+// Peek up to max records and return them as strings
+func peekStrings(t *testing.T, s *Spool, max int) []string {
+	t.Helper()
+
+	recs, err := s.Peek(max)
+	require.NoError(t, err)
+	out := []string{}
+	for _, rec := range recs {
+		out = append(out, string(rec))
+	}
+	return out
+}
+
+// Names of segment files of the directory
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+extSegment))
+	require.NoError(t, err)
+	return files
+}
+
+// Damage the payload of the record at offset of the segment file
+func damageRecord(t *testing.T, path string, off int64) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("X"), off+sizeHeader)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// =======================
+// ==      SUCCESS      ==
+// =======================
+
+// Test - Records are read in order across segments, delivered segments are removed
+func Test_Spool_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// 13 bytes per record -> 3 records per segment
+	appendRecords(t, s, 0, 7)
+	assert.Equal(t, 7, s.Len())
+	assert.Len(t, segmentFiles(t, dir), 3)
+
+	assert.Equal(t, []string{"rec-0", "rec-1", "rec-2", "rec-3"}, peekStrings(t, s, 4))
+	// not acked records are read again
+	assert.Equal(t, []string{"rec-0", "rec-1"}, peekStrings(t, s, 2))
+	require.NoError(t, s.Ack(2))
+	assert.Equal(t, 5, s.Len())
+
+	assert.Equal(t, []string{"rec-2", "rec-3", "rec-4"}, peekStrings(t, s, 3))
+	require.NoError(t, s.Ack(3))
+	assert.Len(t, segmentFiles(t, dir), 2, "the first segment is delivered")
+
+	assert.Equal(t, []string{"rec-5", "rec-6"}, peekStrings(t, s, 10))
+	require.NoError(t, s.Ack(2))
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, peekStrings(t, s, 10))
+	assert.Len(t, segmentFiles(t, dir), 1, "the current segment stays")
+
+	appendRecords(t, s, 7, 8)
+	assert.Equal(t, []string{"rec-7"}, peekStrings(t, s, 10))
+}
+
+// Test - Not delivered records survive reopening
+func Test_Spool_Reopen_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir, SizeSegment: 40, Sync: true})
+	require.NoError(t, err)
+	appendRecords(t, s, 0, 5)
+	peekStrings(t, s, 2)
+	require.NoError(t, s.Ack(2))
+	peekStrings(t, s, 2) // peeked, not acked: delivered again
+	require.NoError(t, s.Close())
+
+	s, err = Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, []string{"rec-2", "rec-3", "rec-4"}, peekStrings(t, s, 10))
+
+	appendRecords(t, s, 5, 6)
+	assert.Equal(t, []string{"rec-2", "rec-3", "rec-4", "rec-5"}, peekStrings(t, s, 10))
+}
+
+// Test - The torn record at the end is cut on opening, new records are written after it
+func Test_Spool_TornWrite_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir})
+	require.NoError(t, err)
+	appendRecords(t, s, 0, 2)
+	require.NoError(t, s.Close())
+
+	// crash during writing of the 3rd record
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open(ConfigT{Dir: dir})
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, 2, s.Len())
+	appendRecords(t, s, 2, 3)
+	assert.Equal(t, []string{"rec-0", "rec-1", "rec-2"}, peekStrings(t, s, 10))
+}
+
+// Test - The failed write of the record is cut from the segment: next records are read after the last whole one
+func Test_Spool_FailedWrite_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir})
+	require.NoError(t, err)
+	defer s.Close()
+	appendRecords(t, s, 0, 2)
+
+	// part of the record is written, then the writer fails
+	path := segmentFiles(t, dir)[0]
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	w := s.w
+	s.w, err = os.Open(path)
+	require.NoError(t, err)
+
+	require.ErrorContains(t, s.Append([]byte("rec-lost")), "fault write record")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, s.segments[0].size, info.Size())
+
+	require.NoError(t, s.w.Close())
+	s.w = w
+	appendRecords(t, s, 2, 4)
+	assert.Equal(t, []string{"rec-0", "rec-1", "rec-2", "rec-3"}, peekStrings(t, s, 10))
+	assert.Equal(t, uint64(0), s.Dropped())
+}
+
+// Test - The corrupted record: the rest of its segment is skipped
+func Test_Spool_Corrupted_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	defer s.Close()
+	appendRecords(t, s, 0, 5)
+
+	// payload of rec-1 in the first segment is damaged
+	damageRecord(t, segmentFiles(t, dir)[0], 13)
+
+	assert.Equal(t, []string{"rec-0", "rec-3", "rec-4"}, peekStrings(t, s, 10))
+	assert.Equal(t, uint64(2), s.Dropped())
+	require.NoError(t, s.Ack(3))
+	assert.Equal(t, 0, s.Len())
+}
+
+// Test - The corrupted record of not the last segment on opening: the rest of the segment is counted as dropped
+// and cut on disk, so the next opening has no loss
+func Test_Spool_CorruptedReopen_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	appendRecords(t, s, 0, 5)
+	require.NoError(t, s.Close())
+
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 2)
+	damageRecord(t, files[0], 13)
+
+	s, err = Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), s.Dropped())
+	assert.Equal(t, 3, s.Len())
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), info.Size())
+	require.NoError(t, s.Close())
+
+	s, err = Open(ConfigT{Dir: dir, SizeSegment: 40})
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, uint64(0), s.Dropped())
+	assert.Equal(t, []string{"rec-0", "rec-3", "rec-4"}, peekStrings(t, s, 10))
+}
+
+// Test - Overflow with removing the oldest segments
+func Test_Spool_DropOldest_SUCCESS(t *testing.T) {
+
+	dir := t.TempDir()
+	s, err := Open(ConfigT{Dir: dir, SizeSegment: 40, MaxBytes: 80, Policy: PolicyDropOldest})
+	require.NoError(t, err)
+	defer s.Close()
+
+	appendRecords(t, s, 0, 9)
+	assert.LessOrEqual(t, s.Size(), int64(80))
+	assert.Equal(t, uint64(3), s.Dropped())
+	assert.Equal(t, 6, s.Len())
+	assert.Equal(t, []string{"rec-3", "rec-4", "rec-5", "rec-6", "rec-7", "rec-8"}, peekStrings(t, s, 10))
+}
+
+// =======================
+// ==       FAULT       ==
+// =======================
+
+// Test - Overflow with rejecting new records
+func Test_Spool_Reject_FAULT(t *testing.T) {
+
+	s, err := Open(ConfigT{Dir: t.TempDir(), SizeSegment: 40, MaxBytes: 80})
+	require.NoError(t, err)
+	defer s.Close()
+
+	appendRecords(t, s, 0, 6)
+	err = s.Append([]byte("rec-6"))
+	require.ErrorIs(t, err, ErrFull)
+	assert.Equal(t, uint64(1), s.Dropped())
+	assert.Equal(t, 6, s.Len())
+
+	// delivered records free space
+	peekStrings(t, s, 3)
+	require.NoError(t, s.Ack(3))
+	require.NoError(t, s.Append([]byte("rec-6")))
+}
+
+// Test - Not allowed config and using after close
+func Test_Spool_FAULT(t *testing.T) {
+
+	_, err := Open(ConfigT{})
+	require.Error(t, err)
+
+	_, err = Open(ConfigT{Dir: t.TempDir(), Policy: Policy(7)})
+	require.Error(t, err)
+
+	s, err := Open(ConfigT{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.Error(t, s.Ack(1), "nothing is peeked")
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, s.Append([]byte("rec")), ErrClosed)
+	_, err = s.Peek(1)
+	require.ErrorIs(t, err, ErrClosed)
+}